@@ -3,13 +3,18 @@ package main
 import (
 	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/marccoxall/helmchecker/internal/actions"
 	"github.com/marccoxall/helmchecker/internal/checker"
 	"github.com/marccoxall/helmchecker/internal/config"
 	"github.com/marccoxall/helmchecker/internal/git"
 	"github.com/marccoxall/helmchecker/internal/github"
 	"github.com/marccoxall/helmchecker/internal/helm"
+	"github.com/marccoxall/helmchecker/internal/report"
 )
 
 func main() {
@@ -22,7 +27,7 @@ func main() {
 	}
 
 	// Initialize Helm client
-	helmClient, err := helm.NewClient(cfg.Kubernetes.Namespace)
+	helmClient, err := helm.NewClient(cfg.Kubernetes.Namespace, cfg.Helm)
 	if err != nil {
 		log.Fatalf("Failed to initialize Helm client: %v", err)
 	}
@@ -40,8 +45,40 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
 	defer cancel()
 
-	if err := checker.Run(ctx); err != nil {
-		log.Fatalf("Chart check failed: %v", err)
+	// Stop starting new updates on SIGTERM/SIGINT; in-flight ones get a grace period
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// A failed run still reports whatever it got through
+	result, err := checker.Run(ctx)
+	if err != nil {
+		log.Printf("Chart check failed: %v", err)
+	}
+
+	log.Printf("Summary: %s", result.Summary())
+	for _, failure := range result.Failed {
+		log.Printf("  - failed %v", failure)
+	}
+
+	// Write an HTML report for sharing outside the CLI
+	if cfg.Checker.ReportDir != "" {
+		path, err := report.WriteHTMLFile(cfg.Checker.ReportDir, result, time.Now())
+		if err != nil {
+			log.Printf("Warning: failed to write HTML report: %v", err)
+		} else {
+			log.Printf("Wrote HTML report to %s", path)
+		}
+	}
+
+	// Surface the result as annotations and step outputs inside GitHub Actions
+	if reporter := actions.FromEnv(); reporter != nil {
+		if err := reporter.Report(result); err != nil {
+			log.Printf("Warning: failed to report to GitHub Actions: %v", err)
+		}
+	}
+
+	if err != nil || len(result.Failed) > 0 {
+		os.Exit(1)
 	}
 
 	log.Println("Helm Chart Checker completed successfully")
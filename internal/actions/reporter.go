@@ -0,0 +1,85 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/marccoxall/helmchecker/internal/checker"
+)
+
+// Reporter writes a run result as GitHub Actions workflow commands and step
+// outputs
+type Reporter struct {
+	out        io.Writer
+	outputFile string
+}
+
+// NewReporter creates a reporter writing workflow commands to out and step
+// outputs to outputFile; an empty outputFile skips the outputs
+func NewReporter(out io.Writer, outputFile string) *Reporter {
+	return &Reporter{out: out, outputFile: outputFile}
+}
+
+// FromEnv returns a reporter for the current GitHub Actions job, or nil when
+// not running inside GitHub Actions
+func FromEnv() *Reporter {
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		return nil
+	}
+	return NewReporter(os.Stdout, os.Getenv("GITHUB_OUTPUT"))
+}
+
+// Report emits a notice summarizing the run, a warning for each failed,
+// blocked or republished chart, and the updates_found, prs_opened and failed step outputs
+func (r *Reporter) Report(result *checker.RunResult) error {
+	r.command("notice", "helmchecker", result.Summary())
+	for _, warning := range result.Warnings {
+		r.command("warning", "helmchecker", warning)
+	}
+	for _, failure := range result.Failed {
+		r.command("warning", failure.Chart, failure.Err.Error())
+	}
+	for _, update := range result.Blocked {
+		r.command("warning", update.Release.Chart, fmt.Sprintf("%s update from %s to %s blocked", update.Change, update.CurrentVersion, update.LatestVersion))
+	}
+	for _, drift := range result.Drifted {
+		r.command("warning", drift.Release.Chart, fmt.Sprintf("chart version %s republished with appVersion %s (installed: %s)", drift.Version, drift.IndexAppVersion, drift.InstalledAppVersion))
+	}
+
+	if r.outputFile == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(r.outputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open GitHub Actions output file: %w", err)
+	}
+
+	outputs := fmt.Sprintf("updates_found=%d\nprs_opened=%d\nfailed=%d\n", result.UpdatesFound, result.PRsOpened, len(result.Failed))
+	if _, err := f.WriteString(outputs); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write GitHub Actions outputs: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write GitHub Actions outputs: %w", err)
+	}
+
+	return nil
+}
+
+// command writes a workflow command such as ::warning title=nginx::message
+func (r *Reporter) command(name, title, message string) {
+	fmt.Fprintf(r.out, "::%s title=%s::%s\n", name, escapeProperty(title), escapeData(message))
+}
+
+// escapeData escapes a workflow command message
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a workflow command property value
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,82 @@
+package actions
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/marccoxall/helmchecker/internal/checker"
+	"github.com/marccoxall/helmchecker/internal/helm"
+)
+
+func TestReport(t *testing.T) {
+	result := &checker.RunResult{
+		Checked:      3,
+		UpdatesFound: 2,
+		PRsOpened:    1,
+		Warnings:     []string{"no helm repositories configured"},
+		Failed: []checker.ChartError{
+			{Chart: "redis", Err: errors.New("failed to get latest version: index unreachable\nretry later")},
+		},
+		Blocked: []*checker.ChartUpdate{
+			{
+				Release:        &helm.Release{Chart: "nginx"},
+				CurrentVersion: "2.0.0",
+				LatestVersion:  "1.9.0",
+				Change:         checker.VersionDowngrade,
+			},
+		},
+		Drifted: []checker.AppVersionDrift{
+			{Release: &helm.Release{Chart: "mysql"}, Version: "9.4.0", InstalledAppVersion: "8.0.35", IndexAppVersion: "8.0.36"},
+		},
+	}
+
+	var out bytes.Buffer
+	outputFile := filepath.Join(t.TempDir(), "output")
+	if err := NewReporter(&out, outputFile).Report(result); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+
+	expected := "::notice title=helmchecker::" + result.Summary() + "\n" +
+		"::warning title=helmchecker::no helm repositories configured\n" +
+		"::warning title=redis::failed to get latest version: index unreachable%0Aretry later\n" +
+		"::warning title=nginx::downgrade update from 2.0.0 to 1.9.0 blocked\n" +
+		"::warning title=mysql::chart version 9.4.0 republished with appVersion 8.0.36 (installed: 8.0.35)\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected annotations:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+
+	outputs, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("failed to read outputs: %v", err)
+	}
+	if string(outputs) != "updates_found=2\nprs_opened=1\nfailed=1\n" {
+		t.Errorf("Unexpected outputs: %q", string(outputs))
+	}
+}
+
+func TestEscapeProperty(t *testing.T) {
+	if got := escapeProperty("a:b,c%\n"); got != "a%3Ab%2Cc%25%0A" {
+		t.Errorf("Unexpected escaped property %q", got)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "")
+	if FromEnv() != nil {
+		t.Errorf("Expected no reporter outside GitHub Actions")
+	}
+
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_OUTPUT", "/tmp/output")
+	reporter := FromEnv()
+	if reporter == nil {
+		t.Fatal("Expected a reporter inside GitHub Actions")
+	}
+	if !strings.HasSuffix(reporter.outputFile, "output") {
+		t.Errorf("Expected GITHUB_OUTPUT to be used, got %q", reporter.outputFile)
+	}
+}
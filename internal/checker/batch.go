@@ -0,0 +1,393 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	gh "github.com/google/go-github/v56/github"
+	"github.com/marccoxall/helmchecker/internal/hooks"
+)
+
+// processGroupedUpdates applies all eligible updates in a single pull request
+// per base branch instead of one per chart
+func (c *Checker) processGroupedUpdates(ctx context.Context, repoPath string, repo *gogit.Repository, updates []*ChartUpdate) bool {
+	failed := false
+
+	// Run the per-chart checks first, grouping what is left by base branch
+	groups := make(map[string][]*preparedUpdate)
+	var baseBranches []string
+	for i, update := range updates {
+		if ctx.Err() != nil {
+			log.Printf("Shutdown requested, skipping %d remaining update(s)", len(updates)-i)
+			c.result.Skipped += len(updates) - i
+			return failed
+		}
+
+		prepared, err := c.prepareUpdate(ctx, repoPath, update)
+		if err != nil {
+			log.Printf("Failed to process update for %s: %v", update.Release.Chart, err)
+			c.result.fail(update.Release.Chart, err)
+			failed = true
+			continue
+		}
+		if prepared == nil {
+			continue
+		}
+
+		if _, ok := groups[prepared.baseBranch]; !ok {
+			baseBranches = append(baseBranches, prepared.baseBranch)
+		}
+		groups[prepared.baseBranch] = append(groups[prepared.baseBranch], prepared)
+	}
+
+	for _, baseBranch := range baseBranches {
+		group := groups[baseBranch]
+
+		// Let a group that has started finish even if shutdown is requested meanwhile
+		groupCtx, cancel := c.gracefulContext(ctx)
+		pending, err := c.applyGroupedUpdates(groupCtx, repoPath, repo, baseBranch, group)
+		cancel()
+		if err != nil {
+			log.Printf("Failed to process grouped updates for %s: %v", baseBranch, err)
+			for _, prepared := range pending {
+				c.result.fail(prepared.update.Release.Chart, err)
+			}
+			failed = true
+		}
+	}
+
+	return failed
+}
+
+// groupBranchDate is the date layout ending grouped update branch names
+const groupBranchDate = "2006-01-02"
+
+// groupBranchPrefix returns the start of the names of the branches holding
+// grouped updates for a base branch
+func (c *Checker) groupBranchPrefix(baseBranch string) string {
+	prefix := "chart-updates-"
+	if baseBranch != c.config.Git.Branch {
+		prefix += baseBranch + "-"
+	}
+	return prefix
+}
+
+// groupBranchName returns the branch holding the grouped updates for a base
+// branch, e.g. chart-updates-2024-01-02
+func (c *Checker) groupBranchName(baseBranch string) string {
+	return sanitizeBranchName(c.groupBranchPrefix(baseBranch)+c.now().UTC().Format(groupBranchDate), "")
+}
+
+// isGroupBranch reports whether branchName holds grouped updates for a base
+// branch, whichever day it was created on
+func (c *Checker) isGroupBranch(branchName, baseBranch string) bool {
+	date, ok := strings.CutPrefix(branchName, c.groupBranchPrefix(baseBranch))
+	if !ok {
+		return false
+	}
+	_, err := time.Parse(groupBranchDate, date)
+	return err == nil
+}
+
+// applyGroupedUpdates edits every chart of a group on one branch, commits
+// once and opens a single pull request listing all of them. An open grouped
+// pull request from an earlier run is updated instead of opening another. On
+// error it returns the updates that were left without a pull request.
+func (c *Checker) applyGroupedUpdates(ctx context.Context, repoPath string, repo *gogit.Repository, baseBranch string, group []*preparedUpdate) ([]*preparedUpdate, error) {
+	existingPR, err := c.findGroupPR(ctx, baseBranch)
+	if err != nil {
+		return group, fmt.Errorf("failed to check for existing PR: %w", err)
+	}
+
+	// Start from the latest commit of the base branch, or of the open
+	// pull request's branch to add to it
+	branchName, startBranch := c.groupBranchName(baseBranch), baseBranch
+	if existingPR != nil {
+		branchName = existingPR.GetHead().GetRef()
+		startBranch = branchName
+	}
+	if err := c.gitClient.FetchBranch(ctx, repo, startBranch); err != nil {
+		return group, fmt.Errorf("failed to update branch %s: %w", startBranch, err)
+	}
+	if err := c.gitClient.CheckoutBranch(repo, startBranch); err != nil {
+		return group, fmt.Errorf("failed to checkout branch %s: %w", startBranch, err)
+	}
+	if err := c.gitClient.CreateBranch(repo, branchName); err != nil {
+		return group, fmt.Errorf("failed to create branch: %w", err)
+	}
+
+	// A chart that can't be edited is left out rather than failing the group.
+	// Charts the open pull request already updates are listed but not edited.
+	var applied, edited []*preparedUpdate
+	for _, prepared := range group {
+		if existingPR != nil && c.chartFileUpToDate(repoPath, prepared.chartFile, prepared.update) {
+			applied = append(applied, prepared)
+			continue
+		}
+		if err := c.updateChartFiles(repoPath, prepared.chartFile, prepared.update); err != nil {
+			err = fmt.Errorf("failed to update chart files: %w", err)
+			log.Printf("Failed to process update for %s: %v", prepared.update.Release.Chart, err)
+			c.result.fail(prepared.update.Release.Chart, err)
+			continue
+		}
+		applied = append(applied, prepared)
+		edited = append(edited, prepared)
+	}
+	if len(edited) == 0 {
+		if existingPR != nil && len(applied) > 0 {
+			log.Printf("PR already exists for grouped updates on %s: %s", branchName, existingPR.GetHTMLURL())
+			c.result.Skipped += len(applied)
+			for _, prepared := range applied {
+				prepared.update.PullRequestURL = existingPR.GetHTMLURL()
+			}
+		}
+		return nil, nil
+	}
+
+	commitMsg := fmt.Sprintf(c.config.Checker.GroupCommitMessage, strconv.Itoa(len(edited)))
+	if err := c.gitClient.CommitChanges(repo, commitMsg); err != nil {
+		return applied, fmt.Errorf("failed to commit changes: %w", err)
+	}
+	if err := c.gitClient.PushBranch(repo, branchName); err != nil {
+		return applied, fmt.Errorf("failed to push branch: %w", err)
+	}
+
+	// Charts the open pull request updated in earlier runs remain on its branch
+	previous := &groupEntries{}
+	if existingPR != nil {
+		previous = previousGroupEntries(existingPR.GetBody(), applied)
+	}
+
+	prTitle := fmt.Sprintf("Update %d Helm chart(s)", len(applied)+len(previous.rows))
+	prBody, overflow := fitPRBody(c.groupPRBody(applied, previous), maxPRBodyLength)
+
+	var pr *gh.PullRequest
+	if existingPR != nil {
+		pr, err = c.githubClient.UpdatePullRequest(ctx,
+			c.config.GitHub.Owner,
+			c.config.GitHub.Repo,
+			existingPR.GetNumber(),
+			prTitle,
+			prBody)
+		if err != nil {
+			return applied, fmt.Errorf("failed to update pull request: %w", err)
+		}
+		log.Printf("Updated pull request for %d chart(s): %s", len(applied), pr.GetHTMLURL())
+	} else {
+		pr, err = c.githubClient.CreatePullRequest(ctx,
+			c.config.GitHub.Owner,
+			c.config.GitHub.Repo,
+			prTitle,
+			prBody,
+			branchName,
+			baseBranch)
+		if err != nil {
+			// Don't leave a pushed branch without a pull request behind
+			if rollbackErr := c.gitClient.DeleteRemoteBranch(repo, branchName); rollbackErr != nil {
+				log.Printf("Warning: failed to delete branch %s after pull request creation failed: %v", branchName, rollbackErr)
+			}
+			return applied, fmt.Errorf("failed to create pull request: %w", err)
+		}
+
+		log.Printf("Created pull request for %d chart(s): %s", len(applied), pr.GetHTMLURL())
+		c.result.PRsOpened++
+		if c.openPRs != nil {
+			c.openPRs = append(c.openPRs, pr)
+		}
+	}
+
+	// Post the part of the description that didn't fit as comments
+	for i, comment := range overflow {
+		if err := c.githubClient.CreateComment(ctx,
+			c.config.GitHub.Owner,
+			c.config.GitHub.Repo,
+			pr.GetNumber(),
+			comment); err != nil {
+			log.Printf("Warning: failed to post description part %d of %d for %s: %v", i+2, len(overflow)+1, branchName, err)
+			break
+		}
+	}
+
+	var reviewers, labels []string
+	for _, prepared := range applied {
+		prepared.update.PullRequestURL = pr.GetHTMLURL()
+		reviewers = mergeReviewers(reviewers, prepared.reviewers)
+		labels = append(labels, chartLabel(prepared.update.Release.Chart))
+	}
+
+	for _, prepared := range edited {
+		update := prepared.update
+		if c.config.Checker.ManifestDiffReview {
+			c.postManifestReview(ctx, pr.GetNumber(), update, prepared.simulation)
+		}
+		c.recordBump(ctx, update)
+
+		c.runHooks(ctx, &hooks.Event{
+			Chart:             update.Release.Chart,
+			Release:           update.Release.Name,
+			Namespace:         update.Release.Namespace,
+			CurrentVersion:    update.CurrentVersion,
+			LatestVersion:     update.LatestVersion,
+			Branch:            branchName,
+			PullRequestNumber: pr.GetNumber(),
+			PullRequestURL:    pr.GetHTMLURL(),
+		})
+	}
+
+	if c.dedupByLabel() {
+		if err := c.githubClient.AddLabels(ctx,
+			c.config.GitHub.Owner,
+			c.config.GitHub.Repo,
+			pr.GetNumber(),
+			labels); err != nil {
+			log.Printf("Warning: failed to label pull request for %s: %v", branchName, err)
+		}
+	}
+
+	if len(reviewers) > 0 {
+		if err := c.githubClient.RequestReviewers(ctx,
+			c.config.GitHub.Owner,
+			c.config.GitHub.Repo,
+			pr.GetNumber(),
+			reviewers); err != nil {
+			log.Printf("Warning: failed to request reviewers for %s: %v", branchName, err)
+		}
+	}
+
+	return nil, nil
+}
+
+// findGroupPR looks for an open pull request of grouped updates into the
+// base branch, opened on any day
+func (c *Checker) findGroupPR(ctx context.Context, baseBranch string) (*gh.PullRequest, error) {
+	prs := c.openPRs
+	if prs == nil {
+		var err error
+		prs, err = c.githubClient.ListOpenPullRequests(ctx, c.config.GitHub.Owner, c.config.GitHub.Repo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, pr := range prs {
+		if pr.GetBase().GetRef() == baseBranch && c.isGroupBranch(pr.GetHead().GetRef(), baseBranch) {
+			return pr, nil
+		}
+	}
+	return nil, nil
+}
+
+// chartFileUpToDate reports whether the chart file already sets the update's
+// version, e.g. on the branch of an open pull request
+func (c *Checker) chartFileUpToDate(repoPath, chartFile string, update *ChartUpdate) bool {
+	content, err := os.ReadFile(filepath.Join(repoPath, chartFile))
+	if err != nil {
+		return false
+	}
+	updated, err := setChartVersion(content, update.Release.Chart, update.LatestVersion)
+	return err == nil && bytes.Equal(content, updated)
+}
+
+// groupPRBody describes grouped updates: a table of every chart followed by
+// the details of each update, keeping the previous entries of charts updated
+// on the branch by earlier runs
+func (c *Checker) groupPRBody(applied []*preparedUpdate, previous *groupEntries) string {
+	var b strings.Builder
+	b.WriteString("Updates the following Helm charts:\n\n")
+	b.WriteString(groupTableHeader)
+	for _, row := range previous.rows {
+		b.WriteString(row + "\n")
+	}
+	for _, prepared := range applied {
+		update := prepared.update
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n",
+			update.Release.Chart,
+			releaseRef(update.Release.Namespace, update.Release.Name),
+			update.CurrentVersion,
+			update.LatestVersion)
+	}
+
+	for _, prepared := range applied {
+		update := prepared.update
+		fmt.Fprintf(&b, "\n## %s %s → %s\n", update.Release.Chart, update.CurrentVersion, update.LatestVersion)
+		b.WriteString(reasonsSection(update))
+		b.WriteString(releaseDetailsSection(update))
+		b.WriteString(crdWarningSection(prepared.simulation))
+		b.WriteString(removedValuesSection(prepared.simulation))
+		b.WriteString(policyViolationsSection(prepared.violations))
+		if !c.config.Checker.ManifestDiffReview {
+			b.WriteString(manifestChangesSection(prepared.simulation))
+		}
+	}
+
+	for _, section := range previous.sections {
+		b.WriteString("\n" + section)
+	}
+
+	return b.String()
+}
+
+// groupTableHeader starts the table of charts in a grouped pull request body
+const groupTableHeader = "| Chart | Release | Current | New |\n|-------|---------|---------|-----|\n"
+
+// groupEntries are the table rows and detail sections of the charts in a
+// grouped pull request body
+type groupEntries struct {
+	rows     []string
+	sections []string
+}
+
+// previousGroupEntries returns the entries of an earlier grouped pull request
+// body for the charts not in applied
+func previousGroupEntries(body string, applied []*preparedUpdate) *groupEntries {
+	updated := make(map[string]bool)
+	for _, prepared := range applied {
+		updated[prepared.update.Release.Chart] = true
+	}
+
+	entries := &groupEntries{}
+	_, rest, ok := strings.Cut(strings.TrimSuffix(body, overflowNotice), groupTableHeader)
+	if !ok {
+		return entries
+	}
+	table, details, _ := strings.Cut(rest, "\n\n## ")
+
+	kept := make(map[string]bool)
+	for _, row := range strings.Split(strings.TrimSpace(table), "\n") {
+		cells := strings.Split(row, "|")
+		if len(cells) < 3 {
+			continue
+		}
+		if chart := strings.TrimSpace(cells[1]); chart != "" && !updated[chart] {
+			kept[chart] = true
+			entries.rows = append(entries.rows, row)
+		}
+	}
+
+	if details == "" {
+		return entries
+	}
+	for _, section := range strings.Split(details, "\n## ") {
+		if chart, _, _ := strings.Cut(section, " "); kept[chart] {
+			entries.sections = append(entries.sections, "## "+strings.TrimRight(section, "\n")+"\n")
+		}
+	}
+	return entries
+}
+
+// releaseRef formats a release as namespace/name, or just its name when the
+// namespace is unknown
+func releaseRef(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
@@ -0,0 +1,209 @@
+package checker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	gh "github.com/google/go-github/v56/github"
+	"github.com/marccoxall/helmchecker/internal/config"
+	"github.com/marccoxall/helmchecker/internal/helm"
+)
+
+func TestBatchUpdates(t *testing.T) {
+	cfg := &config.Config{
+		Git: config.GitConfig{Branch: "main"},
+		Checker: config.CheckerConfig{
+			CommitMessage:      "chore: update helm chart %s to version %s",
+			GroupCommitMessage: "deps: bump %s charts",
+			PullRequestTitle:   "Update Helm chart %s to version %s",
+			PullRequestBody:    "Updates %s from %s to %s",
+			PRDeduplication:    config.DedupBoth,
+			BatchUpdates:       true,
+		},
+	}
+	gitClient := &fakeGitClient{repoPath: chartRepo(t, "nginx", "redis", "mysql")}
+	githubClient := &fakeGitHubClient{
+		// mysql already has its own pull request
+		existing: map[string]*gh.PullRequest{
+			"update-mysql-9.1.0": {HTMLURL: gh.String("https://github.com/o/r/pull/3")},
+		},
+	}
+	c := New(&fakeHelmClient{}, gitClient, githubClient, cfg)
+	c.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	updates := []*ChartUpdate{
+		{Release: &helm.Release{Name: "web", Namespace: "apps", Chart: "nginx"}, CurrentVersion: "1.0.0", LatestVersion: "1.1.0"},
+		{Release: &helm.Release{Name: "cache", Namespace: "apps", Chart: "redis"}, CurrentVersion: "2.0.0", LatestVersion: "2.1.0"},
+		{Release: &helm.Release{Name: "db", Namespace: "data", Chart: "mysql"}, CurrentVersion: "9.0.0", LatestVersion: "9.1.0"},
+	}
+	if err := c.processUpdates(context.Background(), updates); err != nil {
+		t.Fatalf("processUpdates failed: %v", err)
+	}
+
+	// One branch, one commit and one pull request for both remaining charts
+	if strings.Join(gitClient.branches, ",") != "chart-updates-2024-01-02" {
+		t.Errorf("Expected a single grouped branch, got %v", gitClient.branches)
+	}
+	if len(gitClient.commits) != 1 || gitClient.commits[0] != "deps: bump 2 charts" {
+		t.Errorf("Expected a single commit using the group commit message, got %v", gitClient.commits)
+	}
+	if len(gitClient.files) != 2 {
+		t.Errorf("Expected both chart files to be edited, got %v", gitClient.files)
+	}
+	if len(githubClient.created) != 1 {
+		t.Fatalf("Expected a single pull request, got %d", len(githubClient.created))
+	}
+
+	pr := githubClient.created[0]
+	if pr.GetTitle() != "Update 2 Helm chart(s)" {
+		t.Errorf("Unexpected title %q", pr.GetTitle())
+	}
+	for _, want := range []string{
+		"| Chart | Release | Current | New |",
+		"| nginx | apps/web | 1.0.0 | 1.1.0 |",
+		"| redis | apps/cache | 2.0.0 | 2.1.0 |",
+		"## nginx 1.0.0 → 1.1.0",
+	} {
+		if !strings.Contains(pr.GetBody(), want) {
+			t.Errorf("Expected body to contain %q, got:\n%s", want, pr.GetBody())
+		}
+	}
+	if strings.Contains(pr.GetBody(), "mysql") {
+		t.Errorf("Expected mysql with an existing PR to be left out, got:\n%s", pr.GetBody())
+	}
+
+	if labels := githubClient.labels[pr.GetNumber()]; strings.Join(labels, ",") != "helmchecker/chart: nginx,helmchecker/chart: redis" {
+		t.Errorf("Expected the PR to carry both chart labels, got %v", labels)
+	}
+	if updates[0].PullRequestURL != pr.GetHTMLURL() || updates[1].PullRequestURL != pr.GetHTMLURL() {
+		t.Errorf("Expected both updates to link the grouped PR")
+	}
+	if c.result.PRsOpened != 1 || c.result.Skipped != 1 {
+		t.Errorf("Expected 1 PR opened and 1 update skipped, got %d and %d", c.result.PRsOpened, c.result.Skipped)
+	}
+}
+
+func TestBatchUpdatesUpdatesOpenGroupedPR(t *testing.T) {
+	cfg := &config.Config{
+		Git: config.GitConfig{Branch: "main"},
+		Checker: config.CheckerConfig{
+			CommitMessage:      "chore: update helm chart %s to version %s",
+			GroupCommitMessage: "deps: bump %s charts",
+			PullRequestTitle:   "Update Helm chart %s to version %s",
+			PullRequestBody:    "Updates %s from %s to %s",
+			BatchUpdates:       true,
+		},
+	}
+	gitClient := &fakeGitClient{repoPath: chartRepo(t, "nginx", "redis")}
+	githubClient := &fakeGitHubClient{
+		// Grouped pull request opened by the run of an earlier day
+		open: []*gh.PullRequest{{
+			Number:  gh.Int(7),
+			HTMLURL: gh.String("https://github.com/o/r/pull/7"),
+			// mysql was updated on the branch by that run and is in cooldown now
+			Body: gh.String("Updates the following Helm charts:\n\n" +
+				"| Chart | Release | Current | New |\n|-------|---------|---------|-----|\n" +
+				"| mysql | data/db | 9.0.0 | 9.1.0 |\n" +
+				"| nginx | apps/web | 1.0.0 | 1.0.5 |\n" +
+				"\n## mysql 9.0.0 → 9.1.0\nmysql details\n" +
+				"\n## nginx 1.0.0 → 1.0.5\nnginx details\n"),
+			Head: &gh.PullRequestBranch{Ref: gh.String("chart-updates-2024-01-01")},
+			Base: &gh.PullRequestBranch{Ref: gh.String("main")},
+		}},
+	}
+	c := New(&fakeHelmClient{}, gitClient, githubClient, cfg)
+	c.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	updates := []*ChartUpdate{
+		{Release: &helm.Release{Name: "web", Namespace: "apps", Chart: "nginx"}, CurrentVersion: "1.0.0", LatestVersion: "1.1.0"},
+		{Release: &helm.Release{Name: "cache", Namespace: "apps", Chart: "redis"}, CurrentVersion: "2.0.0", LatestVersion: "2.1.0"},
+	}
+	if err := c.processUpdates(context.Background(), updates); err != nil {
+		t.Fatalf("processUpdates failed: %v", err)
+	}
+
+	// The open pull request's branch is extended rather than a new one opened
+	if len(githubClient.created) != 0 {
+		t.Errorf("Expected no new pull request, got %d", len(githubClient.created))
+	}
+	for name, got := range map[string][]string{
+		"fetched":     gitClient.fetched,
+		"checked out": gitClient.checkouts,
+		"pushed":      gitClient.pushed,
+	} {
+		if strings.Join(got, ",") != "chart-updates-2024-01-01" {
+			t.Errorf("Expected the existing branch to be %s, got %v", name, got)
+		}
+	}
+	if len(githubClient.updated) != 1 {
+		t.Fatalf("Expected the open pull request to be updated once, got %d", len(githubClient.updated))
+	}
+
+	pr := githubClient.updated[0]
+	if pr.GetNumber() != 7 {
+		t.Errorf("Expected pull request 7 to be updated, got %d", pr.GetNumber())
+	}
+	if pr.GetTitle() != "Update 3 Helm chart(s)" {
+		t.Errorf("Unexpected title %q", pr.GetTitle())
+	}
+	for _, want := range []string{
+		"| mysql | data/db | 9.0.0 | 9.1.0 |",
+		"## mysql 9.0.0 → 9.1.0\nmysql details\n",
+		"| nginx | apps/web | 1.0.0 | 1.1.0 |",
+		"| redis | apps/cache | 2.0.0 | 2.1.0 |",
+	} {
+		if !strings.Contains(pr.GetBody(), want) {
+			t.Errorf("Expected the body to contain %q, got:\n%s", want, pr.GetBody())
+		}
+	}
+	if strings.Contains(pr.GetBody(), "1.0.5") {
+		t.Errorf("Expected the earlier nginx entry to be replaced, got:\n%s", pr.GetBody())
+	}
+	if updates[0].PullRequestURL != pr.GetHTMLURL() || updates[1].PullRequestURL != pr.GetHTMLURL() {
+		t.Errorf("Expected both updates to link the updated PR")
+	}
+	if c.result.PRsOpened != 0 {
+		t.Errorf("Expected no PR to be counted as opened, got %d", c.result.PRsOpened)
+	}
+}
+
+func TestBatchUpdatesPerBaseBranch(t *testing.T) {
+	cfg := &config.Config{
+		Git: config.GitConfig{Branch: "main"},
+		Checker: config.CheckerConfig{
+			CommitMessage:      "chore: update helm chart %s to version %s",
+			GroupCommitMessage: "deps: bump %s charts",
+			PullRequestTitle:   "Update Helm chart %s to version %s",
+			PullRequestBody:    "Updates %s from %s to %s",
+			BatchUpdates:       true,
+			DirectoryRules: []config.DirectoryRule{
+				{PathPrefix: "charts/redis/", TargetBranch: "release/cache"},
+			},
+		},
+	}
+	gitClient := &fakeGitClient{repoPath: chartRepo(t, "nginx", "redis")}
+	githubClient := &fakeGitHubClient{}
+	c := New(&fakeHelmClient{}, gitClient, githubClient, cfg)
+	c.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	updates := []*ChartUpdate{
+		{Release: &helm.Release{Chart: "nginx"}, CurrentVersion: "1.0.0", LatestVersion: "1.1.0"},
+		{Release: &helm.Release{Chart: "redis"}, CurrentVersion: "2.0.0", LatestVersion: "2.1.0"},
+	}
+	if err := c.processUpdates(context.Background(), updates); err != nil {
+		t.Fatalf("processUpdates failed: %v", err)
+	}
+
+	expected := "chart-updates-2024-01-02,chart-updates-release/cache-2024-01-02"
+	if strings.Join(gitClient.branches, ",") != expected {
+		t.Errorf("Expected branches %s, got %v", expected, gitClient.branches)
+	}
+	if len(githubClient.created) != 2 {
+		t.Fatalf("Expected a pull request per base branch, got %d", len(githubClient.created))
+	}
+	if base := githubClient.created[1].GetBase().GetRef(); base != "release/cache" {
+		t.Errorf("Expected the redis PR to target release/cache, got %s", base)
+	}
+}
@@ -0,0 +1,88 @@
+package checker
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxPRBodyLength is the maximum size GitHub accepts for pull request and comment bodies
+const maxPRBodyLength = 65536
+
+// overflowNotice is appended to a pull request body whose remainder is posted as comments
+const overflowNotice = "\n\n_This description exceeds GitHub's size limit; it continues in the comments below._\n"
+
+// fitPRBody keeps a pull request body within limit, returning the part that
+// fits in the body and the remainder split into comment-sized chunks
+func fitPRBody(body string, limit int) (string, []string) {
+	if len(body) <= limit {
+		return body, nil
+	}
+
+	chunks := splitBody(body, limit-len(overflowNotice))
+	return chunks[0] + overflowNotice, chunks[1:]
+}
+
+// splitBody splits markdown text at line boundaries into chunks of at most
+// limit bytes. Code fences cut by a split are closed at the end of the chunk
+// and reopened at the start of the next one so diffs keep rendering.
+func splitBody(text string, limit int) []string {
+	const closeFence = "```\n"
+
+	var chunks []string
+	var current strings.Builder
+	openFence := ""
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+
+		closing := ""
+		if openFence != "" {
+			closing = closeFence
+		}
+
+		if current.Len() > 0 && current.Len()+len(line)+len(closing) > limit {
+			current.WriteString(closing)
+			chunks = append(chunks, current.String())
+			current.Reset()
+			if openFence != "" {
+				current.WriteString(openFence)
+			}
+		}
+
+		// A single line longer than a chunk is truncated
+		if room := limit - current.Len() - len(closing); len(line) > room {
+			line = truncateUTF8(line, room-1) + "\n"
+		}
+		current.WriteString(line)
+
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "```") {
+			if openFence == "" {
+				openFence = trimmed + "\n"
+			} else if trimmed == "```" {
+				openFence = ""
+			}
+		}
+	}
+
+	if current.Len() > 0 || len(chunks) == 0 {
+		chunks = append(chunks, current.String())
+	}
+
+	return chunks
+}
+
+// truncateUTF8 truncates s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,115 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/marccoxall/helmchecker/internal/config"
+	"github.com/marccoxall/helmchecker/internal/helm"
+)
+
+func TestFitPRBody(t *testing.T) {
+	body, overflow := fitPRBody("short body", 100)
+	if body != "short body" || overflow != nil {
+		t.Errorf("Expected short body to be kept as is, got %q and %v", body, overflow)
+	}
+
+	var b strings.Builder
+	b.WriteString("Intro\n\n```diff\n")
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&b, "+line %02d\n", i)
+	}
+	b.WriteString("```\nOutro\n")
+
+	body, overflow = fitPRBody(b.String(), 200)
+	if len(body) > 200 {
+		t.Errorf("Expected body within 200 bytes, got %d", len(body))
+	}
+	if !strings.HasSuffix(body, overflowNotice) {
+		t.Errorf("Expected body to end with the overflow notice, got %q", body)
+	}
+	if len(overflow) == 0 {
+		t.Fatal("Expected overflow chunks")
+	}
+
+	joined := strings.TrimSuffix(body, overflowNotice)
+	for _, chunk := range overflow {
+		if len(chunk) > 200 {
+			t.Errorf("Expected chunk within 200 bytes, got %d", len(chunk))
+		}
+		// Every chunk must have balanced code fences
+		if fences := strings.Count(chunk, "```"); fences%2 != 0 {
+			t.Errorf("Expected balanced code fences, got %d in %q", fences, chunk)
+		}
+		joined += chunk
+	}
+
+	for i := 0; i < 50; i++ {
+		if !strings.Contains(joined, fmt.Sprintf("+line %02d\n", i)) {
+			t.Errorf("Expected line %d to be kept", i)
+		}
+	}
+	if !strings.HasSuffix(joined, "Outro\n") {
+		t.Errorf("Expected text after the fence to be kept")
+	}
+}
+
+func TestSplitBodyTruncatesLongLines(t *testing.T) {
+	chunks := splitBody(strings.Repeat("é", 100)+"\n", 51)
+	if len(chunks) != 1 || len(chunks[0]) > 51 {
+		t.Fatalf("Expected one chunk within 51 bytes, got %v", chunks)
+	}
+	if !strings.HasSuffix(chunks[0], "é\n") {
+		t.Errorf("Expected truncation on a character boundary, got %q", chunks[0])
+	}
+}
+
+func TestProcessUpdateOversizedBody(t *testing.T) {
+	var changes []helm.ManifestChange
+	for i := 0; i < 40; i++ {
+		changes = append(changes, helm.ManifestChange{
+			Resource: fmt.Sprintf("ConfigMap/apps/config-%d", i),
+			Action:   helm.ManifestChanged,
+			Diff:     strings.Repeat(fmt.Sprintf("+data-%d: value\n", i), 200),
+		})
+	}
+
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+		},
+	}
+	helmClient := &fakeHelmClient{simulation: &helm.UpgradeSimulation{Changes: changes}}
+	githubClient := &fakeGitHubClient{}
+	c := New(helmClient, &fakeGitClient{}, githubClient, cfg)
+
+	update := &ChartUpdate{Release: &helm.Release{Chart: "nginx"}, CurrentVersion: "1.0.0", LatestVersion: "1.1.0"}
+	if err := c.processUpdate(context.Background(), chartRepo(t, update.Release.Chart), nil, update); err != nil {
+		t.Fatalf("processUpdate failed: %v", err)
+	}
+
+	if len(githubClient.created) != 1 {
+		t.Fatalf("Expected one PR, got %d", len(githubClient.created))
+	}
+	body := githubClient.created[0].GetBody()
+	if len(body) > maxPRBodyLength {
+		t.Errorf("Expected PR body within %d bytes, got %d", maxPRBodyLength, len(body))
+	}
+
+	comments := githubClient.comments[1]
+	if len(comments) == 0 {
+		t.Fatal("Expected overflow to be posted as comments")
+	}
+	for _, comment := range comments {
+		if len(comment) > maxPRBodyLength {
+			t.Errorf("Expected comment within %d bytes, got %d", maxPRBodyLength, len(comment))
+		}
+	}
+	if all := body + strings.Join(comments, ""); !strings.Contains(all, "ConfigMap/apps/config-39") {
+		t.Errorf("Expected the last manifest change to be posted")
+	}
+}
@@ -0,0 +1,109 @@
+package checker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// defaultBranchTemplate names update branches when no template is configured
+const defaultBranchTemplate = "update-{{.Chart}}-{{.Version}}"
+
+// maxBranchLength caps update branch names; longer names are truncated and
+// end in the update hash so they stay unique
+const maxBranchLength = 100
+
+// branchData is the data available to the branch name template
+type branchData struct {
+	Chart     string
+	Version   string
+	Timestamp string
+	Hash      string
+}
+
+// branchName renders the update branch name from the configured template
+func (c *Checker) branchName(update *ChartUpdate) (string, error) {
+	text := c.config.Checker.BranchTemplate
+	if text == "" {
+		text = defaultBranchTemplate
+	}
+	tmpl, err := template.New("branch").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse branch template: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(update.Release.Chart + "@" + update.LatestVersion))
+	data := branchData{
+		Chart:     update.Release.Chart,
+		Version:   update.LatestVersion,
+		Timestamp: c.now().UTC().Format("20060102150405"),
+		Hash:      hex.EncodeToString(sum[:])[:8],
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render branch template: %w", err)
+	}
+
+	name := sanitizeBranchName(b.String(), data.Hash)
+	if name == "" {
+		return "", fmt.Errorf("branch template rendered an empty name for %s %s", update.Release.Chart, update.LatestVersion)
+	}
+	return name, nil
+}
+
+// sanitizeBranchName turns name into a valid git branch name: characters git
+// forbids become dashes, path components may not start with a dot or end in
+// .lock, and names longer than maxBranchLength are cut and suffixed with hash
+func sanitizeBranchName(name, hash string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r <= ' ' || r == 0x7f:
+			b.WriteByte('-')
+		case strings.ContainsRune(`~^:?*[\`, r):
+			b.WriteByte('-')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	name = b.String()
+
+	// Sequences git rejects
+	for _, invalid := range []string{"..", "@{", "//"} {
+		for strings.Contains(name, invalid) {
+			name = strings.ReplaceAll(name, invalid, string(invalid[0])+"-")
+		}
+	}
+	for strings.Contains(name, "--") {
+		name = strings.ReplaceAll(name, "--", "-")
+	}
+
+	if len(name) > maxBranchLength {
+		suffix := "-" + hash
+		cut := name[:maxBranchLength-len(suffix)]
+		for !utf8.ValidString(cut) {
+			cut = cut[:len(cut)-1]
+		}
+		name = strings.TrimRight(cut, "-./") + suffix
+	}
+
+	components := strings.Split(name, "/")
+	kept := components[:0]
+	for _, component := range components {
+		component = strings.Trim(component, "-.")
+		component = strings.TrimSuffix(component, ".lock")
+		if component != "" {
+			kept = append(kept, component)
+		}
+	}
+	name = strings.Join(kept, "/")
+
+	if name == "@" {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,78 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marccoxall/helmchecker/internal/config"
+	"github.com/marccoxall/helmchecker/internal/helm"
+)
+
+func TestBranchName(t *testing.T) {
+	update := &ChartUpdate{Release: &helm.Release{Chart: "nginx"}, LatestVersion: "1.2.0"}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"", "update-nginx-1.2.0"},
+		{"deps/helm/{{.Chart}}-{{.Version}}", "deps/helm/nginx-1.2.0"},
+		{"{{.Chart}}-{{.Timestamp}}", "nginx-20240102030405"},
+		{"update-{{.Chart}}-{{.Hash}}", "update-nginx-bfdadb25"},
+	}
+
+	for _, tt := range tests {
+		c := New(nil, nil, nil, &config.Config{Checker: config.CheckerConfig{BranchTemplate: tt.template}})
+		c.now = func() time.Time { return time.Date(2024, 1, 2, 4, 4, 5, 0, time.FixedZone("CET", 3600)) }
+
+		name, err := c.branchName(update)
+		if err != nil {
+			t.Fatalf("%q: branchName failed: %v", tt.template, err)
+		}
+		if name != tt.expected {
+			t.Errorf("%q: expected branch %q, got %q", tt.template, tt.expected, name)
+		}
+	}
+
+	c := New(nil, nil, nil, &config.Config{Checker: config.CheckerConfig{BranchTemplate: "{{.Release}}"}})
+	if _, err := c.branchName(update); err == nil {
+		t.Errorf("Expected an unknown template field to fail")
+	}
+}
+
+func TestSanitizeBranchName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"update-nginx-1.2.0", "update-nginx-1.2.0"},
+		{"update-my chart-1.0.0+build.1", "update-my-chart-1.0.0+build.1"},
+		{"update-a~b^c:d?e*f[g\\h", "update-a-b-c-d-e-f-g-h"},
+		{"update-nginx-1..2", "update-nginx-1.-2"},
+		{"update-@{upstream}", "update-@-upstream}"},
+		{"/deps//helm/", "deps/helm"},
+		{"-.hidden/chart.lock", "hidden/chart"},
+		{"update-nginx-v2.", "update-nginx-v2"},
+		{"@", ""},
+	}
+
+	for _, tt := range tests {
+		if got := sanitizeBranchName(tt.name, "abcd1234"); got != tt.expected {
+			t.Errorf("sanitizeBranchName(%q): expected %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+
+	// Long names are cut and keep the hash so they stay distinct
+	long := "update-" + strings.Repeat("very-long-chart-name-", 10) + "1.0.0"
+	got := sanitizeBranchName(long, "abcd1234")
+	if len(got) > maxBranchLength || !strings.HasSuffix(got, "-abcd1234") {
+		t.Errorf("Expected a name of at most %d characters ending in the hash, got %q (%d)", maxBranchLength, got, len(got))
+	}
+
+	// Multi-byte characters are not split
+	got = sanitizeBranchName(strings.Repeat("é", 60), "abcd1234")
+	if !strings.HasSuffix(got, "-abcd1234") || strings.ContainsRune(got, '�') || !strings.HasPrefix(got, "é") {
+		t.Errorf("Expected a valid truncated name, got %q", got)
+	}
+}
@@ -0,0 +1,180 @@
+package checker
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// chartFileName is the name of the chart metadata file edited for an update
+const chartFileName = "Chart.yaml"
+
+// chartFileRef is the subset of a Chart.yaml used to find the file for a chart
+type chartFileRef struct {
+	Name         string `yaml:"name"`
+	Dependencies []struct {
+		Name string `yaml:"name"`
+	} `yaml:"dependencies"`
+}
+
+// references reports whether the chart file is the chart itself or depends on it
+func (r chartFileRef) references(chartName string) bool {
+	if r.Name == chartName {
+		return true
+	}
+	for _, dependency := range r.Dependencies {
+		if dependency.Name == chartName {
+			return true
+		}
+	}
+	return false
+}
+
+// findChartFile returns the repository-relative path of the Chart.yaml that
+// is, or depends on, the release's chart. A file in a directory named after
+// the release is preferred when several match.
+func findChartFile(repoPath, releaseName, chartName string) (string, error) {
+	var matches []string
+	err := filepath.WalkDir(repoPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != chartFileName {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var ref chartFileRef
+		if err := yaml.Unmarshal(data, &ref); err != nil {
+			// Not every file named Chart.yaml has to be valid
+			return nil
+		}
+		if ref.references(chartName) {
+			rel, err := filepath.Rel(repoPath, path)
+			if err != nil {
+				return err
+			}
+			matches = append(matches, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to search for %s: %w", chartFileName, err)
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no %s for chart %s found in repository", chartFileName, chartName)
+	}
+
+	sort.Strings(matches)
+	for _, match := range matches {
+		if filepath.Base(filepath.Dir(match)) == releaseName {
+			return match, nil
+		}
+	}
+	return matches[0], nil
+}
+
+// setChartVersion sets the version of chartName in a Chart.yaml: its own
+// version if the file is the chart itself, and the version of every matching
+// dependency. Only the edited values change; comments, ordering and formatting
+// of the rest of the file are kept as they are.
+func setChartVersion(content []byte, chartName, version string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", chartFileName, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s is not a mapping", chartFileName)
+	}
+	root := doc.Content[0]
+
+	var targets []*yaml.Node
+	if name := mappingValue(root, "name"); name != nil && name.Value == chartName {
+		if v := mappingValue(root, "version"); v != nil {
+			targets = append(targets, v)
+		}
+	}
+	if dependencies := mappingValue(root, "dependencies"); dependencies != nil && dependencies.Kind == yaml.SequenceNode {
+		for _, dependency := range dependencies.Content {
+			if name := mappingValue(dependency, "name"); name == nil || name.Value != chartName {
+				continue
+			}
+			if v := mappingValue(dependency, "version"); v != nil {
+				targets = append(targets, v)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%s has no version field for chart %s", chartFileName, chartName)
+	}
+
+	return replaceScalars(content, targets, version)
+}
+
+// mappingValue returns the value node of key in a mapping node, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// replaceScalars rewrites the given plain or quoted scalar nodes to value in
+// the original content, using their positions so nothing else is reformatted
+func replaceScalars(content []byte, nodes []*yaml.Node, value string) ([]byte, error) {
+	lines := strings.SplitAfter(string(content), "\n")
+
+	// Edit from the end so earlier positions stay valid
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Line != nodes[j].Line {
+			return nodes[i].Line > nodes[j].Line
+		}
+		return nodes[i].Column > nodes[j].Column
+	})
+
+	for _, node := range nodes {
+		if node.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("version at line %d is not a scalar", node.Line)
+		}
+
+		var old, replacement string
+		switch node.Style {
+		case 0:
+			old, replacement = node.Value, value
+		case yaml.DoubleQuotedStyle:
+			old, replacement = `"`+node.Value+`"`, `"`+value+`"`
+		case yaml.SingleQuotedStyle:
+			old, replacement = "'"+node.Value+"'", "'"+value+"'"
+		default:
+			return nil, fmt.Errorf("version at line %d uses an unsupported style", node.Line)
+		}
+
+		line := lines[node.Line-1]
+		start := node.Column - 1
+		if start+len(old) > len(line) || line[start:start+len(old)] != old {
+			return nil, fmt.Errorf("failed to locate version at line %d", node.Line)
+		}
+		lines[node.Line-1] = line[:start] + replacement + line[start+len(old):]
+	}
+
+	return []byte(strings.Join(lines, "")), nil
+}
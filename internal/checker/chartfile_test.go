@@ -0,0 +1,150 @@
+package checker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/marccoxall/helmchecker/internal/config"
+	"github.com/marccoxall/helmchecker/internal/helm"
+)
+
+const umbrellaChart = `# Platform services
+apiVersion: v2
+name: platform
+version: 0.3.0 # bumped by hand
+dependencies:
+  # Cache
+  - name: redis
+    version: "18.0.0"
+    repository: https://charts.example.com
+  - name: nginx
+    version: 1.0.0
+    repository: https://charts.example.com
+`
+
+// writeChartRepo creates a repository checkout containing the given files
+func writeChartRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+// chartRepo creates a repository checkout with a chart directory per chart
+func chartRepo(t *testing.T, charts ...string) string {
+	t.Helper()
+
+	files := make(map[string]string)
+	for _, chart := range charts {
+		files["charts/"+chart+"/Chart.yaml"] = "apiVersion: v2\nname: " + chart + "\nversion: 1.0.0\n"
+	}
+	return writeChartRepo(t, files)
+}
+
+func TestSetChartVersion(t *testing.T) {
+	updated, err := setChartVersion([]byte(umbrellaChart), "redis", "18.6.1")
+	if err != nil {
+		t.Fatalf("setChartVersion failed: %v", err)
+	}
+
+	expected := strings.Replace(umbrellaChart, `version: "18.0.0"`, `version: "18.6.1"`, 1)
+	if string(updated) != expected {
+		t.Errorf("Expected only the redis dependency version to change, got:\n%s", updated)
+	}
+
+	// The chart's own version is updated when the file is the chart itself
+	updated, err = setChartVersion([]byte(umbrellaChart), "platform", "0.4.0")
+	if err != nil {
+		t.Fatalf("setChartVersion failed: %v", err)
+	}
+	if !strings.Contains(string(updated), "version: 0.4.0 # bumped by hand\n") {
+		t.Errorf("Expected the chart version to change with its comment kept, got:\n%s", updated)
+	}
+
+	if _, err := setChartVersion([]byte(umbrellaChart), "postgresql", "13.0.0"); err == nil {
+		t.Errorf("Expected an error for a chart the file does not reference")
+	}
+}
+
+func TestFindChartFile(t *testing.T) {
+	repoPath := writeChartRepo(t, map[string]string{
+		"apps/platform/Chart.yaml": umbrellaChart,
+		"apps/cache/Chart.yaml":    "apiVersion: v2\nname: cache\nversion: 1.0.0\ndependencies:\n  - name: redis\n    version: 18.0.0\n",
+		"apps/broken/Chart.yaml":   ": not yaml [",
+		".git/Chart.yaml":          "name: redis\n",
+		"README.md":                "redis",
+	})
+
+	path, err := findChartFile(repoPath, "web", "redis")
+	if err != nil {
+		t.Fatalf("findChartFile failed: %v", err)
+	}
+	if path != "apps/cache/Chart.yaml" {
+		t.Errorf("Expected the first matching chart file, got %s", path)
+	}
+
+	// A directory named after the release is preferred
+	path, err = findChartFile(repoPath, "platform", "redis")
+	if err != nil {
+		t.Fatalf("findChartFile failed: %v", err)
+	}
+	if path != "apps/platform/Chart.yaml" {
+		t.Errorf("Expected the release's chart file, got %s", path)
+	}
+
+	if _, err := findChartFile(repoPath, "db", "postgresql"); err == nil || !strings.Contains(err.Error(), "no Chart.yaml for chart postgresql") {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestProcessUpdateEditsChartFile(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+		},
+	}
+	repoPath := writeChartRepo(t, map[string]string{"apps/platform/Chart.yaml": umbrellaChart})
+	update := &ChartUpdate{Release: &helm.Release{Name: "platform", Chart: "redis"}, CurrentVersion: "18.0.0", LatestVersion: "18.6.1"}
+
+	gitClient := &fakeGitClient{}
+	githubClient := &fakeGitHubClient{}
+	c := New(&fakeHelmClient{}, gitClient, githubClient, cfg)
+	if err := c.processUpdate(context.Background(), repoPath, nil, update); err != nil {
+		t.Fatalf("processUpdate failed: %v", err)
+	}
+
+	content, ok := gitClient.files["apps/platform/Chart.yaml"]
+	if !ok {
+		t.Fatalf("Expected apps/platform/Chart.yaml to be written, got %v", gitClient.files)
+	}
+	if !strings.Contains(content, `version: "18.6.1"`) || !strings.Contains(content, "# Cache\n") {
+		t.Errorf("Expected the dependency version to be updated in place, got:\n%s", content)
+	}
+
+	// Without a chart file the update is refused before anything is committed
+	gitClient = &fakeGitClient{}
+	githubClient = &fakeGitHubClient{}
+	c = New(&fakeHelmClient{}, gitClient, githubClient, cfg)
+	update = &ChartUpdate{Release: &helm.Release{Name: "db", Chart: "postgresql"}, CurrentVersion: "13.0.0", LatestVersion: "13.1.0"}
+	err := c.processUpdate(context.Background(), repoPath, nil, update)
+	if err == nil || !strings.Contains(err.Error(), "no Chart.yaml for chart postgresql") {
+		t.Errorf("Expected a missing chart file error, got %v", err)
+	}
+	if len(gitClient.commits) != 0 || len(githubClient.created) != 0 {
+		t.Errorf("Expected no commit or PR without a chart file, got %d commits and %d PRs", len(gitClient.commits), len(githubClient.created))
+	}
+}
@@ -2,46 +2,176 @@ package checker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand/v2"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	gogit "github.com/go-git/go-git/v5"
+	gh "github.com/google/go-github/v56/github"
 	"github.com/marccoxall/helmchecker/internal/config"
-	gitclient "github.com/marccoxall/helmchecker/internal/git"
-	"github.com/marccoxall/helmchecker/internal/github"
 	"github.com/marccoxall/helmchecker/internal/helm"
+	"github.com/marccoxall/helmchecker/internal/hooks"
+	"github.com/marccoxall/helmchecker/internal/policy"
+	"github.com/marccoxall/helmchecker/internal/state"
 )
 
+// HelmClient is the subset of the Helm client used by the checker
+type HelmClient interface {
+	ListReleases(ctx context.Context) ([]*helm.Release, error)
+	UpdateRepositories(ctx context.Context) error
+	GetLatestChartVersion(ctx context.Context, chartName, repoURL, channel string, exclude []*regexp.Regexp, comparator helm.VersionComparator) (*helm.ChartVersion, error)
+	GetChartVersion(ctx context.Context, chartName, repoURL, version string) (*helm.ChartVersion, error)
+	SimulateUpgrade(ctx context.Context, release *helm.Release, targetVersion string) (*helm.UpgradeSimulation, error)
+	IndexDigest(ctx context.Context) (string, error)
+}
+
+// GitClient is the subset of the Git client used by the checker
+type GitClient interface {
+	CloneRepository(ctx context.Context) (string, *gogit.Repository, error)
+	RemoveClone(repoPath string, failed bool) error
+	FetchBranch(ctx context.Context, repo *gogit.Repository, branchName string) error
+	CheckoutBranch(repo *gogit.Repository, branchName string) error
+	CreateBranch(repo *gogit.Repository, branchName string) error
+	CommitChanges(repo *gogit.Repository, message string) error
+	PushBranch(repo *gogit.Repository, branchName string) error
+	DeleteRemoteBranch(repo *gogit.Repository, branchName string) error
+	UpdateFile(repoPath, filePath, content string) error
+}
+
+// GitHubClient is the subset of the GitHub client used by the checker
+type GitHubClient interface {
+	CheckIfPRExists(ctx context.Context, owner, repo, head, base string) (*gh.PullRequest, error)
+	CreatePullRequest(ctx context.Context, owner, repo, title, body, head, base string) (*gh.PullRequest, error)
+	UpdatePullRequest(ctx context.Context, owner, repo string, number int, title, body string) (*gh.PullRequest, error)
+	RequestReviewers(ctx context.Context, owner, repo string, number int, reviewers []string) error
+	FindPRByLabel(ctx context.Context, owner, repo, label string) (*gh.PullRequest, error)
+	AddLabels(ctx context.Context, owner, repo string, number int, labels []string) error
+	CreateComment(ctx context.Context, owner, repo string, number int, body string) error
+	CreateReview(ctx context.Context, owner, repo string, number int, body string) error
+	FindOpenIssue(ctx context.Context, owner, repo, title string) (*gh.Issue, error)
+	ListOpenPullRequests(ctx context.Context, owner, repo string) ([]*gh.PullRequest, error)
+	CreateIssue(ctx context.Context, owner, repo, title, body string) (*gh.Issue, error)
+}
+
 // Checker represents the main chart checker
 type Checker struct {
-	helmClient   *helm.Client
-	gitClient    *gitclient.Client
-	githubClient *github.Client
-	config       *config.Config
+	helmClient      HelmClient
+	gitClient       GitClient
+	githubClient    GitHubClient
+	config          *config.Config
+	versionDenylist []*regexp.Regexp
+	policyEvaluator policy.Evaluator
+	hooks           []hooks.Hook
+	state           *state.State
+	invalidVersions []*ErrInvalidVersion
+	result          *RunResult
+	chartList       *config.ChartList
+	// openPRs holds the open pull requests fetched at the start of a run
+	// when prefetching is enabled; nil means they are looked up per update
+	openPRs []*gh.PullRequest
+
+	// sleep waits for the given duration or until the context is done
+	sleep func(ctx context.Context, d time.Duration) error
+	// now returns the current time
+	now func() time.Time
 }
 
+// Chart annotations (or release labels, which take precedence) read by the checker
+const (
+	// IgnoreAnnotation set to "true" makes the checker ignore the chart
+	IgnoreAnnotation = "helmchecker.io/ignore"
+	// PolicyAnnotation sets the update policy for the chart: update, skip or dry-run
+	PolicyAnnotation = "helmchecker.io/policy"
+)
+
 // ChartUpdate represents a chart that needs to be updated
 type ChartUpdate struct {
 	Release        *helm.Release
 	CurrentVersion string
 	LatestVersion  string
 	Repository     string
+	Change         VersionChange
+	Reasons        []UpdateReason
+	// PullRequestURL is the pull request opened for the update, if any
+	PullRequestURL string
+	// Target is the repository index entry of the latest version, if known
+	Target *helm.ChartVersion
 }
 
 // New creates a new checker instance
-func New(helmClient *helm.Client, gitClient *gitclient.Client, githubClient *github.Client, cfg *config.Config) *Checker {
+func New(helmClient HelmClient, gitClient GitClient, githubClient GitHubClient, cfg *config.Config) *Checker {
+	var denylist []*regexp.Regexp
+	for _, pattern := range cfg.Checker.ExcludeVersionPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Printf("Warning: ignoring invalid version exclude pattern %q: %v", pattern, err)
+			continue
+		}
+		denylist = append(denylist, re)
+	}
+
+	var evaluator policy.Evaluator
+	if len(cfg.Checker.Policy.Files) > 0 {
+		evaluator = policy.NewOPAEvaluator(cfg.Checker.Policy.OPABinary, cfg.Checker.Policy.Files, cfg.Checker.Policy.Query)
+	}
+
+	var postUpdateHooks []hooks.Hook
+	for _, command := range cfg.Checker.PostUpdateCommands {
+		postUpdateHooks = append(postUpdateHooks, hooks.NewCommandHook(command))
+	}
+	for _, url := range cfg.Checker.PostUpdateWebhooks {
+		postUpdateHooks = append(postUpdateHooks, hooks.NewWebhookHook(url))
+	}
+
 	return &Checker{
-		helmClient:   helmClient,
-		gitClient:    gitClient,
-		githubClient: githubClient,
-		config:       cfg,
+		helmClient:      helmClient,
+		gitClient:       gitClient,
+		githubClient:    githubClient,
+		config:          cfg,
+		versionDenylist: denylist,
+		policyEvaluator: evaluator,
+		hooks:           postUpdateHooks,
+		result:          &RunResult{},
+		sleep:           sleepContext,
+		now:             time.Now,
 	}
 }
 
-// Run executes the chart checking process
-func (c *Checker) Run(ctx context.Context) error {
+// Run executes the chart checking process and returns a summary of its outcome
+func (c *Checker) Run(ctx context.Context) (*RunResult, error) {
+	c.result = &RunResult{}
+	err := c.run(ctx)
+	return c.result, err
+}
+
+// run performs a single check, recording its outcome in c.result
+func (c *Checker) run(ctx context.Context) error {
+	if err := c.splay(ctx); err != nil {
+		return err
+	}
+
+	c.invalidVersions = nil
+	defer c.reportInvalidVersions()
+
+	// Reload the chart list so edits apply without a redeploy
+	if path := c.config.Checker.ChartListFile; path != "" {
+		list, err := config.LoadChartList(path)
+		if err != nil {
+			return err
+		}
+		c.chartList = list
+	}
+
 	log.Println("Starting chart update check...")
 
 	// Get all installed releases
@@ -52,6 +182,8 @@ func (c *Checker) Run(ctx context.Context) error {
 
 	log.Printf("Found %d installed releases", len(releases))
 
+	releases = c.filterNamespaces(releases)
+
 	// Check for updates
 	updates, err := c.checkForUpdates(ctx, releases)
 	if err != nil {
@@ -64,10 +196,28 @@ func (c *Checker) Run(ctx context.Context) error {
 	}
 
 	log.Printf("Found %d chart updates", len(updates))
+	c.result.UpdatesFound = len(updates)
+	c.result.Updates = updates
 
-	// Process updates if not in dry run mode
+	// Process updates if not in dry run mode and inside the maintenance window
 	if !c.config.Checker.DryRun {
-		return c.processUpdates(ctx, updates)
+		inWindow, err := c.config.Checker.MaintenanceWindow.Contains(c.now())
+		if err != nil {
+			return fmt.Errorf("failed to evaluate maintenance window: %w", err)
+		}
+		if inWindow {
+			return c.processUpdates(ctx, updates)
+		}
+
+		log.Printf("Outside maintenance window; deferring %d pull request(s)", len(updates))
+		c.result.Skipped += len(updates)
+		for _, update := range updates {
+			log.Printf("DEFERRED: Would update %s from %s to %s",
+				update.Release.Chart,
+				update.CurrentVersion,
+				update.LatestVersion)
+		}
+		return nil
 	}
 
 	// In dry run mode, just log what would be updated
@@ -81,65 +231,400 @@ func (c *Checker) Run(ctx context.Context) error {
 	return nil
 }
 
+// splay waits a random delay up to the configured startup splay, spreading
+// load when many instances start at the same time
+func (c *Checker) splay(ctx context.Context) error {
+	if c.config.Checker.StartupSplay <= 0 {
+		return nil
+	}
+
+	delay := rand.N(c.config.Checker.StartupSplay)
+	log.Printf("Waiting %s before starting (startup splay up to %s)", delay, c.config.Checker.StartupSplay)
+
+	if err := c.sleep(ctx, delay); err != nil {
+		return fmt.Errorf("startup splay interrupted: %w", err)
+	}
+	return nil
+}
+
+// sleepContext sleeps for d, returning early with the context's error if it is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // checkForUpdates checks all releases for available updates
 func (c *Checker) checkForUpdates(ctx context.Context, releases []*helm.Release) ([]*ChartUpdate, error) {
 	var updates []*ChartUpdate
 
 	// Update repository indexes
 	if err := c.helmClient.UpdateRepositories(ctx); err != nil {
+		if errors.Is(err, helm.ErrNoRepositories) {
+			warning := fmt.Sprintf("%v; no chart updates can be found. Add the repositories your charts come from with `helm repo add <name> <url>` or mount a repositories.yaml at $HELM_CONFIG_HOME", err)
+			log.Printf("Warning: %s", warning)
+			c.result.Warnings = append(c.result.Warnings, warning)
+			return nil, nil
+		}
 		log.Printf("Warning: failed to update repositories: %v", err)
 	}
 
+	if c.config.Checker.NegativeCacheTTL > 0 {
+		c.prepareVersionCache(ctx)
+	}
+
+	var candidates []*helm.Release
 	for _, release := range releases {
 		// Skip if chart is in exclude list
 		if c.isExcluded(release.Chart) {
+			c.result.Skipped++
 			continue
 		}
 
 		// Skip if include list is specified and chart is not in it
-		if len(c.config.Checker.IncludeCharts) > 0 && !c.isIncluded(release.Chart) {
+		if !c.isIncluded(release.Chart) {
+			c.result.Skipped++
+			continue
+		}
+
+		if ignored, reason := annotationSkip(release); ignored {
+			log.Printf("Skipping %s: %s", release.Chart, reason)
+			c.result.Skipped++
+			continue
+		}
+
+		if c.listPolicy(release.Chart) == config.PolicySkip {
+			log.Printf("Skipping %s: chart list policy is %s", release.Chart, config.PolicySkip)
+			c.result.Skipped++
+			continue
+		}
+
+		if err := c.verifySources(release); err != nil {
+			log.Printf("Warning: skipping %s: %v", release.Chart, err)
+			c.result.Skipped++
 			continue
 		}
 
 		log.Printf("Checking chart %s (current: %s)", release.Chart, release.Version)
 
-		// Get latest version from repository
-		latest, err := c.helmClient.GetLatestChartVersion(ctx, release.Chart, release.Repository)
-		if err != nil {
-			log.Printf("Warning: failed to get latest version for %s: %v", release.Chart, err)
+		c.reportDependencyPins(release)
+		candidates = append(candidates, release)
+	}
+
+	// Resolve latest versions concurrently, keeping results in release order
+	resolved := c.resolveLatestVersions(ctx, candidates)
+	c.result.Checked += len(candidates)
+
+	for i, release := range candidates {
+		latest := resolved[i]
+		if latest == nil {
 			continue
 		}
 
 		// Compare versions
-		if c.isNewerVersion(latest.Version, release.Version) {
-			updates = append(updates, &ChartUpdate{
+		change, err := c.classifyVersionChange(release.Chart, latest.Version, release.Version)
+		if err != nil {
+			var invalid *ErrInvalidVersion
+			if errors.As(err, &invalid) {
+				c.invalidVersions = append(c.invalidVersions, invalid)
+			}
+			log.Printf("Warning: %v", err)
+			c.result.fail(release.Chart, err)
+			continue
+		}
+		if change == VersionUnchanged {
+			c.checkAppVersionDrift(release, latest)
+		}
+		_, pinned := c.config.Checker.PinnedVersions[release.Chart]
+		if pinned && (change == VersionUnchanged || change == VersionDowngrade) {
+			log.Printf("Skipping %s: installed version %s is not older than pinned version %s", release.Chart, release.Version, latest.Version)
+			continue
+		}
+		switch change {
+		case VersionUnchanged:
+			continue
+		case VersionDowngrade:
+			log.Printf("DOWNGRADE: refusing to update %s from %s to lower version %s; the repository may have been re-indexed or the version yanked",
+				release.Chart,
+				release.Version,
+				latest.Version)
+			c.result.Skipped++
+			c.result.Blocked = append(c.result.Blocked, &ChartUpdate{
 				Release:        release,
 				CurrentVersion: release.Version,
 				LatestVersion:  latest.Version,
 				Repository:     release.Repository,
+				Change:         change,
+				Reasons:        []UpdateReason{ReasonDowngradeBlocked},
 			})
+			continue
 		}
+
+		update := &ChartUpdate{
+			Release:        release,
+			CurrentVersion: release.Version,
+			LatestVersion:  latest.Version,
+			Repository:     release.Repository,
+			Change:         change,
+			Reasons:        updateReasons(latest),
+			Target:         latest,
+		}
+		if _, manual := c.config.Checker.ManualMajorCharts[release.Chart]; manual && change == VersionMajor {
+			update.addReason(ReasonManualMigration)
+		}
+		if pinned {
+			update.addReason(ReasonPinned)
+		}
+		updates = append(updates, update)
 	}
 
 	return updates, nil
 }
 
+// resolveLatestVersions looks up the latest version of each release's chart
+// using a bounded worker pool. The result at index i belongs to releases[i] and
+// is nil when the lookup failed; failures are logged without aborting the others.
+func (c *Checker) resolveLatestVersions(ctx context.Context, releases []*helm.Release) []*helm.ChartVersion {
+	results := make([]*helm.ChartVersion, len(releases))
+	errs := make([]error, len(releases))
+
+	concurrency := c.config.Checker.ResolveConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, release := range releases {
+		// Skip the lookup for charts recently found to be up to date
+		if version, ok := c.cachedLatest(ctx, release); ok && sameVersion(version, release.Version) {
+			log.Printf("Skipping lookup for %s: %s was the latest version when last checked", release.Chart, version)
+			results[i] = &helm.ChartVersion{Version: version, Repository: release.Repository}
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, release *helm.Release) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if pinned, ok := c.config.Checker.PinnedVersions[release.Chart]; ok {
+				target, err := c.helmClient.GetChartVersion(ctx, release.Chart, release.Repository, pinned)
+				if err != nil {
+					log.Printf("Warning: failed to get pinned version %s for %s: %v", pinned, release.Chart, err)
+					errs[i] = fmt.Errorf("failed to get pinned version %s: %w", pinned, err)
+					return
+				}
+				results[i] = target
+				return
+			}
+
+			latest, err := c.helmClient.GetLatestChartVersion(ctx, release.Chart, release.Repository, c.config.Checker.ChannelFor(release.Chart), c.versionDenylist, c.indexComparator(release.Chart))
+			if err != nil {
+				log.Printf("Warning: failed to get latest version for %s: %v", release.Chart, err)
+				errs[i] = fmt.Errorf("failed to get latest version: %w", err)
+				return
+			}
+			results[i] = latest
+		}(i, release)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			c.result.fail(releases[i].Chart, err)
+		}
+	}
+
+	c.recordLatestVersions(ctx, releases, results)
+
+	return results
+}
+
+// checkAppVersionDrift flags a release whose chart version is still the
+// latest but whose index entry now reports a different appVersion, meaning
+// the chart was republished under the same version
+func (c *Checker) checkAppVersionDrift(release *helm.Release, latest *helm.ChartVersion) {
+	if latest.AppVersion == "" || release.AppVersion == "" {
+		return
+	}
+	if strings.TrimPrefix(latest.AppVersion, "v") == strings.TrimPrefix(release.AppVersion, "v") {
+		return
+	}
+
+	log.Printf("Warning: %s %s was republished: release %s runs appVersion %s but the repository now lists appVersion %s for the same chart version",
+		release.Chart, release.Version, release.Name, release.AppVersion, latest.AppVersion)
+	c.result.Drifted = append(c.result.Drifted, AppVersionDrift{
+		Release:             release,
+		Version:             release.Version,
+		InstalledAppVersion: release.AppVersion,
+		IndexAppVersion:     latest.AppVersion,
+	})
+}
+
+// prepareVersionCache loads the cache of up-to-date lookups, dropping it if a
+// repository refresh changed the indexes it was built from
+func (c *Checker) prepareVersionCache(ctx context.Context) {
+	st, err := c.openState(ctx)
+	if err != nil {
+		log.Printf("Warning: latest version cache disabled: %v", err)
+		return
+	}
+
+	digest, err := c.helmClient.IndexDigest(ctx)
+	if err != nil {
+		log.Printf("Warning: failed to fingerprint repository indexes: %v", err)
+	}
+	invalidated, err := st.InvalidateLatest(ctx, digest)
+	if err != nil {
+		log.Printf("Warning: failed to clear latest version cache: %v", err)
+		return
+	}
+	if invalidated {
+		log.Println("Repository indexes changed, cleared latest version cache")
+	}
+}
+
+// versionCacheKey identifies a chart and its channel, or its pinned version,
+// in the latest version cache
+func (c *Checker) versionCacheKey(release *helm.Release) string {
+	key := release.Chart + "@" + release.Repository + "#" + c.config.Checker.ChannelFor(release.Chart)
+	if pinned, ok := c.config.Checker.PinnedVersions[release.Chart]; ok {
+		key += "=" + pinned
+	}
+	return key
+}
+
+// cachedLatest returns the cached latest version of a release's chart, if any
+func (c *Checker) cachedLatest(ctx context.Context, release *helm.Release) (string, bool) {
+	if c.state == nil {
+		return "", false
+	}
+	version, ok, err := c.state.CachedLatest(ctx, c.versionCacheKey(release), c.config.Checker.NegativeCacheTTL, c.now())
+	if err != nil {
+		log.Printf("Warning: failed to read cached latest version of %s: %v", release.Chart, err)
+		return "", false
+	}
+	return version, ok
+}
+
+// recordLatestVersions caches lookups that found the release already on the latest version
+func (c *Checker) recordLatestVersions(ctx context.Context, releases []*helm.Release, results []*helm.ChartVersion) {
+	if c.state == nil || c.config.Checker.NegativeCacheTTL <= 0 {
+		return
+	}
+
+	for i, release := range releases {
+		if results[i] == nil || !sameVersion(results[i].Version, release.Version) {
+			continue
+		}
+		if _, ok := c.cachedLatest(ctx, release); ok {
+			continue
+		}
+		if err := c.state.RecordLatest(ctx, c.versionCacheKey(release), release.Version, c.now(), c.config.Checker.NegativeCacheTTL); err != nil {
+			log.Printf("Warning: failed to cache latest version of %s: %v", release.Chart, err)
+		}
+	}
+}
+
+// redisKeyPrefix namespaces the checker's keys in a shared Redis
+const redisKeyPrefix = "helmchecker:"
+
+// openState opens the persisted run state once per checker
+func (c *Checker) openState(ctx context.Context) (*state.State, error) {
+	if c.state != nil {
+		return c.state, nil
+	}
+
+	store, err := c.stateStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state store: %w", err)
+	}
+	st := state.New(store)
+
+	// Earlier versions kept all state in the state file itself
+	if c.config.Checker.StateBackend != config.StateBackendRedis {
+		if err := st.MigrateLegacy(ctx, c.config.Checker.StateFile, c.config.Checker.BumpCooldown, c.now()); err != nil {
+			log.Printf("Warning: failed to migrate state file: %v", err)
+		}
+	}
+
+	c.state = st
+	return st, nil
+}
+
+// stateStore creates the store for the configured state backend
+func (c *Checker) stateStore() (state.Store, error) {
+	if c.config.Checker.StateBackend == config.StateBackendRedis {
+		return state.NewRedisStore(c.config.Checker.RedisURL, redisKeyPrefix)
+	}
+	return state.NewFileStore(c.config.Checker.StateDirectory()), nil
+}
+
 // processUpdates processes the chart updates by creating branches and PRs
 func (c *Checker) processUpdates(ctx context.Context, updates []*ChartUpdate) error {
+	// Skip charts bumped too recently
+	if c.config.Checker.BumpCooldown > 0 {
+		if _, err := c.openState(ctx); err != nil {
+			return err
+		}
+
+		eligible, err := c.filterCooldown(ctx, updates)
+		if err != nil {
+			return err
+		}
+		c.result.Skipped += len(updates) - len(eligible)
+		updates = eligible
+		if len(updates) == 0 {
+			log.Println("All chart updates are within their bump cooldown")
+			return nil
+		}
+	}
+
 	// Clone the repository
 	repoPath, repo, err := c.gitClient.CloneRepository(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to clone repository: %w", err)
 	}
+	failed := false
 	defer func() {
-		if err := os.RemoveAll(repoPath); err != nil {
+		if err := c.gitClient.RemoveClone(repoPath, failed); err != nil {
 			log.Printf("Warning: failed to clean up temp directory %s: %v", repoPath, err)
 		}
 	}()
 
-	for _, update := range updates {
-		if err := c.processUpdate(ctx, repoPath, repo, update); err != nil {
+	c.openPRs = nil
+	if c.config.Checker.PrefetchOpenPRs {
+		c.prefetchOpenPRs(ctx)
+	}
+
+	if c.config.Checker.BatchUpdates {
+		failed = c.processGroupedUpdates(ctx, repoPath, repo, updates)
+		return nil
+	}
+
+	for i, update := range updates {
+		if ctx.Err() != nil {
+			log.Printf("Shutdown requested, skipping %d remaining update(s)", len(updates)-i)
+			c.result.Skipped += len(updates) - i
+			return nil
+		}
+
+		// Let an update that has started finish even if shutdown is requested meanwhile
+		updateCtx, cancel := c.gracefulContext(ctx)
+		err := c.processUpdate(updateCtx, repoPath, repo, update)
+		cancel()
+		if err != nil {
 			log.Printf("Failed to process update for %s: %v", update.Release.Chart, err)
+			c.result.fail(update.Release.Chart, err)
+			failed = true
 			continue
 		}
 	}
@@ -147,27 +632,219 @@ func (c *Checker) processUpdates(ctx context.Context, updates []*ChartUpdate) er
 	return nil
 }
 
+// gracefulContext returns a context that is not cancelled along with ctx, but
+// only once the shutdown grace period has passed after ctx is done. Without a
+// grace period it is cancelled right away.
+func (c *Checker) gracefulContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	graceCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-graceCtx.Done():
+			return
+		}
+
+		if c.config.Checker.ShutdownGracePeriod <= 0 {
+			cancel()
+			return
+		}
+		if err := c.sleep(graceCtx, c.config.Checker.ShutdownGracePeriod); err == nil {
+			log.Printf("Warning: shutdown grace period of %s expired, aborting in-flight update", c.config.Checker.ShutdownGracePeriod)
+		}
+		cancel()
+	}()
+
+	return graceCtx, cancel
+}
+
+// filterCooldown drops updates for charts whose last bump is within the cooldown
+func (c *Checker) filterCooldown(ctx context.Context, updates []*ChartUpdate) ([]*ChartUpdate, error) {
+	now := c.now()
+
+	var filtered []*ChartUpdate
+	for _, update := range updates {
+		inCooldown, err := c.state.InCooldown(ctx, update.Release.Chart, c.config.Checker.BumpCooldown, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check cooldown of %s: %w", update.Release.Chart, err)
+		}
+		if inCooldown {
+			last, _, _ := c.state.LastBump(ctx, update.Release.Chart)
+			log.Printf("Skipping %s %s: last bumped %s, within cooldown of %s",
+				update.Release.Chart,
+				update.LatestVersion,
+				last.Format(time.RFC3339),
+				c.config.Checker.BumpCooldown)
+			continue
+		}
+		filtered = append(filtered, update)
+	}
+	return filtered, nil
+}
+
+// recordBump persists the time an update PR was opened for the chart
+func (c *Checker) recordBump(ctx context.Context, update *ChartUpdate) {
+	if c.state == nil || c.config.Checker.BumpCooldown <= 0 {
+		return
+	}
+
+	if err := c.state.RecordBump(ctx, update.Release.Chart, c.now(), c.config.Checker.BumpCooldown); err != nil {
+		log.Printf("Warning: failed to save state: %v", err)
+	}
+}
+
+// preparedUpdate is an update that passed all checks, with what is needed to
+// apply it and open its pull request
+type preparedUpdate struct {
+	update     *ChartUpdate
+	branchName string
+	chartFile  string
+	baseBranch string
+	reviewers  []string
+	simulation *helm.UpgradeSimulation
+	violations []policy.Violation
+}
+
 // processUpdate processes a single chart update
 func (c *Checker) processUpdate(ctx context.Context, repoPath string, repo *gogit.Repository, update *ChartUpdate) error {
-	branchName := fmt.Sprintf("update-%s-%s", update.Release.Chart, update.LatestVersion)
+	prepared, err := c.prepareUpdate(ctx, repoPath, update)
+	if err != nil || prepared == nil {
+		return err
+	}
+	return c.applyUpdate(ctx, repoPath, repo, prepared)
+}
+
+// prepareUpdate runs the policy, existing pull request and upgrade checks for
+// an update without touching the repository. It returns nil when the update is
+// skipped or handled otherwise, such as by a migration issue.
+func (c *Checker) prepareUpdate(ctx context.Context, repoPath string, update *ChartUpdate) (*preparedUpdate, error) {
+	branchName, err := c.branchName(update)
+	if err != nil {
+		return nil, err
+	}
 	
 	log.Printf("Processing update for %s: %s -> %s", 
 		update.Release.Chart, 
 		update.CurrentVersion, 
 		update.LatestVersion)
 
+	if releaseSetting(update.Release, PolicyAnnotation) == config.PolicyDryRun {
+		log.Printf("DRY RUN (%s annotation): Would update %s from %s to %s",
+			PolicyAnnotation,
+			update.Release.Chart,
+			update.CurrentVersion,
+			update.LatestVersion)
+		c.result.Skipped++
+		return nil, nil
+	}
+
+	if c.listPolicy(update.Release.Chart) == config.PolicyDryRun {
+		log.Printf("DRY RUN (chart list policy): Would update %s from %s to %s",
+			update.Release.Chart,
+			update.CurrentVersion,
+			update.LatestVersion)
+		c.result.Skipped++
+		return nil, nil
+	}
+
+	// Majors needing a manual migration get an informational issue, not a bump
+	if update.HasReason(ReasonManualMigration) {
+		return nil, c.openMigrationIssue(ctx, update)
+	}
+
+	// Locate the chart file to edit; without one there is nothing to update
+	chartFile, err := findChartFile(repoPath, update.Release.Name, update.Release.Chart)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply any directory-scoped rule for the chart's location
+	baseBranch := c.config.Git.Branch
+	var reviewers []string
+	if rule := c.matchDirectoryRule(chartFile); rule != nil {
+		switch rule.Policy {
+		case config.PolicySkip:
+			log.Printf("Skipping %s: directory rule for %s has policy %s", update.Release.Chart, rule.PathPrefix, rule.Policy)
+			c.result.Skipped++
+			return nil, nil
+		case config.PolicyDryRun:
+			log.Printf("DRY RUN (directory rule %s): Would update %s from %s to %s",
+				rule.PathPrefix,
+				update.Release.Chart,
+				update.CurrentVersion,
+				update.LatestVersion)
+			c.result.Skipped++
+			return nil, nil
+		}
+
+		if rule.TargetBranch != "" {
+			baseBranch = rule.TargetBranch
+		}
+		reviewers = rule.Reviewers
+	}
+	reviewers = mergeReviewers(reviewers, c.maintainerReviewers(update.Release))
+
 	// Check if PR already exists
-	existingPR, err := c.githubClient.CheckIfPRExists(ctx, 
-		c.config.GitHub.Owner, 
-		c.config.GitHub.Repo, 
-		branchName)
+	existingPR, err := c.findExistingPR(ctx, update, branchName, baseBranch)
 	if err != nil {
-		return fmt.Errorf("failed to check for existing PR: %w", err)
+		return nil, fmt.Errorf("failed to check for existing PR: %w", err)
 	}
 
 	if existingPR != nil {
 		log.Printf("PR already exists for %s: %s", update.Release.Chart, *existingPR.HTMLURL)
-		return nil
+		c.result.Skipped++
+		return nil, nil
+	}
+
+	// Render the upgrade and check it against policies before touching the repository
+	simulation := c.simulateUpgrade(ctx, update)
+	if simulation != nil && len(simulation.CRDs) > 0 {
+		update.addReason(ReasonCRDs)
+		log.Printf("Warning: %s %s ships %d CRD(s) that Helm will not upgrade; changes may need to be applied manually",
+			update.Release.Chart, update.LatestVersion, len(simulation.CRDs))
+	}
+	if simulation != nil && len(simulation.RemovedValues) > 0 {
+		update.addReason(ReasonRemovedValues)
+		log.Printf("Warning: %s %s no longer defines values set on release %s: %s",
+			update.Release.Chart, update.LatestVersion, update.Release.Name, strings.Join(simulation.RemovedValues, ", "))
+	}
+	violations, err := c.evaluatePolicies(ctx, update, simulation)
+	if err != nil {
+		if c.config.Checker.Policy.Mode != config.PolicyModeWarn {
+			return nil, fmt.Errorf("failed to evaluate policies: %w", err)
+		}
+		log.Printf("Warning: failed to evaluate policies for %s: %v", update.Release.Chart, err)
+	}
+	if len(violations) > 0 && c.config.Checker.Policy.Mode != config.PolicyModeWarn {
+		for _, violation := range violations {
+			log.Printf("Policy violation for %s %s: %s", update.Release.Chart, update.LatestVersion, violation.Message)
+		}
+		return nil, fmt.Errorf("upgrade blocked by %d policy violation(s)", len(violations))
+	}
+
+	return &preparedUpdate{
+		update:     update,
+		branchName: branchName,
+		chartFile:  chartFile,
+		baseBranch: baseBranch,
+		reviewers:  reviewers,
+		simulation: simulation,
+		violations: violations,
+	}, nil
+}
+
+// applyUpdate edits the chart on a new branch and opens its pull request
+func (c *Checker) applyUpdate(ctx context.Context, repoPath string, repo *gogit.Repository, prepared *preparedUpdate) error {
+	update, branchName, baseBranch := prepared.update, prepared.branchName, prepared.baseBranch
+	simulation, violations := prepared.simulation, prepared.violations
+
+	// Start from the latest commit of the base branch; the clone may be stale
+	// after earlier updates or if the branch moved since it was made
+	if err := c.gitClient.FetchBranch(ctx, repo, baseBranch); err != nil {
+		return fmt.Errorf("failed to update base branch: %w", err)
+	}
+	if err := c.gitClient.CheckoutBranch(repo, baseBranch); err != nil {
+		return fmt.Errorf("failed to checkout base branch: %w", err)
 	}
 
 	// Create a new branch
@@ -176,7 +853,7 @@ func (c *Checker) processUpdate(ctx context.Context, repoPath string, repo *gogi
 	}
 
 	// Update the chart files
-	if err := c.updateChartFiles(repoPath, update); err != nil {
+	if err := c.updateChartFiles(repoPath, prepared.chartFile, update); err != nil {
 		return fmt.Errorf("failed to update chart files: %w", err)
 	}
 
@@ -203,6 +880,15 @@ func (c *Checker) processUpdate(ctx context.Context, repoPath string, repo *gogi
 		update.Release.Chart, 
 		update.CurrentVersion, 
 		update.LatestVersion)
+	prBody += reasonsSection(update)
+	prBody += releaseDetailsSection(update)
+	prBody += crdWarningSection(simulation)
+	prBody += removedValuesSection(simulation)
+	prBody += policyViolationsSection(violations)
+	if !c.config.Checker.ManifestDiffReview {
+		prBody += manifestChangesSection(simulation)
+	}
+	prBody, overflow := fitPRBody(prBody, maxPRBodyLength)
 
 	pr, err := c.githubClient.CreatePullRequest(ctx,
 		c.config.GitHub.Owner,
@@ -210,40 +896,415 @@ func (c *Checker) processUpdate(ctx context.Context, repoPath string, repo *gogi
 		prTitle,
 		prBody,
 		branchName,
-		c.config.Git.Branch)
+		baseBranch)
 	
 	if err != nil {
+		// Don't leave a pushed branch without a pull request behind
+		if rollbackErr := c.gitClient.DeleteRemoteBranch(repo, branchName); rollbackErr != nil {
+			log.Printf("Warning: failed to delete branch %s after pull request creation failed: %v", branchName, rollbackErr)
+		}
 		return fmt.Errorf("failed to create pull request: %w", err)
 	}
 
 	log.Printf("Created pull request for %s: %s", update.Release.Chart, *pr.HTMLURL)
+	c.result.PRsOpened++
+	update.PullRequestURL = pr.GetHTMLURL()
+	if c.openPRs != nil {
+		c.openPRs = append(c.openPRs, pr)
+	}
+
+	// Post the part of the description that didn't fit as comments
+	for i, comment := range overflow {
+		if err := c.githubClient.CreateComment(ctx,
+			c.config.GitHub.Owner,
+			c.config.GitHub.Repo,
+			pr.GetNumber(),
+			comment); err != nil {
+			log.Printf("Warning: failed to post description part %d of %d for %s: %v", i+2, len(overflow)+1, update.Release.Chart, err)
+			break
+		}
+	}
+
+	if c.config.Checker.ManifestDiffReview {
+		c.postManifestReview(ctx, pr.GetNumber(), update, simulation)
+	}
+
+	c.recordBump(ctx, update)
+
+	if c.dedupByLabel() {
+		if err := c.githubClient.AddLabels(ctx,
+			c.config.GitHub.Owner,
+			c.config.GitHub.Repo,
+			pr.GetNumber(),
+			[]string{chartLabel(update.Release.Chart)}); err != nil {
+			log.Printf("Warning: failed to label pull request for %s: %v", update.Release.Chart, err)
+		}
+	}
+
+	c.runHooks(ctx, &hooks.Event{
+		Chart:             update.Release.Chart,
+		Release:           update.Release.Name,
+		Namespace:         update.Release.Namespace,
+		CurrentVersion:    update.CurrentVersion,
+		LatestVersion:     update.LatestVersion,
+		Branch:            branchName,
+		PullRequestNumber: pr.GetNumber(),
+		PullRequestURL:    pr.GetHTMLURL(),
+	})
+
+	if reviewers := prepared.reviewers; len(reviewers) > 0 {
+		if err := c.githubClient.RequestReviewers(ctx,
+			c.config.GitHub.Owner,
+			c.config.GitHub.Repo,
+			pr.GetNumber(),
+			reviewers); err != nil {
+			log.Printf("Warning: failed to request reviewers for %s: %v", update.Release.Chart, err)
+		}
+	}
+
+	return nil
+}
+
+// runHooks invokes the post-update hooks; failures are logged but don't fail the update
+func (c *Checker) runHooks(ctx context.Context, event *hooks.Event) {
+	for _, hook := range c.hooks {
+		if err := hook.Run(ctx, event); err != nil {
+			log.Printf("Warning: post-update hook %s failed for %s: %v", hook.Name(), event.Chart, err)
+		}
+	}
+}
+
+// chartLabel returns the label identifying pull requests for a chart
+func chartLabel(chartName string) string {
+	return "helmchecker/chart: " + chartName
+}
+
+// dedupByLabel reports whether pull requests are labelled and matched by chart
+func (c *Checker) dedupByLabel() bool {
+	strategy := c.config.Checker.PRDeduplication
+	return strategy == config.DedupLabel || strategy == config.DedupBoth
+}
+
+// prefetchOpenPRs lists the repository's open pull requests once so existing
+// PRs can be matched locally. On failure they are looked up per update instead.
+func (c *Checker) prefetchOpenPRs(ctx context.Context) {
+	prs, err := c.githubClient.ListOpenPullRequests(ctx, c.config.GitHub.Owner, c.config.GitHub.Repo)
+	if err != nil {
+		log.Printf("Warning: failed to prefetch open pull requests, checking per chart instead: %v", err)
+		return
+	}
+	if prs == nil {
+		prs = []*gh.PullRequest{}
+	}
+	log.Printf("Prefetched %d open pull request(s)", len(prs))
+	c.openPRs = prs
+}
+
+// findExistingPR looks for an open pull request for the update using the
+// configured deduplication strategy
+func (c *Checker) findExistingPR(ctx context.Context, update *ChartUpdate, branchName, baseBranch string) (*gh.PullRequest, error) {
+	if c.openPRs != nil {
+		return c.matchOpenPR(update, branchName, baseBranch), nil
+	}
+
+	if c.config.Checker.PRDeduplication != config.DedupLabel {
+		pr, err := c.githubClient.CheckIfPRExists(ctx, 
+			c.config.GitHub.Owner, 
+			c.config.GitHub.Repo, 
+			branchName,
+			baseBranch)
+		if err != nil || pr != nil {
+			return pr, err
+		}
+	}
+
+	if c.dedupByLabel() {
+		return c.githubClient.FindPRByLabel(ctx,
+			c.config.GitHub.Owner,
+			c.config.GitHub.Repo,
+			chartLabel(update.Release.Chart))
+	}
+
+	return nil, nil
+}
+
+// matchOpenPR finds an existing PR for the update among the prefetched open
+// pull requests, using the same deduplication strategy as findExistingPR
+func (c *Checker) matchOpenPR(update *ChartUpdate, branchName, baseBranch string) *gh.PullRequest {
+	if c.config.Checker.PRDeduplication != config.DedupLabel {
+		for _, pr := range c.openPRs {
+			if pr.GetHead().GetRef() == branchName && pr.GetBase().GetRef() == baseBranch {
+				return pr
+			}
+		}
+	}
+
+	if c.dedupByLabel() {
+		label := chartLabel(update.Release.Chart)
+		for _, pr := range c.openPRs {
+			for _, l := range pr.Labels {
+				if l.GetName() == label {
+					return pr
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// simulateUpgrade renders the current and target chart versions. Render
+// failures are logged and return nil rather than blocking the update.
+func (c *Checker) simulateUpgrade(ctx context.Context, update *ChartUpdate) *helm.UpgradeSimulation {
+	simulation, err := c.helmClient.SimulateUpgrade(ctx, update.Release, update.LatestVersion)
+	if err != nil {
+		log.Printf("Warning: failed to simulate upgrade for %s: %v", update.Release.Chart, err)
+		return nil
+	}
+	return simulation
+}
+
+// evaluatePolicies evaluates the configured policies against the target
+// version's rendered manifests and the release's values
+func (c *Checker) evaluatePolicies(ctx context.Context, update *ChartUpdate, simulation *helm.UpgradeSimulation) ([]policy.Violation, error) {
+	if c.policyEvaluator == nil {
+		return nil, nil
+	}
+	if simulation == nil {
+		return nil, fmt.Errorf("no rendered manifests available for %s %s", update.Release.Chart, update.LatestVersion)
+	}
+
+	manifests, err := simulation.TargetObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.policyEvaluator.Evaluate(ctx, &policy.Input{
+		Chart:     update.Release.Chart,
+		Version:   update.LatestVersion,
+		Release:   update.Release.Name,
+		Namespace: update.Release.Namespace,
+		Values:    update.Release.Values,
+		Manifests: manifests,
+	})
+}
+
+// crdWarningSection warns that the target chart ships custom resource
+// definitions, which Helm installs but never upgrades
+func crdWarningSection(simulation *helm.UpgradeSimulation) string {
+	if simulation == nil || len(simulation.CRDs) == 0 {
+		return ""
+	}
+
+	actions := make(map[string]string)
+	for _, change := range simulation.Changes {
+		actions[change.Resource] = change.Action
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\n**⚠️ Custom resource definitions:**\n")
+	b.WriteString("This chart ships CRDs. Helm does not upgrade CRDs on `helm upgrade`, so changes to them may need to be applied manually:\n")
+	for _, crd := range simulation.CRDs {
+		action, ok := actions[crd]
+		if !ok {
+			action = "unchanged"
+		}
+		fmt.Fprintf(&b, "- `%s` (%s)\n", crd, action)
+	}
+	return b.String()
+}
+
+// removedValuesSection warns about release values the target chart no longer
+// defines, which Helm ignores or rejects after the upgrade
+func removedValuesSection(simulation *helm.UpgradeSimulation) string {
+	if simulation == nil || len(simulation.RemovedValues) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\n**⚠️ Removed values:**\n")
+	b.WriteString("These values are set on the release but no longer exist in the new chart version. They will be ignored or rejected after the upgrade and should be migrated:\n")
+	for _, key := range simulation.RemovedValues {
+		fmt.Fprintf(&b, "- `%s`\n", key)
+	}
+	return b.String()
+}
+
+// policyViolationsSection lists policy violations for the PR body
+func policyViolationsSection(violations []policy.Violation) string {
+	if len(violations) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\n**Policy violations:**\n")
+	for _, violation := range violations {
+		fmt.Fprintf(&b, "- ⚠️ %s\n", violation.Message)
+	}
+	return b.String()
+}
+
+// postManifestReview posts the rendered manifest diff as a pull request
+// review so reviewers can discuss individual changes. Nothing is posted when
+// the upgrade could not be simulated or changes no resources.
+func (c *Checker) postManifestReview(ctx context.Context, number int, update *ChartUpdate, simulation *helm.UpgradeSimulation) {
+	if simulation == nil {
+		return
+	}
+	if len(simulation.Changes) == 0 {
+		log.Printf("No rendered resources change for %s, not posting a manifest review", update.Release.Chart)
+		return
+	}
+
+	body, overflow := fitPRBody(strings.TrimLeft(manifestChangesSection(simulation), "\n"), maxPRBodyLength)
+	for i, part := range append([]string{body}, overflow...) {
+		if err := c.githubClient.CreateReview(ctx,
+			c.config.GitHub.Owner,
+			c.config.GitHub.Repo,
+			number,
+			part); err != nil {
+			log.Printf("Warning: failed to post manifest review part %d of %d for %s: %v", i+1, len(overflow)+1, update.Release.Chart, err)
+			return
+		}
+	}
+}
+
+// manifestChangesSection describes the resource changes of a simulated upgrade
+// for the PR body
+func manifestChangesSection(simulation *helm.UpgradeSimulation) string {
+	if simulation == nil {
+		return ""
+	}
+
+	if len(simulation.Changes) == 0 {
+		return "\n\n**Manifest changes:**\nNo rendered resources change.\n"
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\n**Manifest changes:**\n")
+	for _, change := range simulation.Changes {
+		fmt.Fprintf(&b, "\n<details><summary>%s (%s)</summary>\n\n```diff\n%s```\n</details>\n",
+			change.Resource, change.Action, change.Diff)
+	}
+	return b.String()
+}
+
+// updateChartFiles sets the new chart version in the chart file
+func (c *Checker) updateChartFiles(repoPath, chartFile string, update *ChartUpdate) error {
+	content, err := os.ReadFile(filepath.Join(repoPath, chartFile))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", chartFile, err)
+	}
+
+	updated, err := setChartVersion(content, update.Release.Chart, update.LatestVersion)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", chartFile, err)
+	}
+
+	return c.gitClient.UpdateFile(repoPath, chartFile, string(updated))
+}
+
+// matchDirectoryRule returns the directory rule with the longest path prefix
+// matching filePath, or nil when no rule applies
+func (c *Checker) matchDirectoryRule(filePath string) *config.DirectoryRule {
+	filePath = path.Clean(filePath)
+	var match *config.DirectoryRule
+	matchLen := -1
+	for i := range c.config.Checker.DirectoryRules {
+		rule := &c.config.Checker.DirectoryRules[i]
+		prefix := path.Clean(rule.PathPrefix)
+		if !inDirectory(filePath, prefix) {
+			continue
+		}
+		if len(prefix) > matchLen {
+			match = rule
+			matchLen = len(prefix)
+		}
+	}
+	return match
+}
+
+// inDirectory reports whether the cleaned filePath is dir or lies below it,
+// so charts/team-a doesn't match charts/team-ab
+func inDirectory(filePath, dir string) bool {
+	if dir == "." {
+		return true
+	}
+	return filePath == dir || strings.HasPrefix(filePath, dir+"/")
+}
+
+// verifySources checks that the release's repository and source URLs point at
+// trusted hosts when a trusted host allowlist is configured
+func (c *Checker) verifySources(release *helm.Release) error {
+	if len(c.config.Checker.TrustedSourceHosts) == 0 {
+		return nil
+	}
+
+	urls := append([]string{release.Repository}, release.Sources...)
+	verified := false
+	for _, rawURL := range urls {
+		if rawURL == "" {
+			continue
+		}
+
+		parsed, err := url.Parse(rawURL)
+		if err != nil || parsed.Hostname() == "" {
+			return fmt.Errorf("untrusted source %q: unable to determine host", rawURL)
+		}
+
+		if !matchesAnyPattern(c.config.Checker.TrustedSourceHosts, parsed.Hostname()) {
+			return fmt.Errorf("untrusted source host %s in %q", parsed.Hostname(), rawURL)
+		}
+		verified = true
+	}
+
+	if !verified {
+		return fmt.Errorf("no source URL available to verify against trusted hosts")
+	}
 	return nil
 }
 
-// updateChartFiles updates the chart files with new version information
-func (c *Checker) updateChartFiles(repoPath string, update *ChartUpdate) error {
-	// This is a simplified implementation
-	// In a real scenario, you would need to:
-	// 1. Find the chart files (Chart.yaml, values.yaml, etc.)
-	// 2. Parse and update the version fields
-	// 3. Handle different chart structures and formats
+// reportDependencyPins warns about chart dependencies pinned to exact versions,
+// which will not pick up transitive security fixes
+func (c *Checker) reportDependencyPins(release *helm.Release) {
+	for _, pin := range helm.ClassifyDependencyPins(release.Dependencies) {
+		if pin.Exact {
+			log.Printf("Warning: chart %s pins dependency %s to exact version %s; consider %s",
+				release.Chart, pin.Name, pin.Constraint, pin.Suggestion)
+		}
+	}
+}
+
+// releaseSetting returns a helmchecker setting from the release labels or,
+// failing that, the chart annotations
+func releaseSetting(release *helm.Release, key string) string {
+	if value, ok := release.Labels[key]; ok {
+		return value
+	}
+	return release.Annotations[key]
+}
+
+// annotationSkip reports whether a release opts out of checking through its
+// ignore or policy annotation, and why
+func annotationSkip(release *helm.Release) (bool, string) {
+	if ignore, _ := strconv.ParseBool(releaseSetting(release, IgnoreAnnotation)); ignore {
+		return true, fmt.Sprintf("%s is set", IgnoreAnnotation)
+	}
 
-	// For demonstration, we'll create a simple update file
-	updateContent := fmt.Sprintf(`# Chart Update
-Chart: %s
-Current Version: %s
-New Version: %s
-Repository: %s
-Timestamp: %s
-`, update.Release.Chart, update.CurrentVersion, update.LatestVersion, update.Repository, "2024-12-02")
+	switch value := releaseSetting(release, PolicyAnnotation); value {
+	case "", config.PolicyUpdate, config.PolicyDryRun:
+	case config.PolicySkip:
+		return true, fmt.Sprintf("%s is %s", PolicyAnnotation, value)
+	default:
+		log.Printf("Warning: ignoring unknown %s %q on %s", PolicyAnnotation, value, release.Chart)
+	}
 
-	filename := fmt.Sprintf("updates/%s-update.txt", update.Release.Chart)
-	return c.gitClient.UpdateFile(repoPath, filename, updateContent)
+	return false, ""
 }
 
 // isExcluded checks if a chart is in the exclude list
 func (c *Checker) isExcluded(chartName string) bool {
-	for _, excluded := range c.config.Checker.ExcludeCharts {
+	for _, excluded := range c.excludeCharts() {
 		if excluded == chartName {
 			return true
 		}
@@ -253,11 +1314,12 @@ func (c *Checker) isExcluded(chartName string) bool {
 
 // isIncluded checks if a chart is in the include list
 func (c *Checker) isIncluded(chartName string) bool {
-	if len(c.config.Checker.IncludeCharts) == 0 {
+	includeCharts := c.includeCharts()
+	if len(includeCharts) == 0 {
 		return true
 	}
 	
-	for _, included := range c.config.Checker.IncludeCharts {
+	for _, included := range includeCharts {
 		if included == chartName {
 			return true
 		}
@@ -265,13 +1327,58 @@ func (c *Checker) isIncluded(chartName string) bool {
 	return false
 }
 
-// isNewerVersion compares two version strings
-// This is a simplified implementation - in production you should use semver
-func (c *Checker) isNewerVersion(latest, current string) bool {
-	// Remove 'v' prefix if present
-	latest = strings.TrimPrefix(latest, "v")
-	current = strings.TrimPrefix(current, "v")
-	
-	// Simple string comparison (not semver compliant)
-	return latest != current && latest > current
+// excludeCharts returns the configured excluded charts plus those from the chart list
+func (c *Checker) excludeCharts() []string {
+	if c.chartList == nil {
+		return c.config.Checker.ExcludeCharts
+	}
+	return append(append([]string(nil), c.config.Checker.ExcludeCharts...), c.chartList.ExcludeCharts...)
+}
+
+// includeCharts returns the configured included charts plus those from the chart list
+func (c *Checker) includeCharts() []string {
+	if c.chartList == nil {
+		return c.config.Checker.IncludeCharts
+	}
+	return append(append([]string(nil), c.config.Checker.IncludeCharts...), c.chartList.IncludeCharts...)
+}
+
+// listPolicy returns the chart list policy for a chart, or "" if none is set
+func (c *Checker) listPolicy(chartName string) string {
+	if c.chartList == nil {
+		return ""
+	}
+	return c.chartList.Policies[chartName]
+}
+
+// filterNamespaces drops releases whose namespace is excluded, or not included
+// when an include list is configured, counting them as skipped. Namespace
+// entries may be glob patterns.
+func (c *Checker) filterNamespaces(releases []*helm.Release) []*helm.Release {
+	var filtered []*helm.Release
+	for _, release := range releases {
+		if matchesAnyPattern(c.config.Checker.ExcludeNamespaces, release.Namespace) {
+			log.Printf("Skipping release %s in excluded namespace %s", release.Name, release.Namespace)
+			c.result.Skipped++
+			continue
+		}
+
+		if len(c.config.Checker.IncludeNamespaces) > 0 && !matchesAnyPattern(c.config.Checker.IncludeNamespaces, release.Namespace) {
+			c.result.Skipped++
+			continue
+		}
+
+		filtered = append(filtered, release)
+	}
+	return filtered
+}
+
+// matchesAnyPattern reports whether name matches any of the glob patterns
+func matchesAnyPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
 }
\ No newline at end of file
@@ -0,0 +1,1473 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	gh "github.com/google/go-github/v56/github"
+	"github.com/marccoxall/helmchecker/internal/config"
+	"github.com/marccoxall/helmchecker/internal/helm"
+	"github.com/marccoxall/helmchecker/internal/hooks"
+	"github.com/marccoxall/helmchecker/internal/policy"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func TestFilterNamespaces(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			ExcludeNamespaces: []string{"kube-system", "vendor-*"},
+		},
+	}
+	c := New(nil, nil, nil, cfg)
+
+	releases := []*helm.Release{
+		{Name: "coredns", Namespace: "kube-system"},
+		{Name: "agent", Namespace: "vendor-monitoring"},
+		{Name: "nginx", Namespace: "web"},
+	}
+
+	filtered := c.filterNamespaces(releases)
+	if len(filtered) != 1 {
+		t.Fatalf("Expected 1 release, got %d", len(filtered))
+	}
+	if filtered[0].Name != "nginx" {
+		t.Errorf("Expected release 'nginx', got '%s'", filtered[0].Name)
+	}
+	if c.result.Skipped != 2 {
+		t.Errorf("Expected 2 skipped releases, got %d", c.result.Skipped)
+	}
+}
+
+func TestFilterNamespacesInclude(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			IncludeNamespaces: []string{"team-*"},
+			ExcludeNamespaces: []string{"team-legacy"},
+		},
+	}
+	c := New(nil, nil, nil, cfg)
+
+	releases := []*helm.Release{
+		{Name: "api", Namespace: "team-a"},
+		{Name: "old", Namespace: "team-legacy"},
+		{Name: "nginx", Namespace: "web"},
+	}
+
+	filtered := c.filterNamespaces(releases)
+	if len(filtered) != 1 {
+		t.Fatalf("Expected 1 release, got %d", len(filtered))
+	}
+	if filtered[0].Name != "api" {
+		t.Errorf("Expected release 'api', got '%s'", filtered[0].Name)
+	}
+	if c.result.Skipped != 2 {
+		t.Errorf("Expected 2 skipped releases, got %d", c.result.Skipped)
+	}
+}
+
+func TestMatchDirectoryRule(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			DirectoryRules: []config.DirectoryRule{
+				{PathPrefix: "teams/", TargetBranch: "main"},
+				{PathPrefix: "teams/payments/", TargetBranch: "payments", Reviewers: []string{"org/payments"}},
+				{PathPrefix: "vendor/", Policy: config.PolicySkip},
+				{PathPrefix: "charts/team-a", TargetBranch: "team-a"},
+			},
+		},
+	}
+	c := New(nil, nil, nil, cfg)
+
+	tests := []struct {
+		path         string
+		expectPrefix string
+	}{
+		{"teams/payments/api/Chart.yaml", "teams/payments/"},
+		{"teams/search/Chart.yaml", "teams/"},
+		{"vendor/redis/Chart.yaml", "vendor/"},
+		{"platform/nginx/Chart.yaml", ""},
+		{"charts/team-a/web/Chart.yaml", "charts/team-a"},
+		{"charts/team-ab/web/Chart.yaml", ""},
+		{"./teams/payments/Chart.yaml", "teams/payments/"},
+	}
+
+	for _, tt := range tests {
+		rule := c.matchDirectoryRule(tt.path)
+		if tt.expectPrefix == "" {
+			if rule != nil {
+				t.Errorf("Expected no rule for %s, got %s", tt.path, rule.PathPrefix)
+			}
+			continue
+		}
+		if rule == nil {
+			t.Errorf("Expected rule %s for %s, got none", tt.expectPrefix, tt.path)
+			continue
+		}
+		if rule.PathPrefix != tt.expectPrefix {
+			t.Errorf("Expected rule %s for %s, got %s", tt.expectPrefix, tt.path, rule.PathPrefix)
+		}
+	}
+
+	rule := c.matchDirectoryRule("teams/payments/api/Chart.yaml")
+	if rule.TargetBranch != "payments" || len(rule.Reviewers) != 1 || rule.Reviewers[0] != "org/payments" {
+		t.Errorf("Unexpected rule applied: %+v", rule)
+	}
+}
+
+func TestExcludeVersionPatterns(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			ExcludeVersionPatterns: []string{`^nightly-\d+$`, `canary`, `(invalid`},
+		},
+	}
+	helmClient := &fakeHelmClient{
+		releases: []*helm.Release{{Name: "web", Chart: "nginx", Version: "1.0.0"}},
+		latest:   map[string]*helm.ChartVersion{"nginx": {Version: "1.0.0"}},
+	}
+	c := New(helmClient, nil, nil, cfg)
+
+	if _, err := c.checkForUpdates(context.Background(), helmClient.releases); err != nil {
+		t.Fatalf("checkForUpdates failed: %v", err)
+	}
+
+	// The resolver receives the valid patterns so excluded versions are never the latest
+	exclude := helmClient.excludes["nginx"]
+	if len(exclude) != 2 {
+		t.Fatalf("Expected 2 exclude patterns passed to the resolver, got %d", len(exclude))
+	}
+	denied := func(version string) bool {
+		for _, re := range exclude {
+			if re.MatchString(version) {
+				return true
+			}
+		}
+		return false
+	}
+
+	tests := []struct {
+		version string
+		denied  bool
+	}{
+		{"nightly-20240101", true},
+		{"1.2.3-canary.4", true},
+		{"canary", true},
+		{"1.2.3", false},
+		{"1.2.3-rc.1", false},
+		{"nightly", false},
+	}
+
+	for _, tt := range tests {
+		if got := denied(tt.version); got != tt.denied {
+			t.Errorf("%q excluded = %v, expected %v", tt.version, got, tt.denied)
+		}
+	}
+}
+
+func TestSplay(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			StartupSplay: 5 * time.Second,
+		},
+	}
+	c := New(nil, nil, nil, cfg)
+
+	var delays []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+
+	for i := 0; i < 50; i++ {
+		if err := c.splay(context.Background()); err != nil {
+			t.Fatalf("Unexpected splay error: %v", err)
+		}
+	}
+
+	if len(delays) != 50 {
+		t.Fatalf("Expected 50 sleeps, got %d", len(delays))
+	}
+	for _, d := range delays {
+		if d < 0 || d >= cfg.Checker.StartupSplay {
+			t.Errorf("Delay %s outside of [0, %s)", d, cfg.Checker.StartupSplay)
+		}
+	}
+}
+
+func TestSplayDisabledByDefault(t *testing.T) {
+	c := New(nil, nil, nil, &config.Config{})
+
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		t.Errorf("Expected no sleep when splay is disabled, got %s", d)
+		return nil
+	}
+
+	if err := c.splay(context.Background()); err != nil {
+		t.Fatalf("Unexpected splay error: %v", err)
+	}
+}
+
+func TestVerifySources(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			TrustedSourceHosts: []string{"github.com", "*.bitnami.com"},
+		},
+	}
+	c := New(nil, nil, nil, cfg)
+
+	tests := []struct {
+		name    string
+		release *helm.Release
+		trusted bool
+	}{
+		{
+			name:    "trusted repository and sources",
+			release: &helm.Release{Repository: "https://github.com/bitnami/charts", Sources: []string{"https://github.com/bitnami/charts", "https://charts.bitnami.com/bitnami"}},
+			trusted: true,
+		},
+		{
+			name:    "typosquatted source",
+			release: &helm.Release{Repository: "https://github.com/bitnami/charts", Sources: []string{"https://github.com/bitnami/charts", "https://githuh.com/bitnami/charts"}},
+			trusted: false,
+		},
+		{
+			name:    "untrusted repository",
+			release: &helm.Release{Repository: "https://evil.example.com/charts"},
+			trusted: false,
+		},
+		{
+			name:    "no sources",
+			release: &helm.Release{},
+			trusted: false,
+		},
+	}
+
+	for _, tt := range tests {
+		err := c.verifySources(tt.release)
+		if tt.trusted && err != nil {
+			t.Errorf("%s: expected trusted, got error: %v", tt.name, err)
+		}
+		if !tt.trusted && err == nil {
+			t.Errorf("%s: expected untrusted, got no error", tt.name)
+		}
+	}
+
+	// Without an allowlist every release is accepted
+	c = New(nil, nil, nil, &config.Config{})
+	if err := c.verifySources(&helm.Release{Repository: "https://evil.example.com/charts"}); err != nil {
+		t.Errorf("Expected no verification without allowlist, got: %v", err)
+	}
+}
+
+type fakeEvaluator struct {
+	violations []policy.Violation
+	input      *policy.Input
+}
+
+func (f *fakeEvaluator) Evaluate(ctx context.Context, input *policy.Input) ([]policy.Violation, error) {
+	f.input = input
+	return f.violations, nil
+}
+
+func TestEvaluatePolicies(t *testing.T) {
+	c := New(nil, nil, nil, &config.Config{})
+	update := &ChartUpdate{
+		Release:       &helm.Release{Name: "web", Namespace: "apps", Chart: "nginx"},
+		LatestVersion: "2.0.0",
+	}
+	simulation := &helm.UpgradeSimulation{
+		TargetManifests: map[string]string{
+			"Deployment/apps/web": "kind: Deployment\nmetadata:\n  name: web\n",
+		},
+	}
+
+	// Without configured policies nothing is evaluated
+	violations, err := c.evaluatePolicies(context.Background(), update, simulation)
+	if err != nil || violations != nil {
+		t.Fatalf("Expected no evaluation without policies, got %v, %v", violations, err)
+	}
+
+	evaluator := &fakeEvaluator{violations: []policy.Violation{{Message: "resources.limits required"}}}
+	c.policyEvaluator = evaluator
+
+	violations, err = c.evaluatePolicies(context.Background(), update, simulation)
+	if err != nil {
+		t.Fatalf("Failed to evaluate policies: %v", err)
+	}
+	if len(violations) != 1 {
+		t.Fatalf("Expected 1 violation, got %d", len(violations))
+	}
+	if evaluator.input.Chart != "nginx" || len(evaluator.input.Manifests) != 1 || evaluator.input.Manifests[0]["kind"] != "Deployment" {
+		t.Errorf("Unexpected policy input: %+v", evaluator.input)
+	}
+
+	section := policyViolationsSection(violations)
+	if !strings.Contains(section, "resources.limits required") {
+		t.Errorf("Expected violation in PR section, got: %s", section)
+	}
+
+	// Without rendered output the policies cannot be evaluated
+	if _, err := c.evaluatePolicies(context.Background(), update, nil); err == nil {
+		t.Errorf("Expected error when no simulation is available")
+	}
+}
+
+type fakeHelmClient struct {
+	releases      []*helm.Release
+	updateErr     error
+	latest        map[string]*helm.ChartVersion
+	latestDelay   time.Duration
+	simulation    *helm.UpgradeSimulation
+	simulationErr error
+	indexDigest   string
+	versions      map[string][]string
+	// index resolves latest versions by channel when set
+	index *repo.IndexFile
+
+	mu          sync.Mutex
+	latestCalls int
+	channels    map[string]string
+	excludes    map[string][]*regexp.Regexp
+	inFlight    int
+	maxInFlight int
+}
+
+func (f *fakeHelmClient) ListReleases(ctx context.Context) ([]*helm.Release, error) {
+	return f.releases, nil
+}
+
+func (f *fakeHelmClient) UpdateRepositories(ctx context.Context) error {
+	return f.updateErr
+}
+
+func (f *fakeHelmClient) GetLatestChartVersion(ctx context.Context, chartName, repoURL, channel string, exclude []*regexp.Regexp, comparator helm.VersionComparator) (*helm.ChartVersion, error) {
+	f.mu.Lock()
+	f.latestCalls++
+	if f.channels == nil {
+		f.channels = make(map[string]string)
+		f.excludes = make(map[string][]*regexp.Regexp)
+	}
+	f.channels[chartName] = channel
+	f.excludes[chartName] = exclude
+	f.inFlight++
+	if f.inFlight > f.maxInFlight {
+		f.maxInFlight = f.inFlight
+	}
+	f.mu.Unlock()
+
+	time.Sleep(f.latestDelay)
+
+	f.mu.Lock()
+	f.inFlight--
+	f.mu.Unlock()
+
+	if f.index != nil {
+		return helm.LatestInChannel(f.index, chartName, channel, exclude, comparator)
+	}
+	if latest, ok := f.latest[chartName]; ok {
+		return latest, nil
+	}
+	return nil, fmt.Errorf("chart %s not found", chartName)
+}
+
+func (f *fakeHelmClient) GetChartVersion(ctx context.Context, chartName, repoURL, version string) (*helm.ChartVersion, error) {
+	for _, v := range f.versions[chartName] {
+		if v == version {
+			return &helm.ChartVersion{Version: v}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s %s", helm.ErrChartVersionNotFound, chartName, version)
+}
+
+func (f *fakeHelmClient) IndexDigest(ctx context.Context) (string, error) {
+	return f.indexDigest, nil
+}
+
+func (f *fakeHelmClient) SimulateUpgrade(ctx context.Context, release *helm.Release, targetVersion string) (*helm.UpgradeSimulation, error) {
+	return f.simulation, f.simulationErr
+}
+
+// captureLog redirects the standard logger for the duration of a test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func TestRunWithNoRepositories(t *testing.T) {
+	logs := captureLog(t)
+
+	helmClient := &fakeHelmClient{
+		releases:  []*helm.Release{{Name: "web", Namespace: "apps", Chart: "nginx", Version: "1.0.0"}},
+		updateErr: helm.ErrNoRepositories,
+	}
+	c := New(helmClient, nil, nil, &config.Config{})
+
+	result, err := c.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected clean exit, got: %v", err)
+	}
+
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "no helm repositories configured") {
+		t.Errorf("Expected the no-repositories warning in the run result, got %v", result.Warnings)
+	}
+	if helmClient.latestCalls != 0 {
+		t.Errorf("Expected no version lookups without repositories, got %d", helmClient.latestCalls)
+	}
+	if !strings.Contains(logs.String(), "no helm repositories configured") || !strings.Contains(logs.String(), "helm repo add") {
+		t.Errorf("Expected a no-repositories warning with guidance, got:\n%s", logs.String())
+	}
+}
+
+type fakeGitClient struct {
+	cloneCalls int
+	cloneErr   error
+	branches   []string
+	files      map[string]string
+	commits    []string
+	pushed     []string
+	deleted    []string
+	fetched    []string
+	checkouts  []string
+
+	// removedFailed records whether the clone was removed after a failed update
+	removedFailed bool
+
+	// onPush is called after a branch is pushed
+	onPush func()
+
+	// repoPath is the checkout returned by CloneRepository
+	repoPath string
+}
+
+func (f *fakeGitClient) CloneRepository(ctx context.Context) (string, *gogit.Repository, error) {
+	f.cloneCalls++
+	if f.cloneErr != nil {
+		return "", nil, f.cloneErr
+	}
+	return f.repoPath, nil, nil
+}
+
+func (f *fakeGitClient) RemoveClone(repoPath string, failed bool) error {
+	f.removedFailed = failed
+	return nil
+}
+
+func (f *fakeGitClient) FetchBranch(ctx context.Context, repo *gogit.Repository, branchName string) error {
+	f.fetched = append(f.fetched, branchName)
+	return nil
+}
+
+func (f *fakeGitClient) CheckoutBranch(repo *gogit.Repository, branchName string) error {
+	f.checkouts = append(f.checkouts, branchName)
+	return nil
+}
+
+func (f *fakeGitClient) CreateBranch(repo *gogit.Repository, branchName string) error {
+	f.branches = append(f.branches, branchName)
+	return nil
+}
+
+func (f *fakeGitClient) CommitChanges(repo *gogit.Repository, message string) error {
+	f.commits = append(f.commits, message)
+	return nil
+}
+
+func (f *fakeGitClient) PushBranch(repo *gogit.Repository, branchName string) error {
+	f.pushed = append(f.pushed, branchName)
+	if f.onPush != nil {
+		f.onPush()
+	}
+	return nil
+}
+
+func (f *fakeGitClient) DeleteRemoteBranch(repo *gogit.Repository, branchName string) error {
+	f.deleted = append(f.deleted, branchName)
+	return nil
+}
+
+func (f *fakeGitClient) UpdateFile(repoPath, filePath, content string) error {
+	if f.files == nil {
+		f.files = make(map[string]string)
+	}
+	f.files[filePath] = content
+	return nil
+}
+
+func TestRunDefersOutsideMaintenanceWindow(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			MaintenanceWindow: config.MaintenanceWindow{
+				Days:  []string{"Mon", "Tue", "Wed", "Thu", "Fri"},
+				Hours: "09-17",
+			},
+		},
+	}
+
+	newChecker := func(now time.Time) (*Checker, *fakeGitClient) {
+		helmClient := &fakeHelmClient{
+			releases: []*helm.Release{{Name: "web", Namespace: "apps", Chart: "nginx", Version: "1.0.0"}},
+			latest:   map[string]*helm.ChartVersion{"nginx": {Version: "1.1.0"}},
+		}
+		gitClient := &fakeGitClient{cloneErr: fmt.Errorf("clone stopped by test")}
+		c := New(helmClient, gitClient, nil, cfg)
+		c.now = func() time.Time { return now }
+		return c, gitClient
+	}
+
+	// Saturday: updates are reported but no PRs are created
+	logs := captureLog(t)
+	c, gitClient := newChecker(time.Date(2024, 1, 6, 10, 0, 0, 0, time.UTC))
+	if _, err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Expected deferred run to succeed, got: %v", err)
+	}
+	if gitClient.cloneCalls != 0 {
+		t.Errorf("Expected no clone outside the maintenance window, got %d", gitClient.cloneCalls)
+	}
+	if !strings.Contains(logs.String(), "DEFERRED: Would update nginx from 1.0.0 to 1.1.0") {
+		t.Errorf("Expected deferred candidate to be reported, got:\n%s", logs.String())
+	}
+
+	// Wednesday morning: updates are processed
+	c, gitClient = newChecker(time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC))
+	if _, err := c.Run(context.Background()); err == nil {
+		t.Fatalf("Expected the test clone error to surface inside the window")
+	}
+	if gitClient.cloneCalls != 1 {
+		t.Errorf("Expected one clone inside the maintenance window, got %d", gitClient.cloneCalls)
+	}
+}
+
+type fakeGitHubClient struct {
+	existing  map[string]*gh.PullRequest
+	created   []*gh.PullRequest
+	updated   []*gh.PullRequest
+	reviewers map[int][]string
+	labelled  map[string]*gh.PullRequest
+	labels    map[int][]string
+	comments  map[int][]string
+	reviews   map[int][]string
+	issues    []*gh.Issue
+	open      []*gh.PullRequest
+	listCalls int
+
+	// createBlocks makes CreatePullRequest wait until its context is done
+	createBlocks bool
+	// createErrs fails CreatePullRequest for the given head branches
+	createErrs map[string]error
+}
+
+func (f *fakeGitHubClient) CheckIfPRExists(ctx context.Context, owner, repo, head, base string) (*gh.PullRequest, error) {
+	return f.existing[head], nil
+}
+
+func (f *fakeGitHubClient) CreatePullRequest(ctx context.Context, owner, repo, title, body, head, base string) (*gh.PullRequest, error) {
+	if f.createBlocks {
+		<-ctx.Done()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.createErrs[head]; err != nil {
+		return nil, err
+	}
+	pr := &gh.PullRequest{
+		Number:  gh.Int(len(f.created) + 1),
+		Title:   gh.String(title),
+		Body:    gh.String(body),
+		HTMLURL: gh.String(fmt.Sprintf("https://github.com/%s/%s/pull/%d", owner, repo, len(f.created)+1)),
+		Head:    &gh.PullRequestBranch{Ref: gh.String(head)},
+		Base:    &gh.PullRequestBranch{Ref: gh.String(base)},
+	}
+	f.created = append(f.created, pr)
+	return pr, nil
+}
+
+func (f *fakeGitHubClient) RequestReviewers(ctx context.Context, owner, repo string, number int, reviewers []string) error {
+	if f.reviewers == nil {
+		f.reviewers = make(map[int][]string)
+	}
+	f.reviewers[number] = reviewers
+	return nil
+}
+
+func (f *fakeGitHubClient) FindPRByLabel(ctx context.Context, owner, repo, label string) (*gh.PullRequest, error) {
+	return f.labelled[label], nil
+}
+
+func (f *fakeGitHubClient) AddLabels(ctx context.Context, owner, repo string, number int, labels []string) error {
+	if f.labels == nil {
+		f.labels = make(map[int][]string)
+	}
+	f.labels[number] = append(f.labels[number], labels...)
+	return nil
+}
+
+func (f *fakeGitHubClient) FindOpenIssue(ctx context.Context, owner, repo, title string) (*gh.Issue, error) {
+	for _, issue := range f.issues {
+		if issue.GetTitle() == title {
+			return issue, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeGitHubClient) UpdatePullRequest(ctx context.Context, owner, repo string, number int, title, body string) (*gh.PullRequest, error) {
+	pr := &gh.PullRequest{
+		Number:  gh.Int(number),
+		Title:   gh.String(title),
+		Body:    gh.String(body),
+		HTMLURL: gh.String(fmt.Sprintf("https://github.com/%s/%s/pull/%d", owner, repo, number)),
+	}
+	f.updated = append(f.updated, pr)
+	return pr, nil
+}
+
+func (f *fakeGitHubClient) ListOpenPullRequests(ctx context.Context, owner, repo string) ([]*gh.PullRequest, error) {
+	f.listCalls++
+	return f.open, nil
+}
+
+func (f *fakeGitHubClient) CreateIssue(ctx context.Context, owner, repo, title, body string) (*gh.Issue, error) {
+	issue := &gh.Issue{
+		Number:  gh.Int(len(f.issues) + 1),
+		Title:   gh.String(title),
+		Body:    gh.String(body),
+		HTMLURL: gh.String(fmt.Sprintf("https://github.com/%s/%s/issues/%d", owner, repo, len(f.issues)+1)),
+	}
+	f.issues = append(f.issues, issue)
+	return issue, nil
+}
+
+func (f *fakeGitHubClient) CreateReview(ctx context.Context, owner, repo string, number int, body string) error {
+	if f.reviews == nil {
+		f.reviews = make(map[int][]string)
+	}
+	f.reviews[number] = append(f.reviews[number], body)
+	return nil
+}
+
+func (f *fakeGitHubClient) CreateComment(ctx context.Context, owner, repo string, number int, body string) error {
+	if f.comments == nil {
+		f.comments = make(map[int][]string)
+	}
+	f.comments[number] = append(f.comments[number], body)
+	return nil
+}
+
+func TestBumpCooldown(t *testing.T) {
+	stateDir := t.TempDir()
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+			BumpCooldown:     72 * time.Hour,
+			StateFile:        filepath.Join(stateDir, "state.json"),
+		},
+	}
+	start := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+
+	run := func(now time.Time, latest string) *fakeGitHubClient {
+		helmClient := &fakeHelmClient{
+			releases: []*helm.Release{{Name: "web", Namespace: "apps", Chart: "nginx", Version: "1.0.0"}},
+			latest:   map[string]*helm.ChartVersion{"nginx": {Version: latest}},
+		}
+		githubClient := &fakeGitHubClient{}
+		c := New(helmClient, &fakeGitClient{repoPath: chartRepo(t, "nginx")}, githubClient, cfg)
+		c.now = func() time.Time { return now }
+
+		if _, err := c.Run(context.Background()); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		return githubClient
+	}
+
+	if created := run(start, "1.0.1").created; len(created) != 1 {
+		t.Fatalf("Expected first run to open 1 PR, got %d", len(created))
+	}
+
+	// State is kept in its own directory rather than beside the state file
+	if entries, _ := os.ReadDir(stateDir); len(entries) != 1 || entries[0].Name() != "state.json.d" {
+		t.Errorf("Expected only the state directory next to the state file, got %v", entries)
+	}
+
+	// A newer version the next day is still within the cooldown
+	if created := run(start.Add(24*time.Hour), "1.0.2").created; len(created) != 0 {
+		t.Errorf("Expected no PR within cooldown, got %d", len(created))
+	}
+
+	// Once the cooldown elapses the bump goes ahead
+	if created := run(start.Add(73*time.Hour), "1.0.3").created; len(created) != 1 {
+		t.Errorf("Expected a PR after the cooldown, got %d", len(created))
+	}
+}
+
+func TestCheckForUpdatesConcurrentResolution(t *testing.T) {
+	var releases []*helm.Release
+	latest := make(map[string]*helm.ChartVersion)
+	for i := 0; i < 12; i++ {
+		chart := fmt.Sprintf("chart-%02d", i)
+		releases = append(releases, &helm.Release{Name: chart, Chart: chart, Version: "1.0.0"})
+		if i%4 != 3 {
+			latest[chart] = &helm.ChartVersion{Version: "1.1.0"}
+		}
+	}
+
+	helmClient := &fakeHelmClient{
+		releases:    releases,
+		latest:      latest,
+		latestDelay: 20 * time.Millisecond,
+	}
+	c := New(helmClient, nil, nil, &config.Config{Checker: config.CheckerConfig{ResolveConcurrency: 4}})
+
+	updates, err := c.checkForUpdates(context.Background(), releases)
+	if err != nil {
+		t.Fatalf("checkForUpdates failed: %v", err)
+	}
+
+	if helmClient.maxInFlight < 2 || helmClient.maxInFlight > 4 {
+		t.Errorf("Expected between 2 and 4 concurrent lookups, got %d", helmClient.maxInFlight)
+	}
+	if helmClient.latestCalls != len(releases) {
+		t.Errorf("Expected %d lookups, got %d", len(releases), helmClient.latestCalls)
+	}
+
+	// Failed lookups are skipped and the rest keep release order
+	if len(updates) != 9 {
+		t.Fatalf("Expected 9 updates, got %d", len(updates))
+	}
+	for i := 1; i < len(updates); i++ {
+		if updates[i-1].Release.Chart >= updates[i].Release.Chart {
+			t.Errorf("Updates out of order: %s before %s", updates[i-1].Release.Chart, updates[i].Release.Chart)
+		}
+	}
+}
+
+func TestResolveUsesChartChannel(t *testing.T) {
+	releases := []*helm.Release{
+		{Name: "app", Chart: "app", Version: "1.0.0"},
+		{Name: "db", Chart: "db", Version: "1.0.0"},
+	}
+	helmClient := &fakeHelmClient{releases: releases}
+	c := New(helmClient, nil, nil, &config.Config{Checker: config.CheckerConfig{
+		ChartChannels: map[string]string{"db": config.ChannelEdge},
+	}})
+
+	c.resolveLatestVersions(context.Background(), releases)
+
+	if helmClient.channels["app"] != config.ChannelStable {
+		t.Errorf("Expected app resolved on %s channel, got %q", config.ChannelStable, helmClient.channels["app"])
+	}
+	if helmClient.channels["db"] != config.ChannelEdge {
+		t.Errorf("Expected db resolved on %s channel, got %q", config.ChannelEdge, helmClient.channels["db"])
+	}
+}
+
+func TestAppVersionDrift(t *testing.T) {
+	releases := []*helm.Release{
+		{Name: "db", Chart: "mysql", Version: "9.4.0", AppVersion: "8.0.35"},
+		{Name: "web", Chart: "nginx", Version: "1.0.0", AppVersion: "v1.25.0"},
+		{Name: "cache", Chart: "redis", Version: "18.0.0", AppVersion: "7.2.0"},
+	}
+	helmClient := &fakeHelmClient{
+		releases: releases,
+		latest: map[string]*helm.ChartVersion{
+			// Republished under the same chart version
+			"mysql": {Version: "9.4.0", AppVersion: "8.0.36"},
+			// Same appVersion written differently
+			"nginx": {Version: "1.0.0", AppVersion: "1.25.0"},
+			// A new chart version may well bump the appVersion
+			"redis": {Version: "18.1.0", AppVersion: "7.2.4"},
+		},
+	}
+	c := New(helmClient, nil, nil, &config.Config{})
+
+	updates, err := c.checkForUpdates(context.Background(), releases)
+	if err != nil {
+		t.Fatalf("checkForUpdates failed: %v", err)
+	}
+
+	if len(updates) != 1 || updates[0].Release.Chart != "redis" {
+		t.Errorf("Expected only redis to be updated, got %d update(s)", len(updates))
+	}
+	if len(c.result.Drifted) != 1 {
+		t.Fatalf("Expected 1 appVersion drift, got %d", len(c.result.Drifted))
+	}
+	drift := c.result.Drifted[0]
+	if drift.Release.Chart != "mysql" || drift.Version != "9.4.0" || drift.InstalledAppVersion != "8.0.35" || drift.IndexAppVersion != "8.0.36" {
+		t.Errorf("Unexpected drift %+v", drift)
+	}
+}
+
+func TestPinnedTargetVersion(t *testing.T) {
+	releases := []*helm.Release{
+		{Name: "cache", Chart: "redis", Version: "18.0.0"},
+		{Name: "web", Chart: "nginx", Version: "1.0.0"},
+		{Name: "db", Chart: "postgresql", Version: "13.0.0"},
+		{Name: "queue", Chart: "rabbitmq", Version: "12.0.0"},
+	}
+	helmClient := &fakeHelmClient{
+		releases: releases,
+		latest:   map[string]*helm.ChartVersion{"rabbitmq": {Version: "14.0.0"}},
+		versions: map[string][]string{
+			"redis":      {"18.0.0", "18.6.1", "19.0.0"},
+			"postgresql": {"12.5.0", "13.0.0"},
+		},
+	}
+	c := New(helmClient, nil, nil, &config.Config{Checker: config.CheckerConfig{
+		PinnedVersions: map[string]string{
+			"redis":      "18.6.1",
+			"nginx":      "2.0.0",
+			"postgresql": "12.5.0",
+		},
+	}})
+
+	updates, err := c.checkForUpdates(context.Background(), releases)
+	if err != nil {
+		t.Fatalf("checkForUpdates failed: %v", err)
+	}
+
+	// The pinned version present in the index is proposed instead of the latest
+	if len(updates) != 2 {
+		t.Fatalf("Expected 2 updates, got %d", len(updates))
+	}
+	if updates[0].Release.Chart != "redis" || updates[0].LatestVersion != "18.6.1" || !updates[0].HasReason(ReasonPinned) {
+		t.Errorf("Expected redis to be updated to pinned 18.6.1, got %s %s %v", updates[0].Release.Chart, updates[0].LatestVersion, updates[0].Reasons)
+	}
+	if updates[1].Release.Chart != "rabbitmq" || updates[1].LatestVersion != "14.0.0" || updates[1].HasReason(ReasonPinned) {
+		t.Errorf("Expected unpinned rabbitmq to follow the latest version, got %s %s %v", updates[1].Release.Chart, updates[1].LatestVersion, updates[1].Reasons)
+	}
+	if helmClient.latestCalls != 1 {
+		t.Errorf("Expected only the unpinned chart to look up its latest version, got %d lookups", helmClient.latestCalls)
+	}
+
+	// A pinned version absent from the index fails the chart
+	if len(c.result.Failed) != 1 || c.result.Failed[0].Chart != "nginx" || !errors.Is(c.result.Failed[0].Err, helm.ErrChartVersionNotFound) {
+		t.Errorf("Expected nginx to fail with a missing pinned version, got %+v", c.result.Failed)
+	}
+
+	// A pinned version older than the installed one is not a downgrade
+	if len(c.result.Blocked) != 0 {
+		t.Errorf("Expected no blocked updates, got %d", len(c.result.Blocked))
+	}
+}
+
+func TestShutdownDuringUpdate(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			CommitMessage:       "chore: update helm chart %s to version %s",
+			PullRequestTitle:    "Update Helm chart %s to version %s",
+			PullRequestBody:     "Updates %s from %s to %s",
+			ShutdownGracePeriod: time.Hour,
+		},
+	}
+	updates := []*ChartUpdate{
+		{Release: &helm.Release{Chart: "nginx"}, CurrentVersion: "1.0.0", LatestVersion: "1.1.0"},
+		{Release: &helm.Release{Chart: "redis"}, CurrentVersion: "2.0.0", LatestVersion: "2.1.0"},
+	}
+
+	// Shutdown mid-update: the current update completes, the next one is skipped
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gitClient := &fakeGitClient{onPush: cancel, repoPath: chartRepo(t, "nginx", "redis")}
+	githubClient := &fakeGitHubClient{}
+	c := New(&fakeHelmClient{}, gitClient, githubClient, cfg)
+
+	if err := c.processUpdates(ctx, updates); err != nil {
+		t.Fatalf("processUpdates failed: %v", err)
+	}
+	if len(githubClient.created) != 1 || githubClient.created[0].GetHead().GetRef() != "update-nginx-1.1.0" {
+		t.Errorf("Expected the in-flight update to open its PR, got %d PRs", len(githubClient.created))
+	}
+	if len(gitClient.pushed) != 1 {
+		t.Errorf("Expected the remaining update to be skipped, got pushes %v", gitClient.pushed)
+	}
+	if len(gitClient.deleted) != 0 {
+		t.Errorf("Expected no branches to be deleted, got %v", gitClient.deleted)
+	}
+
+	// Grace period expires before the PR is opened: the pushed branch is removed
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	gitClient = &fakeGitClient{onPush: cancel, repoPath: chartRepo(t, "nginx", "redis")}
+	githubClient = &fakeGitHubClient{createBlocks: true}
+	c = New(&fakeHelmClient{}, gitClient, githubClient, cfg)
+	c.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	if err := c.processUpdates(ctx, updates); err != nil {
+		t.Fatalf("processUpdates failed: %v", err)
+	}
+	if len(githubClient.created) != 0 {
+		t.Errorf("Expected no PRs after the grace period expired, got %d", len(githubClient.created))
+	}
+	if len(gitClient.deleted) != 1 || gitClient.deleted[0] != "update-nginx-1.1.0" {
+		t.Errorf("Expected the pushed branch to be deleted, got %v", gitClient.deleted)
+	}
+	if len(gitClient.pushed) != 1 {
+		t.Errorf("Expected the remaining update to be skipped, got pushes %v", gitClient.pushed)
+	}
+
+	// Without a grace period the in-flight update is aborted right away
+	logs := captureLog(t)
+	cfg.Checker.ShutdownGracePeriod = 0
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	gitClient = &fakeGitClient{onPush: cancel, repoPath: chartRepo(t, "nginx", "redis")}
+	githubClient = &fakeGitHubClient{createBlocks: true}
+	c = New(&fakeHelmClient{}, gitClient, githubClient, cfg)
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		t.Errorf("Expected no wait without a grace period, waited %s", d)
+		return nil
+	}
+
+	if err := c.processUpdates(ctx, updates); err != nil {
+		t.Fatalf("processUpdates failed: %v", err)
+	}
+	if len(githubClient.created) != 0 || len(gitClient.deleted) != 1 {
+		t.Errorf("Expected the in-flight update to be aborted, got %d PRs and deletions %v", len(githubClient.created), gitClient.deleted)
+	}
+	if strings.Contains(logs.String(), "grace period") {
+		t.Errorf("Expected no grace period warning, got:\n%s", logs.String())
+	}
+}
+
+func TestPRDeduplicationByLabel(t *testing.T) {
+	update := &ChartUpdate{Release: &helm.Release{Chart: "nginx"}, CurrentVersion: "1.0.0", LatestVersion: "1.2.0"}
+	// An open PR for the chart created under an older branch naming scheme
+	existing := &gh.PullRequest{Number: gh.Int(7), HTMLURL: gh.String("https://github.com/o/r/pull/7")}
+
+	tests := []struct {
+		strategy      string
+		expectCreated bool
+	}{
+		{config.DedupBranch, true},
+		{config.DedupLabel, false},
+		{config.DedupBoth, false},
+	}
+
+	for _, tt := range tests {
+		cfg := &config.Config{
+			Checker: config.CheckerConfig{
+				CommitMessage:    "chore: update helm chart %s to version %s",
+				PullRequestTitle: "Update Helm chart %s to version %s",
+				PullRequestBody:  "Updates %s from %s to %s",
+				PRDeduplication:  tt.strategy,
+			},
+		}
+		githubClient := &fakeGitHubClient{
+			labelled: map[string]*gh.PullRequest{"helmchecker/chart: nginx": existing},
+		}
+		c := New(&fakeHelmClient{}, &fakeGitClient{}, githubClient, cfg)
+
+		if err := c.processUpdate(context.Background(), chartRepo(t, update.Release.Chart), nil, update); err != nil {
+			t.Fatalf("%s: processUpdate failed: %v", tt.strategy, err)
+		}
+
+		if created := len(githubClient.created) > 0; created != tt.expectCreated {
+			t.Errorf("%s: expected PR created=%v, got %v", tt.strategy, tt.expectCreated, created)
+		}
+	}
+
+	// New PRs are labelled so later runs can find them
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+			PRDeduplication:  config.DedupLabel,
+		},
+	}
+	githubClient := &fakeGitHubClient{}
+	c := New(&fakeHelmClient{}, &fakeGitClient{}, githubClient, cfg)
+	if err := c.processUpdate(context.Background(), chartRepo(t, update.Release.Chart), nil, update); err != nil {
+		t.Fatalf("processUpdate failed: %v", err)
+	}
+	if labels := githubClient.labels[1]; len(labels) != 1 || labels[0] != "helmchecker/chart: nginx" {
+		t.Errorf("Expected new PR to carry the chart label, got %v", labels)
+	}
+}
+
+func TestCRDWarningSection(t *testing.T) {
+	if section := crdWarningSection(&helm.UpgradeSimulation{}); section != "" {
+		t.Errorf("Expected no section without CRDs, got %q", section)
+	}
+
+	simulation := &helm.UpgradeSimulation{
+		Changes: []helm.ManifestChange{
+			{Resource: "CustomResourceDefinition/widgets.example.com", Action: helm.ManifestChanged},
+		},
+		CRDs: []string{
+			"CustomResourceDefinition/gadgets.example.com",
+			"CustomResourceDefinition/widgets.example.com",
+		},
+	}
+
+	section := crdWarningSection(simulation)
+	for _, want := range []string{
+		"Helm does not upgrade CRDs",
+		"- `CustomResourceDefinition/gadgets.example.com` (unchanged)",
+		"- `CustomResourceDefinition/widgets.example.com` (changed)",
+	} {
+		if !strings.Contains(section, want) {
+			t.Errorf("Expected section to contain %q, got:\n%s", want, section)
+		}
+	}
+}
+
+type fakeHook struct {
+	err    error
+	events []*hooks.Event
+}
+
+func (f *fakeHook) Name() string {
+	return "fake"
+}
+
+func (f *fakeHook) Run(ctx context.Context, event *hooks.Event) error {
+	f.events = append(f.events, event)
+	return f.err
+}
+
+func TestPostUpdateHooks(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+		},
+	}
+	githubClient := &fakeGitHubClient{}
+	c := New(&fakeHelmClient{}, &fakeGitClient{}, githubClient, cfg)
+
+	failing := &fakeHook{err: fmt.Errorf("pipeline unavailable")}
+	recording := &fakeHook{}
+	c.hooks = []hooks.Hook{failing, recording}
+
+	logs := captureLog(t)
+	update := &ChartUpdate{
+		Release:        &helm.Release{Name: "web", Namespace: "apps", Chart: "nginx"},
+		CurrentVersion: "1.0.0",
+		LatestVersion:  "1.1.0",
+	}
+	if err := c.processUpdate(context.Background(), chartRepo(t, update.Release.Chart), nil, update); err != nil {
+		t.Fatalf("Expected hook failure not to fail the update, got %v", err)
+	}
+
+	if len(failing.events) != 1 || len(recording.events) != 1 {
+		t.Fatalf("Expected every hook to run once, got %d and %d", len(failing.events), len(recording.events))
+	}
+
+	expected := hooks.Event{
+		Chart:             "nginx",
+		Release:           "web",
+		Namespace:         "apps",
+		CurrentVersion:    "1.0.0",
+		LatestVersion:     "1.1.0",
+		Branch:            "update-nginx-1.1.0",
+		PullRequestNumber: 1,
+		PullRequestURL:    githubClient.created[0].GetHTMLURL(),
+	}
+	if *recording.events[0] != expected {
+		t.Errorf("Expected event %+v, got %+v", expected, *recording.events[0])
+	}
+
+	if !strings.Contains(logs.String(), "post-update hook fake failed for nginx: pipeline unavailable") {
+		t.Errorf("Expected hook failure to be logged, got:\n%s", logs.String())
+	}
+}
+
+func TestNegativeVersionCache(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			NegativeCacheTTL: time.Hour,
+			StateFile:        filepath.Join(t.TempDir(), "state.json"),
+		},
+	}
+	releases := []*helm.Release{
+		{Name: "web", Chart: "nginx", Version: "1.1.0", Repository: "https://charts.example.com"},
+		{Name: "cache", Chart: "redis", Version: "2.0.0", Repository: "https://charts.example.com"},
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	check := func(now time.Time, digest string) (*fakeHelmClient, []*ChartUpdate) {
+		helmClient := &fakeHelmClient{
+			latest: map[string]*helm.ChartVersion{
+				"nginx": {Version: "1.1.0"},
+				"redis": {Version: "2.1.0"},
+			},
+			indexDigest: digest,
+		}
+		c := New(helmClient, nil, nil, cfg)
+		c.now = func() time.Time { return now }
+
+		updates, err := c.checkForUpdates(context.Background(), releases)
+		if err != nil {
+			t.Fatalf("checkForUpdates failed: %v", err)
+		}
+		return helmClient, updates
+	}
+
+	// First run looks up both charts and caches that nginx is up to date
+	helmClient, updates := check(start, "index-1")
+	if helmClient.latestCalls != 2 {
+		t.Errorf("Expected 2 lookups on the first run, got %d", helmClient.latestCalls)
+	}
+	if len(updates) != 1 || updates[0].Release.Chart != "redis" {
+		t.Fatalf("Expected only redis to need an update, got %+v", updates)
+	}
+
+	// Within the TTL the nginx lookup is skipped; redis is never cached
+	helmClient, updates = check(start.Add(30*time.Minute), "index-1")
+	if helmClient.latestCalls != 1 {
+		t.Errorf("Expected only the redis lookup on a cache hit, got %d", helmClient.latestCalls)
+	}
+	if len(updates) != 1 || updates[0].Release.Chart != "redis" {
+		t.Errorf("Expected cached run to find the same updates, got %+v", updates)
+	}
+
+	// After the TTL both charts are looked up again
+	helmClient, _ = check(start.Add(2*time.Hour), "index-1")
+	if helmClient.latestCalls != 2 {
+		t.Errorf("Expected 2 lookups after the TTL, got %d", helmClient.latestCalls)
+	}
+
+	// A refreshed index invalidates the cache
+	helmClient, _ = check(start.Add(2*time.Hour+time.Minute), "index-2")
+	if helmClient.latestCalls != 2 {
+		t.Errorf("Expected 2 lookups after the index changed, got %d", helmClient.latestCalls)
+	}
+}
+
+func TestChartAnnotations(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+		},
+	}
+	helmClient := &fakeHelmClient{
+		releases: []*helm.Release{
+			{Name: "web", Chart: "nginx", Version: "1.0.0"},
+			{Name: "vendored", Chart: "ignored", Version: "1.0.0", Annotations: map[string]string{IgnoreAnnotation: "true"}},
+			{Name: "frozen", Chart: "skipped", Version: "1.0.0", Annotations: map[string]string{PolicyAnnotation: "skip"}},
+			{Name: "preview", Chart: "previewed", Version: "1.0.0", Annotations: map[string]string{PolicyAnnotation: "dry-run"}},
+			// The release label overrides the chart's ignore annotation
+			{Name: "override", Chart: "relabelled", Version: "1.0.0",
+				Annotations: map[string]string{IgnoreAnnotation: "true"},
+				Labels:      map[string]string{IgnoreAnnotation: "false"}},
+		},
+		latest: map[string]*helm.ChartVersion{
+			"nginx":      {Version: "1.1.0"},
+			"ignored":    {Version: "1.1.0"},
+			"skipped":    {Version: "1.1.0"},
+			"previewed":  {Version: "1.1.0"},
+			"relabelled": {Version: "1.1.0"},
+		},
+	}
+	githubClient := &fakeGitHubClient{}
+	c := New(helmClient, &fakeGitClient{repoPath: chartRepo(t, "nginx", "relabelled")}, githubClient, cfg)
+
+	logs := captureLog(t)
+	result, err := c.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if result.Checked != 3 {
+		t.Errorf("Expected annotated charts not to be checked, got %d checked", result.Checked)
+	}
+
+	var opened []string
+	for _, pr := range githubClient.created {
+		opened = append(opened, pr.GetHead().GetRef())
+	}
+	if len(opened) != 2 || opened[0] != "update-nginx-1.1.0" || opened[1] != "update-relabelled-1.1.0" {
+		t.Errorf("Expected PRs for nginx and relabelled only, got %v", opened)
+	}
+
+	for _, want := range []string{
+		"Skipping ignored: helmchecker.io/ignore is set",
+		"Skipping skipped: helmchecker.io/policy is skip",
+		"DRY RUN (helmchecker.io/policy annotation): Would update previewed from 1.0.0 to 1.1.0",
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("Expected log %q, got:\n%s", want, logs.String())
+		}
+	}
+}
+
+func TestProcessUpdatesReportsFailedClone(t *testing.T) {
+	cfg := &config.Config{
+		Git: config.GitConfig{Branch: "main"},
+		Checker: config.CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+		},
+	}
+	updates := []*ChartUpdate{
+		{Release: &helm.Release{Chart: "nginx"}, CurrentVersion: "1.0.0", LatestVersion: "1.1.0"},
+	}
+
+	gitClient := &fakeGitClient{repoPath: chartRepo(t, "nginx")}
+	c := New(&fakeHelmClient{}, gitClient, &fakeGitHubClient{}, cfg)
+	if err := c.processUpdates(context.Background(), updates); err != nil {
+		t.Fatalf("processUpdates failed: %v", err)
+	}
+	if gitClient.removedFailed {
+		t.Errorf("Expected clone removed as successful")
+	}
+
+	gitClient = &fakeGitClient{repoPath: chartRepo(t, "nginx")}
+	githubClient := &fakeGitHubClient{createErrs: map[string]error{"update-nginx-1.1.0": fmt.Errorf("validation failed")}}
+	c = New(&fakeHelmClient{}, gitClient, githubClient, cfg)
+	if err := c.processUpdates(context.Background(), updates); err != nil {
+		t.Fatalf("processUpdates failed: %v", err)
+	}
+	if !gitClient.removedFailed {
+		t.Errorf("Expected clone removed as failed after a failed update")
+	}
+}
+
+func TestManifestDiffReview(t *testing.T) {
+	cfg := &config.Config{
+		Git: config.GitConfig{Branch: "main"},
+		Checker: config.CheckerConfig{
+			CommitMessage:      "chore: update helm chart %s to version %s",
+			PullRequestTitle:   "Update Helm chart %s to version %s",
+			PullRequestBody:    "Updates %s from %s to %s",
+			ManifestDiffReview: true,
+		},
+	}
+	update := &ChartUpdate{Release: &helm.Release{Chart: "nginx"}, CurrentVersion: "1.0.0", LatestVersion: "1.1.0"}
+
+	helmClient := &fakeHelmClient{simulation: &helm.UpgradeSimulation{
+		Changes: []helm.ManifestChange{
+			{Resource: "Deployment/nginx", Action: helm.ManifestChanged, Diff: "-replicas: 1\n+replicas: 2\n"},
+		},
+	}}
+	githubClient := &fakeGitHubClient{}
+	c := New(helmClient, &fakeGitClient{repoPath: chartRepo(t, "nginx")}, githubClient, cfg)
+	if err := c.processUpdates(context.Background(), []*ChartUpdate{update}); err != nil {
+		t.Fatalf("processUpdates failed: %v", err)
+	}
+
+	if strings.Contains(githubClient.created[0].GetBody(), "Manifest changes") {
+		t.Errorf("Expected manifest diff to be left out of the body, got:\n%s", githubClient.created[0].GetBody())
+	}
+	reviews := githubClient.reviews[1]
+	if len(reviews) != 1 {
+		t.Fatalf("Expected 1 review, got %d", len(reviews))
+	}
+	if !strings.HasPrefix(reviews[0], "**Manifest changes:**") || !strings.Contains(reviews[0], "+replicas: 2") {
+		t.Errorf("Expected review with the manifest diff, got:\n%s", reviews[0])
+	}
+
+	// Nothing is posted when no rendered resources change
+	helmClient.simulation = &helm.UpgradeSimulation{}
+	githubClient = &fakeGitHubClient{}
+	c = New(helmClient, &fakeGitClient{repoPath: chartRepo(t, "nginx")}, githubClient, cfg)
+	if err := c.processUpdates(context.Background(), []*ChartUpdate{update}); err != nil {
+		t.Fatalf("processUpdates failed: %v", err)
+	}
+	if len(githubClient.created) != 1 {
+		t.Fatalf("Expected the pull request to be created, got %d", len(githubClient.created))
+	}
+	if len(githubClient.reviews) != 0 {
+		t.Errorf("Expected no review without manifest changes, got %v", githubClient.reviews)
+	}
+}
+
+func TestChartListFile(t *testing.T) {
+	listFile := filepath.Join(t.TempDir(), "charts.yaml")
+	writeList := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(listFile, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write chart list: %v", err)
+		}
+	}
+	writeList("includeCharts: [nginx, redis, grafana]\nexcludeCharts: [redis]\npolicies:\n  grafana: dry-run\n")
+
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+			ChartListFile:    listFile,
+		},
+	}
+	helmClient := &fakeHelmClient{
+		releases: []*helm.Release{
+			{Name: "web", Chart: "nginx", Version: "1.0.0"},
+			{Name: "cache", Chart: "redis", Version: "1.0.0"},
+			{Name: "dashboards", Chart: "grafana", Version: "1.0.0"},
+			{Name: "db", Chart: "postgres", Version: "1.0.0"},
+		},
+		latest: map[string]*helm.ChartVersion{
+			"nginx":    {Version: "1.1.0"},
+			"redis":    {Version: "1.1.0"},
+			"grafana":  {Version: "1.1.0"},
+			"postgres": {Version: "1.1.0"},
+		},
+	}
+	githubClient := &fakeGitHubClient{}
+	c := New(helmClient, &fakeGitClient{repoPath: chartRepo(t, "nginx", "postgres")}, githubClient, cfg)
+
+	logs := captureLog(t)
+	if _, err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(githubClient.created) != 1 || githubClient.created[0].GetHead().GetRef() != "update-nginx-1.1.0" {
+		t.Errorf("Expected a PR for nginx only, got %d PR(s)", len(githubClient.created))
+	}
+	if !strings.Contains(logs.String(), "DRY RUN (chart list policy): Would update grafana from 1.0.0 to 1.1.0") {
+		t.Errorf("Expected grafana to be a dry run, got:\n%s", logs.String())
+	}
+
+	// The list is reloaded on every run
+	writeList("includeCharts: [postgres]\n")
+	githubClient.created = nil
+	if _, err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(githubClient.created) != 1 || githubClient.created[0].GetHead().GetRef() != "update-postgres-1.1.0" {
+		t.Errorf("Expected a PR for postgres only after reload, got %d PR(s)", len(githubClient.created))
+	}
+
+	// An invalid list fails the run
+	writeList("policies:\n  nginx: sometimes\n")
+	if _, err := c.Run(context.Background()); err == nil {
+		t.Errorf("Expected invalid chart list to fail the run")
+	}
+}
+
+func TestProcessUpdatesRefreshesBaseBranch(t *testing.T) {
+	cfg := &config.Config{
+		Git: config.GitConfig{Branch: "main"},
+		Checker: config.CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+		},
+	}
+	gitClient := &fakeGitClient{repoPath: chartRepo(t, "nginx", "redis")}
+	c := New(&fakeHelmClient{}, gitClient, &fakeGitHubClient{}, cfg)
+
+	updates := []*ChartUpdate{
+		{Release: &helm.Release{Chart: "nginx"}, CurrentVersion: "1.0.0", LatestVersion: "1.1.0"},
+		{Release: &helm.Release{Chart: "redis"}, CurrentVersion: "2.0.0", LatestVersion: "2.1.0"},
+	}
+	if err := c.processUpdates(context.Background(), updates); err != nil {
+		t.Fatalf("processUpdates failed: %v", err)
+	}
+
+	// Each update fetches and checks out the latest base before branching
+	expected := []string{"main", "main"}
+	if strings.Join(gitClient.fetched, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected base fetched before each update, got %v", gitClient.fetched)
+	}
+	if strings.Join(gitClient.checkouts, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected base checked out before each update, got %v", gitClient.checkouts)
+	}
+	if len(gitClient.branches) != 2 {
+		t.Errorf("Expected 2 update branches, got %v", gitClient.branches)
+	}
+}
+
+func TestPrefetchOpenPRs(t *testing.T) {
+	cfg := &config.Config{
+		Git: config.GitConfig{Branch: "main"},
+		Checker: config.CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+			PRDeduplication:  config.DedupBoth,
+			PrefetchOpenPRs:  true,
+		},
+	}
+	githubClient := &fakeGitHubClient{
+		// CheckIfPRExists and FindPRByLabel would find nothing
+		open: []*gh.PullRequest{
+			{
+				Number:  gh.Int(3),
+				HTMLURL: gh.String("https://github.com/o/r/pull/3"),
+				Head:    &gh.PullRequestBranch{Ref: gh.String("update-nginx-1.1.0")},
+				Base:    &gh.PullRequestBranch{Ref: gh.String("main")},
+			},
+			{
+				Number:  gh.Int(4),
+				HTMLURL: gh.String("https://github.com/o/r/pull/4"),
+				Head:    &gh.PullRequestBranch{Ref: gh.String("update-redis-2.0.5")},
+				Base:    &gh.PullRequestBranch{Ref: gh.String("main")},
+				Labels:  []*gh.Label{{Name: gh.String("helmchecker/chart: redis")}},
+			},
+			{
+				// Same branch name against another base
+				Number:  gh.Int(5),
+				HTMLURL: gh.String("https://github.com/o/r/pull/5"),
+				Head:    &gh.PullRequestBranch{Ref: gh.String("update-mysql-9.1.0")},
+				Base:    &gh.PullRequestBranch{Ref: gh.String("release")},
+			},
+		},
+	}
+	gitClient := &fakeGitClient{repoPath: chartRepo(t, "nginx", "redis", "mysql")}
+	c := New(&fakeHelmClient{}, gitClient, githubClient, cfg)
+
+	updates := []*ChartUpdate{
+		{Release: &helm.Release{Chart: "nginx"}, CurrentVersion: "1.0.0", LatestVersion: "1.1.0"},
+		{Release: &helm.Release{Chart: "redis"}, CurrentVersion: "2.0.0", LatestVersion: "2.1.0"},
+		{Release: &helm.Release{Chart: "mysql"}, CurrentVersion: "9.0.0", LatestVersion: "9.1.0"},
+	}
+	if err := c.processUpdates(context.Background(), updates); err != nil {
+		t.Fatalf("processUpdates failed: %v", err)
+	}
+
+	if githubClient.listCalls != 1 {
+		t.Errorf("Expected open pull requests to be listed once, got %d", githubClient.listCalls)
+	}
+	// nginx matches by branch and redis by label; only mysql gets a PR
+	if len(githubClient.created) != 1 || githubClient.created[0].GetHead().GetRef() != "update-mysql-9.1.0" {
+		t.Errorf("Expected a single PR for mysql, got %d", len(githubClient.created))
+	}
+	if c.result.Skipped != 2 {
+		t.Errorf("Expected 2 updates skipped for existing PRs, got %d", c.result.Skipped)
+	}
+}
+
+func TestPrefetchOpenPRsDisabled(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+		},
+	}
+	githubClient := &fakeGitHubClient{}
+	gitClient := &fakeGitClient{repoPath: chartRepo(t, "nginx")}
+	c := New(&fakeHelmClient{}, gitClient, githubClient, cfg)
+
+	updates := []*ChartUpdate{
+		{Release: &helm.Release{Chart: "nginx"}, CurrentVersion: "1.0.0", LatestVersion: "1.1.0"},
+	}
+	if err := c.processUpdates(context.Background(), updates); err != nil {
+		t.Fatalf("processUpdates failed: %v", err)
+	}
+	if githubClient.listCalls != 0 {
+		t.Errorf("Expected no open pull request listing by default, got %d", githubClient.listCalls)
+	}
+}
@@ -0,0 +1,154 @@
+package checker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/marccoxall/helmchecker/internal/config"
+	"github.com/marccoxall/helmchecker/internal/helm"
+)
+
+// VersionComparator orders the versions of one versioning scheme
+type VersionComparator interface {
+	// Validate returns an error if version is not valid in the scheme
+	Validate(version string) error
+	// Compare returns -1, 0 or 1 as a is older than, equal to or newer than b.
+	// Both versions must be valid.
+	Compare(a, b string) int
+}
+
+// versionComparators are the available comparators by version scheme
+var versionComparators = map[string]VersionComparator{
+	config.VersionSchemeSemver:  SemverComparator{},
+	config.VersionSchemeDate:    DateComparator{},
+	config.VersionSchemeInteger: IntegerComparator{},
+}
+
+// comparatorFor returns the comparator for a chart's configured version
+// scheme, defaulting to semantic versioning
+func (c *Checker) comparatorFor(chart string) VersionComparator {
+	if comparator, ok := versionComparators[c.config.Checker.VersionSchemes[chart]]; ok {
+		return comparator
+	}
+	return SemverComparator{}
+}
+
+// indexComparator returns the comparator ordering a chart's versions when
+// resolving the latest one, or nil for semantic versions, the default order
+// of the repository indexes
+func (c *Checker) indexComparator(chart string) helm.VersionComparator {
+	comparator := c.comparatorFor(chart)
+	if _, ok := comparator.(SemverComparator); ok {
+		return nil
+	}
+	return comparator
+}
+
+// SemverComparator orders semantic versions. Partial versions are normalized,
+// so 1.0, 1.0.0 and v1.0.0 are equal.
+type SemverComparator struct{}
+
+// Validate implements VersionComparator
+func (SemverComparator) Validate(version string) error {
+	_, err := semver.NewVersion(strings.TrimSpace(version))
+	return err
+}
+
+// Compare implements VersionComparator
+func (SemverComparator) Compare(a, b string) int {
+	va, _ := semver.NewVersion(strings.TrimSpace(a))
+	vb, _ := semver.NewVersion(strings.TrimSpace(b))
+	return va.Compare(vb)
+}
+
+// DateComparator orders date-based versions such as 2024.01.02 or
+// 2024-01-02.1 by comparing their numeric components in turn. Missing
+// trailing components count as zero.
+type DateComparator struct{}
+
+// Validate implements VersionComparator
+func (DateComparator) Validate(version string) error {
+	_, err := numericComponents(version)
+	return err
+}
+
+// Compare implements VersionComparator
+func (DateComparator) Compare(a, b string) int {
+	ca, _ := numericComponents(a)
+	cb, _ := numericComponents(b)
+	for i := 0; i < len(ca) || i < len(cb); i++ {
+		var na, nb uint64
+		if i < len(ca) {
+			na = ca[i]
+		}
+		if i < len(cb) {
+			nb = cb[i]
+		}
+		if cmp := compareUint(na, nb); cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+// IntegerComparator orders monotonically increasing integer versions such as
+// 41 or v42
+type IntegerComparator struct{}
+
+// Validate implements VersionComparator
+func (IntegerComparator) Validate(version string) error {
+	_, err := parseInteger(version)
+	return err
+}
+
+// Compare implements VersionComparator
+func (IntegerComparator) Compare(a, b string) int {
+	ia, _ := parseInteger(a)
+	ib, _ := parseInteger(b)
+	return compareUint(ia, ib)
+}
+
+// numericComponents splits a version on dots, dashes and underscores into
+// numeric components
+func numericComponents(version string) ([]uint64, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	parts := strings.FieldsFunc(trimmed, func(r rune) bool {
+		return r == '.' || r == '-' || r == '_'
+	})
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("no numeric components")
+	}
+
+	components := make([]uint64, len(parts))
+	for i, part := range parts {
+		n, err := parseInteger(part)
+		if err != nil {
+			return nil, err
+		}
+		components[i] = n
+	}
+	return components, nil
+}
+
+// parseInteger parses a non-negative integer version with an optional v prefix
+func parseInteger(version string) (uint64, error) {
+	n, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(version), "v"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a non-negative integer", version)
+	}
+	return n, nil
+}
+
+// compareUint returns -1, 0 or 1 as a is less than, equal to or greater than b
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
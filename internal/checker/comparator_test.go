@@ -0,0 +1,138 @@
+package checker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/marccoxall/helmchecker/internal/config"
+	"github.com/marccoxall/helmchecker/internal/helm"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func TestVersionComparators(t *testing.T) {
+	tests := []struct {
+		comparator VersionComparator
+		a, b       string
+		expected   int
+	}{
+		{SemverComparator{}, "1.10.0", "1.9.0", 1},
+		{SemverComparator{}, "1.0.0-rc.1", "1.0.0", -1},
+		{SemverComparator{}, "v1.0", "1.0.0", 0},
+		{DateComparator{}, "2024.01.02", "2023.12.31", 1},
+		{DateComparator{}, "2024.01.02", "2024.1.2", 0},
+		{DateComparator{}, "2024.01.02", "2024.01.02.1", -1},
+		{DateComparator{}, "2024-02-01", "2024-01-15", 1},
+		{IntegerComparator{}, "42", "41", 1},
+		{IntegerComparator{}, "v9", "10", -1},
+		{IntegerComparator{}, "007", "7", 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.comparator.Compare(tt.a, tt.b); got != tt.expected {
+			t.Errorf("%T.Compare(%q, %q) = %d, expected %d", tt.comparator, tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestVersionComparatorsValidate(t *testing.T) {
+	invalid := []struct {
+		comparator VersionComparator
+		version    string
+	}{
+		{SemverComparator{}, "latest"},
+		{DateComparator{}, "2024.01.beta"},
+		{DateComparator{}, ""},
+		{IntegerComparator{}, "1.2"},
+		{IntegerComparator{}, "-3"},
+	}
+
+	for _, tt := range invalid {
+		if err := tt.comparator.Validate(tt.version); err == nil {
+			t.Errorf("%T.Validate(%q): expected error", tt.comparator, tt.version)
+		}
+	}
+}
+
+func TestClassifyVersionChangeUsesChartScheme(t *testing.T) {
+	c := New(nil, nil, nil, &config.Config{Checker: config.CheckerConfig{
+		VersionSchemes: map[string]string{
+			"calendar": config.VersionSchemeDate,
+			"build":    config.VersionSchemeInteger,
+		},
+	}})
+
+	tests := []struct {
+		chart           string
+		latest, current string
+		expected        VersionChange
+	}{
+		// Date and integer versions have no patch, minor or major level
+		{"calendar", "2024.10.01", "2024.09.30", VersionNewer},
+		{"calendar", "2024.01.02", "2024.01.02", VersionUnchanged},
+		{"calendar", "2023.12.31", "2024.01.02", VersionDowngrade},
+		{"build", "120", "119", VersionNewer},
+		{"nginx", "1.2.0", "1.1.0", VersionMinor},
+	}
+
+	for _, tt := range tests {
+		change, err := c.classifyVersionChange(tt.chart, tt.latest, tt.current)
+		if err != nil {
+			t.Errorf("classifyVersionChange(%s, %q, %q): unexpected error: %v", tt.chart, tt.latest, tt.current, err)
+			continue
+		}
+		if change != tt.expected {
+			t.Errorf("classifyVersionChange(%s, %q, %q) = %s, expected %s", tt.chart, tt.latest, tt.current, change, tt.expected)
+		}
+	}
+
+	change, err := c.classifyVersionChange("build", "120", "119")
+	if err != nil || change != VersionNewer {
+		t.Errorf("Expected 120 to be newer than 119, got %s (err=%v)", change, err)
+	}
+
+	if _, err := c.classifyVersionChange("build", "120", "1.2.0"); err == nil {
+		t.Errorf("Expected an error for a version outside the chart's scheme")
+	}
+}
+
+func TestResolveLatestUsesChartScheme(t *testing.T) {
+	releases := []*helm.Release{
+		{Name: "feed", Chart: "feed", Version: "2024-01-02.1"},
+		{Name: "agent", Chart: "agent", Version: "41"},
+	}
+	index := repo.NewIndexFile()
+	for name, versions := range map[string][]string{
+		// As semantic versions these are all pre-releases of 2024.0.0
+		"feed":  {"2023-12-31", "2024-01-02.1", "2024-01-10", "2024-01-09.3", "latest"},
+		"agent": {"9", "41", "42", "v40"},
+	} {
+		for _, v := range versions {
+			index.Entries[name] = append(index.Entries[name], &repo.ChartVersion{Metadata: &chart.Metadata{Name: name, Version: v}})
+		}
+	}
+
+	c := New(&fakeHelmClient{releases: releases, index: index}, nil, nil, &config.Config{Checker: config.CheckerConfig{
+		VersionSchemes: map[string]string{
+			"feed":  config.VersionSchemeDate,
+			"agent": config.VersionSchemeInteger,
+		},
+	}})
+	updates, err := c.checkForUpdates(context.Background(), releases)
+	if err != nil {
+		t.Fatalf("checkForUpdates failed: %v", err)
+	}
+
+	expected := map[string]string{"feed": "2024-01-10", "agent": "42"}
+	if len(updates) != len(expected) {
+		t.Fatalf("Expected %d updates, got %d", len(expected), len(updates))
+	}
+	for _, update := range updates {
+		if update.LatestVersion != expected[update.Release.Chart] {
+			t.Errorf("Expected %s to resolve to %s, got %s", update.Release.Chart, expected[update.Release.Chart], update.LatestVersion)
+		}
+		if update.Change != VersionNewer {
+			t.Errorf("Expected %s to be a newer version, got %s", update.Release.Chart, update.Change)
+		}
+	}
+}
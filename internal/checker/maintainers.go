@@ -0,0 +1,76 @@
+package checker
+
+import (
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/marccoxall/helmchecker/internal/helm"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// maintainerReviewers resolves the maintainers listed in a release's chart to
+// GitHub reviewers. Maintainers are looked up in the configured mapping by
+// email and then name, falling back to a github.com profile URL; maintainers
+// without a GitHub handle are logged and skipped.
+func (c *Checker) maintainerReviewers(release *helm.Release) []string {
+	if !c.config.Checker.RequestMaintainerReviews {
+		return nil
+	}
+
+	var reviewers []string
+	for _, maintainer := range release.Maintainers {
+		if maintainer == nil {
+			continue
+		}
+
+		reviewer := c.maintainerHandle(maintainer)
+		if reviewer == "" {
+			log.Printf("Maintainer %q of %s has no GitHub handle, not requesting their review", maintainer.Name, release.Chart)
+			continue
+		}
+		reviewers = append(reviewers, reviewer)
+	}
+	return reviewers
+}
+
+// maintainerHandle returns the GitHub user or org/team for a chart maintainer,
+// or "" if none is known
+func (c *Checker) maintainerHandle(maintainer *chart.Maintainer) string {
+	mapping := c.config.Checker.MaintainerReviewers
+	if handle, ok := mapping[maintainer.Email]; ok && maintainer.Email != "" {
+		return handle
+	}
+	if handle, ok := mapping[maintainer.Name]; ok && maintainer.Name != "" {
+		return handle
+	}
+
+	u, err := url.Parse(maintainer.URL)
+	if err != nil || !strings.EqualFold(u.Hostname(), "github.com") {
+		return ""
+	}
+	// Only profile URLs such as https://github.com/alice name a user
+	handle := strings.Trim(u.Path, "/")
+	if handle == "" || strings.Contains(handle, "/") {
+		return ""
+	}
+	return handle
+}
+
+// mergeReviewers returns reviewers followed by those in extra not already
+// listed, without modifying reviewers
+func mergeReviewers(reviewers, extra []string) []string {
+	merged := append([]string(nil), reviewers...)
+	seen := make(map[string]bool, len(reviewers))
+	for _, reviewer := range reviewers {
+		seen[strings.ToLower(reviewer)] = true
+	}
+	for _, reviewer := range extra {
+		if seen[strings.ToLower(reviewer)] {
+			continue
+		}
+		seen[strings.ToLower(reviewer)] = true
+		merged = append(merged, reviewer)
+	}
+	return merged
+}
@@ -0,0 +1,91 @@
+package checker
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/marccoxall/helmchecker/internal/config"
+	"github.com/marccoxall/helmchecker/internal/helm"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestMaintainerReviewers(t *testing.T) {
+	release := &helm.Release{
+		Chart: "payments",
+		Maintainers: []*chart.Maintainer{
+			{Name: "Alice", Email: "alice@example.com"},
+			{Name: "Payments Team"},
+			{Name: "Bob", URL: "https://github.com/bob-gh"},
+			{Name: "Carol", URL: "https://example.com/carol"},
+			{Name: "Dave", URL: "https://github.com/org/repo"},
+			nil,
+		},
+	}
+	c := New(nil, nil, nil, &config.Config{Checker: config.CheckerConfig{
+		RequestMaintainerReviews: true,
+		MaintainerReviewers: map[string]string{
+			"alice@example.com": "alice-gh",
+			"Payments Team":     "org/payments",
+		},
+	}})
+
+	reviewers := c.maintainerReviewers(release)
+
+	// Carol and Dave have no GitHub handle and are skipped
+	expected := []string{"alice-gh", "org/payments", "bob-gh"}
+	if strings.Join(reviewers, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected reviewers %v, got %v", expected, reviewers)
+	}
+
+	// Maintainer reviews are opt-in
+	c.config.Checker.RequestMaintainerReviews = false
+	if reviewers := c.maintainerReviewers(release); len(reviewers) != 0 {
+		t.Errorf("Expected no reviewers when disabled, got %v", reviewers)
+	}
+}
+
+func TestMergeReviewers(t *testing.T) {
+	rule := []string{"alice", "org/payments"}
+	merged := mergeReviewers(rule, []string{"Alice", "bob"})
+
+	if strings.Join(merged, ",") != "alice,org/payments,bob" {
+		t.Errorf("Unexpected merged reviewers %v", merged)
+	}
+	if len(rule) != 2 {
+		t.Errorf("Expected rule reviewers to be unchanged, got %v", rule)
+	}
+}
+
+func TestProcessUpdateRequestsMaintainerReviews(t *testing.T) {
+	cfg := &config.Config{
+		Git: config.GitConfig{Branch: "main"},
+		Checker: config.CheckerConfig{
+			CommitMessage:            "chore: update helm chart %s to version %s",
+			PullRequestTitle:         "Update Helm chart %s to version %s",
+			PullRequestBody:          "Updates %s from %s to %s",
+			RequestMaintainerReviews: true,
+			DirectoryRules: []config.DirectoryRule{
+				{PathPrefix: "charts/", Reviewers: []string{"org/platform"}},
+			},
+		},
+	}
+	githubClient := &fakeGitHubClient{}
+	c := New(&fakeHelmClient{}, &fakeGitClient{repoPath: chartRepo(t, "nginx")}, githubClient, cfg)
+
+	update := &ChartUpdate{
+		Release: &helm.Release{
+			Chart:       "nginx",
+			Maintainers: []*chart.Maintainer{{Name: "Bob", URL: "https://github.com/bob-gh"}},
+		},
+		CurrentVersion: "1.0.0",
+		LatestVersion:  "1.1.0",
+	}
+	if err := c.processUpdates(context.Background(), []*ChartUpdate{update}); err != nil {
+		t.Fatalf("processUpdates failed: %v", err)
+	}
+
+	if got := strings.Join(githubClient.reviewers[1], ","); got != "org/platform,bob-gh" {
+		t.Errorf("Expected directory rule and maintainer reviewers, got %q", got)
+	}
+}
@@ -0,0 +1,63 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// migrationIssueTitle returns the title of the issue announcing a major
+// version that needs a manual migration
+func migrationIssueTitle(update *ChartUpdate) string {
+	return fmt.Sprintf("Manual upgrade required: Helm chart %s %s to %s",
+		update.Release.Chart,
+		update.CurrentVersion,
+		update.LatestVersion)
+}
+
+// migrationIssueBody describes the major version and links its migration guide
+func (c *Checker) migrationIssueBody(update *ChartUpdate) string {
+	body := fmt.Sprintf("Helm chart %s has a new major version %s (installed: %s). "+
+		"It is configured to require a manual migration across major versions, so no update pull request was opened.\n",
+		update.Release.Chart,
+		update.LatestVersion,
+		update.CurrentVersion)
+
+	if guide := c.config.Checker.ManualMajorCharts[update.Release.Chart]; guide != "" {
+		body += fmt.Sprintf("\n**Migration guide:** %s\n", guide)
+	}
+
+	return body + reasonsSection(update)
+}
+
+// openMigrationIssue opens an informational issue for an update across a major
+// version that needs a manual migration, unless one is already open
+func (c *Checker) openMigrationIssue(ctx context.Context, update *ChartUpdate) error {
+	c.result.Skipped++
+	c.result.Blocked = append(c.result.Blocked, update)
+
+	title := migrationIssueTitle(update)
+	existing, err := c.githubClient.FindOpenIssue(ctx,
+		c.config.GitHub.Owner,
+		c.config.GitHub.Repo,
+		title)
+	if err != nil {
+		return fmt.Errorf("failed to check for existing migration issue: %w", err)
+	}
+	if existing != nil {
+		log.Printf("Migration issue already exists for %s: %s", update.Release.Chart, existing.GetHTMLURL())
+		return nil
+	}
+
+	issue, err := c.githubClient.CreateIssue(ctx,
+		c.config.GitHub.Owner,
+		c.config.GitHub.Repo,
+		title,
+		c.migrationIssueBody(update))
+	if err != nil {
+		return fmt.Errorf("failed to create migration issue: %w", err)
+	}
+
+	log.Printf("Opened migration issue for %s %s: %s", update.Release.Chart, update.LatestVersion, issue.GetHTMLURL())
+	return nil
+}
@@ -0,0 +1,87 @@
+package checker
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/marccoxall/helmchecker/internal/config"
+	"github.com/marccoxall/helmchecker/internal/helm"
+)
+
+func TestManualMajorOpensIssue(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+			ManualMajorCharts: map[string]string{
+				"cert-manager": "https://cert-manager.io/docs/releases/upgrading/",
+			},
+		},
+	}
+	helmClient := &fakeHelmClient{
+		releases: []*helm.Release{
+			{Name: "certs", Chart: "cert-manager", Version: "1.4.0"},
+			{Name: "web", Chart: "nginx", Version: "1.0.0"},
+		},
+		latest: map[string]*helm.ChartVersion{
+			"cert-manager": {Version: "2.0.0"},
+			"nginx":        {Version: "2.0.0"},
+		},
+	}
+	githubClient := &fakeGitHubClient{}
+	c := New(helmClient, &fakeGitClient{repoPath: chartRepo(t, "nginx")}, githubClient, cfg)
+
+	result, err := c.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// Only charts without a manual migration requirement get a bump PR
+	if len(githubClient.created) != 1 || githubClient.created[0].GetHead().GetRef() != "update-nginx-2.0.0" {
+		t.Errorf("Expected a bump PR for nginx only, got %d PR(s)", len(githubClient.created))
+	}
+
+	if len(githubClient.issues) != 1 {
+		t.Fatalf("Expected 1 migration issue, got %d", len(githubClient.issues))
+	}
+	issue := githubClient.issues[0]
+	if issue.GetTitle() != "Manual upgrade required: Helm chart cert-manager 1.4.0 to 2.0.0" {
+		t.Errorf("Unexpected issue title %q", issue.GetTitle())
+	}
+	if !strings.Contains(issue.GetBody(), "https://cert-manager.io/docs/releases/upgrading/") {
+		t.Errorf("Expected migration guide link in issue body, got:\n%s", issue.GetBody())
+	}
+	if len(result.Blocked) != 1 || !result.Blocked[0].HasReason(ReasonManualMigration) {
+		t.Errorf("Expected the major update to be reported as blocked, got %v", result.Blocked)
+	}
+
+	// The issue is not opened again on the next run
+	if _, err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(githubClient.issues) != 1 {
+		t.Errorf("Expected the existing migration issue to be reused, got %d issues", len(githubClient.issues))
+	}
+}
+
+func TestManualMajorAllowsMinorUpdates(t *testing.T) {
+	c := New(nil, nil, nil, &config.Config{Checker: config.CheckerConfig{
+		ManualMajorCharts: map[string]string{"cert-manager": ""},
+	}})
+	c.helmClient = &fakeHelmClient{latest: map[string]*helm.ChartVersion{"cert-manager": {Version: "1.5.0"}}}
+
+	updates, err := c.checkForUpdates(context.Background(), []*helm.Release{{Name: "certs", Chart: "cert-manager", Version: "1.4.0"}})
+	if err != nil {
+		t.Fatalf("checkForUpdates failed: %v", err)
+	}
+	if len(updates) != 1 || updates[0].HasReason(ReasonManualMigration) {
+		t.Errorf("Expected a regular minor update, got %+v", updates)
+	}
+
+	body := c.migrationIssueBody(&ChartUpdate{Release: &helm.Release{Chart: "cert-manager"}, CurrentVersion: "1.4.0", LatestVersion: "2.0.0"})
+	if strings.Contains(body, "Migration guide") {
+		t.Errorf("Expected no guide link when none is configured, got:\n%s", body)
+	}
+}
@@ -0,0 +1,145 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/marccoxall/helmchecker/internal/helm"
+)
+
+// UpdateReason classifies why a chart update matters
+type UpdateReason string
+
+// Update reasons
+const (
+	// ReasonNewVersion means a newer chart version is available
+	ReasonNewVersion UpdateReason = "new-version"
+	// ReasonDeprecated means the chart is marked deprecated upstream
+	ReasonDeprecated UpdateReason = "deprecated"
+	// ReasonCRDs means the target version ships CRDs that Helm won't upgrade
+	ReasonCRDs UpdateReason = "crds"
+	// ReasonSecurityFix means the target version is flagged as containing security fixes
+	ReasonSecurityFix UpdateReason = "security-fix"
+	// ReasonDowngradeBlocked means the latest version is lower than the installed one
+	ReasonDowngradeBlocked UpdateReason = "downgrade-blocked"
+	// ReasonManualMigration means the new major version needs a manual migration
+	ReasonManualMigration UpdateReason = "manual-migration"
+	// ReasonRemovedValues means release values are set that the target version no longer defines
+	ReasonRemovedValues UpdateReason = "removed-values"
+	// ReasonPinned means the target version is pinned in configuration rather than the latest
+	ReasonPinned UpdateReason = "pinned"
+)
+
+// SecurityUpdatesAnnotation is the Artifact Hub chart annotation flagging
+// versions that contain security fixes
+const SecurityUpdatesAnnotation = "artifacthub.io/containsSecurityUpdates"
+
+// Description returns a human-readable description of the reason
+func (r UpdateReason) Description() string {
+	switch r {
+	case ReasonNewVersion:
+		return "A newer chart version is available"
+	case ReasonDeprecated:
+		return "The chart is deprecated upstream; plan a migration"
+	case ReasonCRDs:
+		return "The chart ships CRDs that need manual upgrades"
+	case ReasonSecurityFix:
+		return "The new version contains security fixes"
+	case ReasonDowngradeBlocked:
+		return "The latest version is lower than the installed one; update refused"
+	case ReasonManualMigration:
+		return "The new major version requires a manual migration; no automatic update is made"
+	case ReasonRemovedValues:
+		return "Values set on the release no longer exist in the new version"
+	case ReasonPinned:
+		return "The target version is pinned in configuration"
+	default:
+		return string(r)
+	}
+}
+
+// updateReasons classifies an available update from its latest version
+func updateReasons(latest *helm.ChartVersion) []UpdateReason {
+	reasons := []UpdateReason{ReasonNewVersion}
+	if latest.Deprecated {
+		reasons = append(reasons, ReasonDeprecated)
+	}
+	if strings.EqualFold(latest.Annotations[SecurityUpdatesAnnotation], "true") {
+		reasons = append(reasons, ReasonSecurityFix)
+	}
+	return reasons
+}
+
+// HasReason reports whether the update carries reason
+func (u *ChartUpdate) HasReason(reason UpdateReason) bool {
+	for _, r := range u.Reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// addReason adds reason to the update unless it is already present
+func (u *ChartUpdate) addReason(reason UpdateReason) {
+	if !u.HasReason(reason) {
+		u.Reasons = append(u.Reasons, reason)
+	}
+}
+
+// reasonsSection lists why the update matters for the PR body
+func reasonsSection(update *ChartUpdate) string {
+	if len(update.Reasons) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\n**Why this update:**\n")
+	for _, reason := range update.Reasons {
+		description := reason.Description()
+		if reason == ReasonNewVersion && update.Change != VersionUnchanged {
+			description = fmt.Sprintf("%s (%s)", description, update.Change)
+		}
+		fmt.Fprintf(&b, "- %s\n", description)
+	}
+	return b.String()
+}
+
+// releaseDetailsSection lists the provenance of the latest version from its
+// repository index entry, so reviewers can see where it comes from
+func releaseDetailsSection(update *ChartUpdate) string {
+	target := update.Target
+	if target == nil {
+		return ""
+	}
+
+	var details []string
+	if target.AppVersion != "" {
+		details = append(details, fmt.Sprintf("App version: %s", target.AppVersion))
+	}
+	if !target.Created.IsZero() {
+		details = append(details, fmt.Sprintf("Published: %s", target.Created.UTC().Format("2006-01-02 15:04 UTC")))
+	}
+	if target.Digest != "" {
+		details = append(details, fmt.Sprintf("Digest: `%s`", target.Digest))
+	}
+	if target.URL != "" {
+		details = append(details, fmt.Sprintf("Download: %s", target.URL))
+	}
+	if target.Home != "" {
+		details = append(details, fmt.Sprintf("Home: %s", target.Home))
+	}
+	if len(target.Sources) > 0 {
+		details = append(details, fmt.Sprintf("Sources: %s", strings.Join(target.Sources, ", ")))
+	}
+	if len(details) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\n**Release details:**\n")
+	for _, detail := range details {
+		fmt.Fprintf(&b, "- %s\n", detail)
+	}
+	return b.String()
+}
@@ -0,0 +1,195 @@
+package checker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marccoxall/helmchecker/internal/config"
+	"github.com/marccoxall/helmchecker/internal/helm"
+)
+
+func TestUpdateReasons(t *testing.T) {
+	helmClient := &fakeHelmClient{
+		releases: []*helm.Release{
+			{Name: "web", Chart: "nginx", Version: "1.0.0"},
+			{Name: "old", Chart: "legacy", Version: "1.0.0"},
+			{Name: "auth", Chart: "keycloak", Version: "1.0.0"},
+			{Name: "cache", Chart: "redis", Version: "2.0.0"},
+		},
+		latest: map[string]*helm.ChartVersion{
+			"nginx":    {Version: "1.1.0"},
+			"legacy":   {Version: "2.0.0", Deprecated: true},
+			"keycloak": {Version: "1.0.1", Annotations: map[string]string{SecurityUpdatesAnnotation: "true"}},
+			"redis":    {Version: "1.9.0"},
+		},
+	}
+	c := New(helmClient, nil, nil, &config.Config{})
+
+	updates, err := c.checkForUpdates(context.Background(), helmClient.releases)
+	if err != nil {
+		t.Fatalf("checkForUpdates failed: %v", err)
+	}
+
+	expected := map[string][]UpdateReason{
+		"nginx":    {ReasonNewVersion},
+		"legacy":   {ReasonNewVersion, ReasonDeprecated},
+		"keycloak": {ReasonNewVersion, ReasonSecurityFix},
+	}
+	if len(updates) != len(expected) {
+		t.Fatalf("Expected %d updates, got %d", len(expected), len(updates))
+	}
+	for _, update := range updates {
+		want := expected[update.Release.Chart]
+		if len(update.Reasons) != len(want) {
+			t.Errorf("%s: expected reasons %v, got %v", update.Release.Chart, want, update.Reasons)
+			continue
+		}
+		for i := range want {
+			if update.Reasons[i] != want[i] {
+				t.Errorf("%s: expected reasons %v, got %v", update.Release.Chart, want, update.Reasons)
+				break
+			}
+		}
+	}
+
+	// The downgrade is blocked and recorded with its reason
+	if len(c.result.Blocked) != 1 {
+		t.Fatalf("Expected one blocked update, got %d", len(c.result.Blocked))
+	}
+	blocked := c.result.Blocked[0]
+	if blocked.Release.Chart != "redis" || !blocked.HasReason(ReasonDowngradeBlocked) {
+		t.Errorf("Expected redis to be blocked as a downgrade, got %s %v", blocked.Release.Chart, blocked.Reasons)
+	}
+}
+
+func TestUpdateReasonCRDs(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+		},
+	}
+	helmClient := &fakeHelmClient{
+		simulation: &helm.UpgradeSimulation{CRDs: []string{"CustomResourceDefinition/widgets.example.com"}},
+	}
+	githubClient := &fakeGitHubClient{}
+	c := New(helmClient, &fakeGitClient{}, githubClient, cfg)
+
+	update := &ChartUpdate{
+		Release:        &helm.Release{Chart: "operator"},
+		CurrentVersion: "1.0.0",
+		LatestVersion:  "1.1.0",
+		Change:         VersionMinor,
+		Reasons:        []UpdateReason{ReasonNewVersion},
+	}
+	if err := c.processUpdate(context.Background(), chartRepo(t, update.Release.Chart), nil, update); err != nil {
+		t.Fatalf("processUpdate failed: %v", err)
+	}
+
+	if !update.HasReason(ReasonCRDs) {
+		t.Errorf("Expected CRD reason to be added, got %v", update.Reasons)
+	}
+
+	body := githubClient.created[0].GetBody()
+	for _, want := range []string{
+		"**Why this update:**",
+		"- A newer chart version is available (minor)",
+		"- The chart ships CRDs that need manual upgrades",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected PR body to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestUpdateReasonRemovedValues(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+		},
+	}
+	helmClient := &fakeHelmClient{
+		simulation: &helm.UpgradeSimulation{RemovedValues: []string{"image.pullPolicy", "legacyMode"}},
+	}
+	githubClient := &fakeGitHubClient{}
+	c := New(helmClient, &fakeGitClient{}, githubClient, cfg)
+
+	update := &ChartUpdate{
+		Release:        &helm.Release{Name: "web", Chart: "nginx"},
+		CurrentVersion: "1.0.0",
+		LatestVersion:  "2.0.0",
+		Change:         VersionMajor,
+		Reasons:        []UpdateReason{ReasonNewVersion},
+	}
+	if err := c.processUpdate(context.Background(), chartRepo(t, update.Release.Chart), nil, update); err != nil {
+		t.Fatalf("processUpdate failed: %v", err)
+	}
+
+	if !update.HasReason(ReasonRemovedValues) {
+		t.Errorf("Expected removed values reason to be added, got %v", update.Reasons)
+	}
+
+	body := githubClient.created[0].GetBody()
+	for _, want := range []string{
+		"- Values set on the release no longer exist in the new version",
+		"**⚠️ Removed values:**",
+		"- `image.pullPolicy`",
+		"- `legacyMode`",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected PR body to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestReleaseDetailsSection(t *testing.T) {
+	target := &helm.ChartVersion{
+		Version:    "19.0.0",
+		AppVersion: "7.2.4",
+		URL:        "https://charts.example.com/redis-19.0.0.tgz",
+		Digest:     "3b1c0e6f",
+		Created:    time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+		Home:       "https://redis.io",
+		Sources:    []string{"https://github.com/example/charts", "https://github.com/redis/redis"},
+	}
+	helmClient := &fakeHelmClient{
+		releases: []*helm.Release{{Name: "cache", Chart: "redis", Version: "18.0.0"}},
+		latest:   map[string]*helm.ChartVersion{"redis": target},
+	}
+	c := New(helmClient, nil, nil, &config.Config{})
+
+	updates, err := c.checkForUpdates(context.Background(), helmClient.releases)
+	if err != nil {
+		t.Fatalf("checkForUpdates failed: %v", err)
+	}
+	if len(updates) != 1 || updates[0].Target != target {
+		t.Fatalf("Expected the index entry to be kept on the update, got %+v", updates)
+	}
+
+	section := releaseDetailsSection(updates[0])
+	for _, want := range []string{
+		"**Release details:**",
+		"- App version: 7.2.4",
+		"- Published: 2024-03-01 10:30 UTC",
+		"- Digest: `3b1c0e6f`",
+		"- Download: https://charts.example.com/redis-19.0.0.tgz",
+		"- Home: https://redis.io",
+		"- Sources: https://github.com/example/charts, https://github.com/redis/redis",
+	} {
+		if !strings.Contains(section, want) {
+			t.Errorf("Expected section to contain %q, got:\n%s", want, section)
+		}
+	}
+
+	if section := releaseDetailsSection(&ChartUpdate{Target: &helm.ChartVersion{Version: "1.0.0"}}); section != "" {
+		t.Errorf("Expected no section without metadata, got %q", section)
+	}
+	if section := releaseDetailsSection(&ChartUpdate{}); section != "" {
+		t.Errorf("Expected no section without an index entry, got %q", section)
+	}
+}
@@ -0,0 +1,71 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/marccoxall/helmchecker/internal/helm"
+)
+
+// ChartError is a failure to check or update a single chart
+type ChartError struct {
+	Chart string
+	Err   error
+}
+
+// Error implements the error interface
+func (e ChartError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Chart, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e ChartError) Unwrap() error {
+	return e.Err
+}
+
+// RunResult summarizes the outcome of a checker run
+type RunResult struct {
+	// Checked is the number of charts whose latest version was resolved
+	Checked int
+	// UpdatesFound is the number of charts with a newer version available
+	UpdatesFound int
+	// PRsOpened is the number of pull requests created
+	PRsOpened int
+	// Skipped is the number of charts or updates deliberately not acted on,
+	// e.g. excluded, in cooldown, deferred or with an existing pull request
+	Skipped int
+	// Updates lists the updates found, with the pull request opened for each if any
+	Updates []*ChartUpdate
+	// Failed lists the charts that could not be checked or updated
+	Failed []ChartError
+	// Blocked lists updates that were found but refused, such as downgrades
+	Blocked []*ChartUpdate
+	// Drifted lists charts republished with a new appVersion under the
+	// installed chart version
+	Drifted []AppVersionDrift
+	// Warnings lists run-wide problems that aren't tied to a single chart,
+	// such as having no Helm repositories configured
+	Warnings []string
+}
+
+// AppVersionDrift is an installed chart version whose repository entry now
+// reports a different appVersion than the release was installed with
+type AppVersionDrift struct {
+	Release *helm.Release
+	// Version is the chart version shared by the release and the index entry
+	Version string
+	// InstalledAppVersion is the appVersion of the installed chart
+	InstalledAppVersion string
+	// IndexAppVersion is the appVersion the repository now lists
+	IndexAppVersion string
+}
+
+// Summary returns a one-line description of the run
+func (r *RunResult) Summary() string {
+	return fmt.Sprintf("checked %d chart(s), found %d update(s), opened %d pull request(s), skipped %d, failed %d",
+		r.Checked, r.UpdatesFound, r.PRsOpened, r.Skipped, len(r.Failed))
+}
+
+// fail records a chart failure
+func (r *RunResult) fail(chart string, err error) {
+	r.Failed = append(r.Failed, ChartError{Chart: chart, Err: err})
+}
@@ -0,0 +1,90 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gh "github.com/google/go-github/v56/github"
+	"github.com/marccoxall/helmchecker/internal/config"
+	"github.com/marccoxall/helmchecker/internal/helm"
+)
+
+func TestRunResult(t *testing.T) {
+	cfg := &config.Config{
+		Checker: config.CheckerConfig{
+			ExcludeCharts:    []string{"legacy"},
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+		},
+	}
+	helmClient := &fakeHelmClient{
+		releases: []*helm.Release{
+			{Name: "web", Chart: "nginx", Version: "1.0.0"},
+			{Name: "cache", Chart: "redis", Version: "2.0.0"},
+			{Name: "db", Chart: "postgres", Version: "3.0.0"},
+			{Name: "queue", Chart: "kafka", Version: "4.0.0"},
+			{Name: "old", Chart: "legacy", Version: "0.1.0"},
+			{Name: "missing", Chart: "mysql", Version: "5.0.0"},
+			{Name: "odd", Chart: "custom", Version: "not-a-version"},
+		},
+		latest: map[string]*helm.ChartVersion{
+			"nginx":    {Version: "1.1.0"},
+			"redis":    {Version: "2.1.0"},
+			"postgres": {Version: "3.0.0"},
+			"kafka":    {Version: "4.1.0"},
+			"legacy":   {Version: "0.2.0"},
+			"custom":   {Version: "1.0.0"},
+		},
+	}
+	githubClient := &fakeGitHubClient{
+		existing:   map[string]*gh.PullRequest{"update-redis-2.1.0": {HTMLURL: gh.String("https://github.com/o/r/pull/3")}},
+		createErrs: map[string]error{"update-kafka-4.1.0": errors.New("validation failed")},
+	}
+	c := New(helmClient, &fakeGitClient{repoPath: chartRepo(t, "nginx", "redis", "kafka", "legacy")}, githubClient, cfg)
+
+	result, err := c.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if result.Checked != 6 {
+		t.Errorf("Expected 6 charts checked, got %d", result.Checked)
+	}
+	if result.UpdatesFound != 3 {
+		t.Errorf("Expected 3 updates found, got %d", result.UpdatesFound)
+	}
+	if result.PRsOpened != 1 {
+		t.Errorf("Expected 1 PR opened, got %d", result.PRsOpened)
+	}
+	opened := make(map[string]string)
+	for _, update := range result.Updates {
+		opened[update.Release.Chart] = update.PullRequestURL
+	}
+	if len(result.Updates) != 3 || opened["nginx"] == "" || opened["kafka"] != "" {
+		t.Errorf("Expected 3 updates with a PR URL for nginx only, got %v", opened)
+	}
+
+	// legacy is excluded and redis already has a PR
+	if result.Skipped != 2 {
+		t.Errorf("Expected 2 skipped, got %d", result.Skipped)
+	}
+
+	failed := make(map[string]error)
+	for _, failure := range result.Failed {
+		failed[failure.Chart] = failure.Err
+	}
+	if len(failed) != 3 || failed["mysql"] == nil || failed["custom"] == nil || failed["kafka"] == nil {
+		t.Fatalf("Expected mysql, custom and kafka to fail, got %v", result.Failed)
+	}
+	var invalid *ErrInvalidVersion
+	if !errors.As(failed["custom"], &invalid) {
+		t.Errorf("Expected custom to fail with ErrInvalidVersion, got %v", failed["custom"])
+	}
+
+	expected := "checked 6 chart(s), found 3 update(s), opened 1 pull request(s), skipped 2, failed 3"
+	if summary := result.Summary(); summary != expected {
+		t.Errorf("Expected summary %q, got %q", expected, summary)
+	}
+}
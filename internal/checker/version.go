@@ -0,0 +1,144 @@
+package checker
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// ErrInvalidVersion is returned when a chart version cannot be parsed as a
+// semantic version and so cannot be compared
+type ErrInvalidVersion struct {
+	Chart   string
+	Version string
+	Err     error
+}
+
+// Error implements the error interface
+func (e *ErrInvalidVersion) Error() string {
+	return fmt.Sprintf("chart %s has invalid version %q: %v", e.Chart, e.Version, e.Err)
+}
+
+// Unwrap returns the underlying parse error
+func (e *ErrInvalidVersion) Unwrap() error {
+	return e.Err
+}
+
+// parseVersion parses a chart version as a semantic version. Partial versions
+// are normalized, so 1.0, 1.0.0 and v1.0.0 parse to the same version.
+func parseVersion(chart, version string) (*semver.Version, error) {
+	v, err := semver.NewVersion(strings.TrimSpace(version))
+	if err != nil {
+		return nil, &ErrInvalidVersion{Chart: chart, Version: version, Err: err}
+	}
+	return v, nil
+}
+
+// VersionChange classifies the change from a chart's current version to the
+// latest one
+type VersionChange int
+
+// Version changes, from no change to a downgrade
+const (
+	VersionUnchanged VersionChange = iota
+	VersionPatch
+	VersionMinor
+	VersionMajor
+	VersionDowngrade
+	// VersionNewer is an upgrade in a version scheme without patch, minor and
+	// major levels, such as date-based versions
+	VersionNewer
+)
+
+// String returns the name of the version change
+func (v VersionChange) String() string {
+	switch v {
+	case VersionUnchanged:
+		return "unchanged"
+	case VersionPatch:
+		return "patch"
+	case VersionMinor:
+		return "minor"
+	case VersionMajor:
+		return "major"
+	case VersionDowngrade:
+		return "downgrade"
+	case VersionNewer:
+		return "newer"
+	default:
+		return fmt.Sprintf("VersionChange(%d)", int(v))
+	}
+}
+
+// classifyVersionChange classifies the change from current to latest using the
+// chart's version scheme, returning an *ErrInvalidVersion if either is not
+// valid in it. A latest version lower than current, e.g. after a repository
+// re-index or a yanked release, is a VersionDowngrade.
+func (c *Checker) classifyVersionChange(chart, latest, current string) (VersionChange, error) {
+	comparator := c.comparatorFor(chart)
+	if _, ok := comparator.(SemverComparator); !ok {
+		for _, version := range []string{latest, current} {
+			if err := comparator.Validate(version); err != nil {
+				return VersionUnchanged, &ErrInvalidVersion{Chart: chart, Version: version, Err: err}
+			}
+		}
+
+		switch cmp := comparator.Compare(latest, current); {
+		case cmp < 0:
+			return VersionDowngrade, nil
+		case cmp == 0:
+			return VersionUnchanged, nil
+		default:
+			return VersionNewer, nil
+		}
+	}
+
+	latestVersion, err := parseVersion(chart, latest)
+	if err != nil {
+		return VersionUnchanged, err
+	}
+
+	currentVersion, err := parseVersion(chart, current)
+	if err != nil {
+		return VersionUnchanged, err
+	}
+
+	switch {
+	case latestVersion.LessThan(currentVersion):
+		return VersionDowngrade, nil
+	case !latestVersion.GreaterThan(currentVersion):
+		return VersionUnchanged, nil
+	case latestVersion.Major() != currentVersion.Major():
+		return VersionMajor, nil
+	case latestVersion.Minor() != currentVersion.Minor():
+		return VersionMinor, nil
+	default:
+		return VersionPatch, nil
+	}
+}
+
+// sameVersion reports whether two versions are equal once normalized, falling
+// back to comparing the raw strings when either cannot be parsed
+func sameVersion(a, b string) bool {
+	va, errA := semver.NewVersion(strings.TrimSpace(a))
+	vb, errB := semver.NewVersion(strings.TrimSpace(b))
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return va.Equal(vb)
+}
+
+// reportInvalidVersions summarises the unparseable versions met during a run
+// so they can be fixed
+func (c *Checker) reportInvalidVersions() {
+	if len(c.invalidVersions) == 0 {
+		return
+	}
+
+	log.Printf("Warning: %d chart version(s) could not be compared because they are not valid in their version scheme:", len(c.invalidVersions))
+	for _, invalid := range c.invalidVersions {
+		log.Printf("  - %s: %q", invalid.Chart, invalid.Version)
+	}
+}
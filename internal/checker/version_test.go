@@ -0,0 +1,221 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/marccoxall/helmchecker/internal/config"
+	"github.com/marccoxall/helmchecker/internal/helm"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func TestEquivalentVersionsAreNotNewer(t *testing.T) {
+	c := New(nil, nil, nil, &config.Config{})
+
+	tests := []struct {
+		latest  string
+		current string
+	}{
+		{"1.0", "1.0.0"},
+		{"1.0.0", "1.0"},
+		{"v1.0.0", "1.0.0"},
+		{"1.0.0", "v1.0"},
+		{"1", "1.0.0"},
+		{"1.0.0+build.5", "1.0.0"},
+		{" 1.0.0", "1.0.0"},
+	}
+
+	for _, tt := range tests {
+		change, err := c.classifyVersionChange("chart", tt.latest, tt.current)
+		if err != nil {
+			t.Errorf("classifyVersionChange(%q, %q): unexpected error: %v", tt.latest, tt.current, err)
+			continue
+		}
+		if change != VersionUnchanged {
+			t.Errorf("classifyVersionChange(%q, %q) = %s, expected %s", tt.latest, tt.current, change, VersionUnchanged)
+		}
+		if !sameVersion(tt.latest, tt.current) {
+			t.Errorf("sameVersion(%q, %q) = false, expected true", tt.latest, tt.current)
+		}
+	}
+
+	if sameVersion("1.0.1", "1.0") {
+		t.Errorf("Expected 1.0.1 and 1.0 to differ")
+	}
+	if !sameVersion("latest-build", "latest-build") {
+		t.Errorf("Expected identical unparseable versions to be equal")
+	}
+}
+
+func TestPrereleaseFallsBackToStable(t *testing.T) {
+	releases := []*helm.Release{
+		{Name: "app", Chart: "app", Version: "1.0.0"},
+		{Name: "db", Chart: "db", Version: "1.0.0"},
+		{Name: "web", Chart: "web", Version: "1.0.0"},
+	}
+	version := func(name, v string, annotations map[string]string) *repo.ChartVersion {
+		return &repo.ChartVersion{Metadata: &chart.Metadata{Name: name, Version: v, Annotations: annotations}}
+	}
+	index := repo.NewIndexFile()
+	for _, name := range []string{"app", "db"} {
+		index.Entries[name] = repo.ChartVersions{
+			version(name, "1.0.0", nil),
+			version(name, "1.0.1", nil),
+			version(name, "1.1.0-rc.1", nil),
+		}
+	}
+	// web's newest version is only published to the edge channel
+	index.Entries["web"] = repo.ChartVersions{
+		version("web", "1.0.0", nil),
+		version("web", "1.0.1", nil),
+		version("web", "1.1.0", map[string]string{helm.ChannelAnnotation: "edge"}),
+	}
+	helmClient := &fakeHelmClient{releases: releases, index: index}
+
+	c := New(helmClient, nil, nil, &config.Config{Checker: config.CheckerConfig{
+		ChartChannels: map[string]string{"db": config.ChannelEdge},
+	}})
+	updates, err := c.checkForUpdates(context.Background(), releases)
+	if err != nil {
+		t.Fatalf("checkForUpdates failed: %v", err)
+	}
+
+	// Stable charts are offered the previous stable version, the edge chart the pre-release
+	expected := map[string]string{"app": "1.0.1", "db": "1.1.0-rc.1", "web": "1.0.1"}
+	if len(updates) != len(expected) {
+		t.Fatalf("Expected %d updates, got %d", len(expected), len(updates))
+	}
+	for _, update := range updates {
+		if update.LatestVersion != expected[update.Release.Chart] {
+			t.Errorf("Expected %s to be updated to %s, got %s", update.Release.Chart, expected[update.Release.Chart], update.LatestVersion)
+		}
+	}
+	if c.result.Skipped != 0 {
+		t.Errorf("Expected no chart to be skipped, got %d skipped", c.result.Skipped)
+	}
+
+	// Allowing pre-releases opts every chart in
+	c = New(helmClient, nil, nil, &config.Config{Checker: config.CheckerConfig{AllowPrerelease: true}})
+	updates, err = c.checkForUpdates(context.Background(), releases)
+	if err != nil {
+		t.Fatalf("checkForUpdates failed: %v", err)
+	}
+	for _, update := range updates {
+		if update.LatestVersion == "1.0.1" {
+			t.Errorf("Expected %s to be offered its newest version, got %s", update.Release.Chart, update.LatestVersion)
+		}
+	}
+}
+
+func TestClassifyVersionChangeInvalid(t *testing.T) {
+	c := New(nil, nil, nil, &config.Config{})
+
+	_, err := c.classifyVersionChange("nginx", "1.2.3", "latest-build")
+
+	var invalid *ErrInvalidVersion
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Expected *ErrInvalidVersion, got %v", err)
+	}
+	if invalid.Chart != "nginx" || invalid.Version != "latest-build" {
+		t.Errorf("Unexpected invalid version error: %+v", invalid)
+	}
+}
+
+func TestRunContinuesPastInvalidVersions(t *testing.T) {
+	logs := captureLog(t)
+
+	helmClient := &fakeHelmClient{
+		releases: []*helm.Release{
+			{Name: "legacy", Namespace: "apps", Chart: "legacy", Version: "latest-build"},
+			{Name: "web", Namespace: "apps", Chart: "nginx", Version: "1.0.0"},
+		},
+		latest: map[string]*helm.ChartVersion{
+			"legacy": {Version: "1.0.0"},
+			"nginx":  {Version: "1.1.0"},
+		},
+	}
+	c := New(helmClient, nil, nil, &config.Config{Checker: config.CheckerConfig{DryRun: true}})
+
+	if _, err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(c.invalidVersions) != 1 || c.invalidVersions[0].Chart != "legacy" {
+		t.Errorf("Expected one invalid version for legacy, got %+v", c.invalidVersions)
+	}
+	if !strings.Contains(logs.String(), "DRY RUN: Would update nginx from 1.0.0 to 1.1.0") {
+		t.Errorf("Expected valid chart to still be processed, got:\n%s", logs.String())
+	}
+	if !strings.Contains(logs.String(), `legacy: "latest-build"`) {
+		t.Errorf("Expected invalid versions to be reported at the end of the run, got:\n%s", logs.String())
+	}
+}
+
+func TestClassifyVersionChange(t *testing.T) {
+	c := New(nil, nil, nil, &config.Config{})
+
+	tests := []struct {
+		latest  string
+		current string
+		change  VersionChange
+	}{
+		{"1.0.0", "1.0.0", VersionUnchanged},
+		{"1.0.1", "1.0.0", VersionPatch},
+		{"1.0.0", "1.0.0-rc.1", VersionPatch},
+		{"1.2.0", "1.1.9", VersionMinor},
+		{"2.0.0", "1.9.9", VersionMajor},
+		{"1.0.0", "1.1.0", VersionDowngrade},
+		{"0.9.0", "1.0.0", VersionDowngrade},
+		{"1.10.0", "1.9.0", VersionMinor},
+		{"v2.0.0", "1.9.9", VersionMajor},
+		{"1.0.0-rc.1", "1.0.0", VersionDowngrade},
+		{"1.0.0-rc.2", "1.0.0-rc.1", VersionPatch},
+		{"1.0.0+build.2", "1.0.0+build.1", VersionUnchanged},
+	}
+
+	for _, tt := range tests {
+		change, err := c.classifyVersionChange("chart", tt.latest, tt.current)
+		if err != nil {
+			t.Errorf("classifyVersionChange(%q, %q): unexpected error: %v", tt.latest, tt.current, err)
+			continue
+		}
+		if change != tt.change {
+			t.Errorf("classifyVersionChange(%q, %q) = %s, expected %s", tt.latest, tt.current, change, tt.change)
+		}
+	}
+}
+
+func TestRunRefusesDowngrade(t *testing.T) {
+	logs := captureLog(t)
+
+	helmClient := &fakeHelmClient{
+		releases: []*helm.Release{
+			{Name: "web", Namespace: "apps", Chart: "nginx", Version: "1.4.0"},
+			{Name: "cache", Namespace: "apps", Chart: "redis", Version: "2.0.0"},
+		},
+		latest: map[string]*helm.ChartVersion{
+			"nginx": {Version: "1.3.2"},
+			"redis": {Version: "2.0.0"},
+		},
+	}
+	githubClient := &fakeGitHubClient{}
+	c := New(helmClient, &fakeGitClient{}, githubClient, &config.Config{})
+
+	result, err := c.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(githubClient.created) != 0 || result.UpdatesFound != 0 {
+		t.Errorf("Expected no downgrade PR, got %d PRs and %d updates", len(githubClient.created), result.UpdatesFound)
+	}
+	if !strings.Contains(logs.String(), "DOWNGRADE: refusing to update nginx from 1.4.0 to lower version 1.3.2") {
+		t.Errorf("Expected downgrade-specific log, got:\n%s", logs.String())
+	}
+	if strings.Contains(logs.String(), "DOWNGRADE: refusing to update redis") {
+		t.Errorf("Expected an unchanged version not to be reported as a downgrade")
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ChartList is a curated list of the charts to manage, kept in a file (for
+// example under version control) so it can change without redeploying
+type ChartList struct {
+	IncludeCharts []string `json:"includeCharts"`
+	ExcludeCharts []string `json:"excludeCharts"`
+	// Policies sets the update policy per chart: update, skip or dry-run
+	Policies map[string]string `json:"policies"`
+}
+
+// LoadChartList reads and validates a YAML or JSON chart list file
+func LoadChartList(path string) (*ChartList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chart list %s: %w", path, err)
+	}
+
+	var list ChartList
+	if err := yaml.UnmarshalStrict(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse chart list %s: %w", path, err)
+	}
+
+	if err := list.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid chart list %s: %w", path, err)
+	}
+
+	return &list, nil
+}
+
+// Validate checks that chart names are set and policies are known
+func (l *ChartList) Validate() error {
+	var errors []string
+
+	for _, chartName := range append(append([]string(nil), l.IncludeCharts...), l.ExcludeCharts...) {
+		if strings.TrimSpace(chartName) == "" {
+			errors = append(errors, "chart names must not be empty")
+			break
+		}
+	}
+
+	chartNames := make([]string, 0, len(l.Policies))
+	for chartName := range l.Policies {
+		chartNames = append(chartNames, chartName)
+	}
+	sort.Strings(chartNames)
+	for _, chartName := range chartNames {
+		switch policy := l.Policies[chartName]; policy {
+		case PolicyUpdate, PolicySkip, PolicyDryRun:
+		default:
+			errors = append(errors, fmt.Sprintf("chart %s has unknown policy %q", chartName, policy))
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadChartList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "charts.yaml")
+	content := "includeCharts:\n  - nginx\n  - redis\nexcludeCharts:\n  - legacy\npolicies:\n  redis: dry-run\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write chart list: %v", err)
+	}
+
+	list, err := LoadChartList(path)
+	if err != nil {
+		t.Fatalf("LoadChartList failed: %v", err)
+	}
+
+	if strings.Join(list.IncludeCharts, ",") != "nginx,redis" {
+		t.Errorf("Unexpected include charts %v", list.IncludeCharts)
+	}
+	if strings.Join(list.ExcludeCharts, ",") != "legacy" {
+		t.Errorf("Unexpected exclude charts %v", list.ExcludeCharts)
+	}
+	if list.Policies["redis"] != PolicyDryRun {
+		t.Errorf("Expected redis policy %s, got %q", PolicyDryRun, list.Policies["redis"])
+	}
+}
+
+func TestLoadChartListInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown policy": "policies:\n  nginx: sometimes\n",
+		"unknown field":  "includeChart:\n  - nginx\n",
+		"empty name":     "excludeCharts:\n  - \"\"\n",
+		"malformed":      "includeCharts: [nginx\n",
+	}
+
+	for name, content := range tests {
+		path := filepath.Join(t.TempDir(), "charts.yaml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write chart list: %v", err)
+		}
+		if _, err := LoadChartList(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if _, err := LoadChartList(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("Expected error for a missing chart list")
+	}
+}
@@ -1,15 +1,25 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"text/template"
+	"time"
+
+	"github.com/marccoxall/helmchecker/internal/secrets"
 )
 
 // Config represents the application configuration
 type Config struct {
 	Kubernetes KubernetesConfig `yaml:"kubernetes"`
+	Helm       HelmConfig       `yaml:"helm"`
 	Git        GitConfig        `yaml:"git"`
 	GitHub     GitHubConfig     `yaml:"github"`
 	Checker    CheckerConfig    `yaml:"checker"`
@@ -20,6 +30,36 @@ type KubernetesConfig struct {
 	Namespace string `yaml:"namespace"`
 }
 
+// HelmConfig holds Helm-related configuration
+type HelmConfig struct {
+	RepositoryTLS    []RepositoryTLS      `yaml:"repositoryTLS"`
+	IndexConcurrency int                  `yaml:"indexConcurrency"`
+	Registries       []RegistryCredential `yaml:"registries"`
+	DiffContext      int                  `yaml:"diffContext"`
+}
+
+// RegistryCredential holds explicit credentials for an OCI registry. Registries
+// without one use the Helm registry config and Docker config logins.
+type RegistryCredential struct {
+	// Host is the registry host, e.g. ghcr.io
+	Host     string `yaml:"host" json:"host"`
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+	// PlainHTTP talks to the registry over HTTP instead of HTTPS
+	PlainHTTP bool `yaml:"plainHTTP" json:"plainHTTP"`
+}
+
+// RepositoryTLS holds the TLS settings used to reach a chart repository, such
+// as a private CA or a client certificate for repositories requiring mTLS
+type RepositoryTLS struct {
+	// Repository is a repository name from repositories.yaml or a URL prefix
+	Repository            string `yaml:"repository" json:"repository"`
+	CAFile                string `yaml:"caFile" json:"caFile"`
+	CertFile              string `yaml:"certFile" json:"certFile"`
+	KeyFile               string `yaml:"keyFile" json:"keyFile"`
+	InsecureSkipTLSVerify bool   `yaml:"insecureSkipTLSVerify" json:"insecureSkipTLSVerify"`
+}
+
 // GitConfig holds Git-related configuration
 type GitConfig struct {
 	Repository string `yaml:"repository"`
@@ -27,6 +67,11 @@ type GitConfig struct {
 	Username   string `yaml:"username"`
 	Email      string `yaml:"email"`
 	Branch     string `yaml:"branch"`
+
+	MaxConcurrentClones  int    `yaml:"maxConcurrentClones"`
+	CloneDiskBudgetMB    int    `yaml:"cloneDiskBudgetMB"`
+	TempDir              string `yaml:"tempDir"`
+	RetainCloneOnFailure bool   `yaml:"retainCloneOnFailure"`
 }
 
 // GitHubConfig holds GitHub-related configuration
@@ -38,27 +83,164 @@ type GitHubConfig struct {
 
 // CheckerConfig holds checker-related configuration
 type CheckerConfig struct {
-	DryRun           bool     `yaml:"dryRun"`
-	ExcludeCharts    []string `yaml:"excludeCharts"`
-	IncludeCharts    []string `yaml:"includeCharts"`
-	CheckPrerelease  bool     `yaml:"checkPrerelease"`
-	CommitMessage    string   `yaml:"commitMessage"`
-	PullRequestTitle string   `yaml:"pullRequestTitle"`
-	PullRequestBody  string   `yaml:"pullRequestBody"`
+	DryRun                   bool              `yaml:"dryRun"`
+	ExcludeCharts            []string          `yaml:"excludeCharts"`
+	IncludeCharts            []string          `yaml:"includeCharts"`
+	ExcludeNamespaces        []string          `yaml:"excludeNamespaces"`
+	IncludeNamespaces        []string          `yaml:"includeNamespaces"`
+	CheckPrerelease          bool              `yaml:"checkPrerelease"`
+	AllowPrerelease          bool              `yaml:"allowPrerelease"`
+	CommitMessage            string            `yaml:"commitMessage"`
+	GroupCommitMessage       string            `yaml:"groupCommitMessage"`
+	BranchTemplate           string            `yaml:"branchTemplate"`
+	PullRequestTitle         string            `yaml:"pullRequestTitle"`
+	PullRequestBody          string            `yaml:"pullRequestBody"`
+	DirectoryRules           []DirectoryRule   `yaml:"directoryRules"`
+	ExcludeVersionPatterns   []string          `yaml:"excludeVersionPatterns"`
+	StartupSplay             time.Duration     `yaml:"startupSplay"`
+	TrustedSourceHosts       []string          `yaml:"trustedSourceHosts"`
+	Policy                   PolicyConfig      `yaml:"policy"`
+	MaintenanceWindow        MaintenanceWindow `yaml:"maintenanceWindow"`
+	BumpCooldown             time.Duration     `yaml:"bumpCooldown"`
+	StateFile                string            `yaml:"stateFile"`
+	StateDir                 string            `yaml:"stateDir"`
+	StateBackend             string            `yaml:"stateBackend"`
+	RedisURL                 string            `yaml:"redisURL"`
+	ResolveConcurrency       int               `yaml:"resolveConcurrency"`
+	ShutdownGracePeriod      time.Duration     `yaml:"shutdownGracePeriod"`
+	PRDeduplication          string            `yaml:"prDeduplication"`
+	PrefetchOpenPRs          bool              `yaml:"prefetchOpenPRs"`
+	BatchUpdates             bool              `yaml:"batchUpdates"`
+	PostUpdateCommands       []string          `yaml:"postUpdateCommands"`
+	NegativeCacheTTL         time.Duration     `yaml:"negativeCacheTTL"`
+	PostUpdateWebhooks       []string          `yaml:"postUpdateWebhooks"`
+	ChartChannels            map[string]string `yaml:"chartChannels"`
+	RequestMaintainerReviews bool              `yaml:"requestMaintainerReviews"`
+	MaintainerReviewers      map[string]string `yaml:"maintainerReviewers"`
+	ManifestDiffReview       bool              `yaml:"manifestDiffReview"`
+	ChartListFile            string            `yaml:"chartListFile"`
+	ManualMajorCharts        map[string]string `yaml:"manualMajorCharts"`
+	VersionSchemes           map[string]string `yaml:"versionSchemes"`
+	PinnedVersions           map[string]string `yaml:"pinnedVersions"`
+	ReportDir                string            `yaml:"reportDir"`
+}
+
+// PolicyConfig configures Rego policy evaluation against rendered upgrades
+type PolicyConfig struct {
+	Files     []string `yaml:"files"`
+	Query     string   `yaml:"query"`
+	Mode      string   `yaml:"mode"`
+	OPABinary string   `yaml:"opaBinary"`
+}
+
+// Policy evaluation modes
+const (
+	// PolicyModeBlock skips upgrades that violate a policy
+	PolicyModeBlock = "block"
+	// PolicyModeWarn opens the pull request and lists the violations in it
+	PolicyModeWarn = "warn"
+)
+
+// Pull request deduplication strategies
+const (
+	// DedupBranch matches existing pull requests by head branch (the default)
+	DedupBranch = "branch"
+	// DedupLabel matches existing pull requests by a chart-identifying label
+	DedupLabel = "label"
+	// DedupBoth matches existing pull requests by head branch or label
+	DedupBoth = "both"
+)
+
+// Backends persisting run state and caches between runs
+const (
+	// StateBackendFile keeps state on disk in CHECKER_STATE_DIR (the default)
+	StateBackendFile = "file"
+	// StateBackendRedis keeps state in Redis at CHECKER_REDIS_URL
+	StateBackendRedis = "redis"
+)
+
+// Release channels a chart can be subscribed to
+const (
+	// ChannelStable follows stable releases only
+	ChannelStable = "stable"
+	// ChannelEdge follows every release, including pre-releases
+	ChannelEdge = "edge"
+)
+
+// Version schemes used to compare chart versions
+const (
+	// VersionSchemeSemver compares semantic versions (the default)
+	VersionSchemeSemver = "semver"
+	// VersionSchemeDate compares date-based versions such as 2024.01.02
+	VersionSchemeDate = "date"
+	// VersionSchemeInteger compares monotonically increasing integer versions
+	VersionSchemeInteger = "integer"
+)
+
+// ChannelFor returns the release channel a chart is subscribed to. Charts
+// without an explicit channel follow edge when pre-releases are allowed or
+// checked and stable otherwise.
+func (c *CheckerConfig) ChannelFor(chartName string) string {
+	if channel, ok := c.ChartChannels[chartName]; ok {
+		return channel
+	}
+	if c.AllowPrerelease || c.CheckPrerelease {
+		return ChannelEdge
+	}
+	return ChannelStable
+}
+
+// StateDirectory returns the directory the file state backend keeps its
+// entries in: StateDir if set, otherwise a directory named after the state
+// file, so the store never shares a directory with unrelated files
+func (c *CheckerConfig) StateDirectory() string {
+	if c.StateDir != "" {
+		return c.StateDir
+	}
+	return c.StateFile + ".d"
 }
 
+// DirectoryRule scopes update handling to charts under a path prefix, allowing
+// monorepos to route different directories to different branches and reviewers
+type DirectoryRule struct {
+	PathPrefix   string   `yaml:"pathPrefix" json:"pathPrefix"`
+	TargetBranch string   `yaml:"targetBranch" json:"targetBranch"`
+	Reviewers    []string `yaml:"reviewers" json:"reviewers"`
+	Policy       string   `yaml:"policy" json:"policy"`
+}
+
+// Directory rule policies
+const (
+	// PolicyUpdate opens a pull request for the update (the default)
+	PolicyUpdate = "update"
+	// PolicySkip ignores updates for charts under the directory
+	PolicySkip = "skip"
+	// PolicyDryRun only logs updates for charts under the directory
+	PolicyDryRun = "dry-run"
+)
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	cfg := &Config{
 		Kubernetes: KubernetesConfig{
 			Namespace: getEnvOrDefault("KUBERNETES_NAMESPACE", ""),
 		},
+		Helm: HelmConfig{
+			IndexConcurrency: getIntEnvOrDefault("HELM_INDEX_CONCURRENCY", 0),
+			DiffContext:      getIntEnvOrDefault("HELM_DIFF_CONTEXT", 3),
+		},
 		Git: GitConfig{
 			Repository: getEnvOrDefault("GIT_REPOSITORY", ""),
 			Token:      getEnvOrDefault("GIT_TOKEN", ""),
 			Username:   getEnvOrDefault("GIT_USERNAME", "helmchecker"),
 			Email:      getEnvOrDefault("GIT_EMAIL", "helmchecker@example.com"),
 			Branch:     getEnvOrDefault("GIT_BRANCH", "main"),
+
+			MaxConcurrentClones: getIntEnvOrDefault("GIT_MAX_CONCURRENT_CLONES", 0),
+			CloneDiskBudgetMB:   getIntEnvOrDefault("GIT_CLONE_DISK_BUDGET_MB", 0),
+
+			TempDir:              getEnvOrDefault("GIT_TEMP_DIR", ""),
+			RetainCloneOnFailure: getBoolEnvOrDefault("GIT_RETAIN_CLONE_ON_FAILURE", false),
 		},
 		GitHub: GitHubConfig{
 			Token: getEnvOrDefault("GITHUB_TOKEN", ""),
@@ -66,14 +248,90 @@ func Load() (*Config, error) {
 			Repo:  getEnvOrDefault("GITHUB_REPO", ""),
 		},
 		Checker: CheckerConfig{
-			DryRun:           getBoolEnvOrDefault("CHECKER_DRY_RUN", false),
-			CheckPrerelease:  getBoolEnvOrDefault("CHECKER_CHECK_PRERELEASE", false),
-			CommitMessage:    getEnvOrDefault("CHECKER_COMMIT_MESSAGE", "chore: update helm chart %s to version %s"),
-			PullRequestTitle: getEnvOrDefault("CHECKER_PR_TITLE", "Update Helm chart %s to version %s"),
-			PullRequestBody:  getEnvOrDefault("CHECKER_PR_BODY", "This PR updates the Helm chart %s from version %s to %s.\n\n**Changes:**\n- Updated chart version\n- Updated application version (if applicable)\n\n**Testing:**\n- [ ] Chart linting passed\n- [ ] Deployment tested in staging\n\nGenerated by helmchecker 🤖"),
+			DryRun:                   getBoolEnvOrDefault("CHECKER_DRY_RUN", false),
+			CheckPrerelease:          getBoolEnvOrDefault("CHECKER_CHECK_PRERELEASE", false),
+			AllowPrerelease:          getBoolEnvOrDefault("CHECKER_ALLOW_PRERELEASE", false),
+			ExcludeNamespaces:        getListEnvOrDefault("CHECKER_EXCLUDE_NAMESPACES", nil),
+			IncludeNamespaces:        getListEnvOrDefault("CHECKER_INCLUDE_NAMESPACES", nil),
+			ExcludeVersionPatterns:   getListEnvOrDefault("CHECKER_EXCLUDE_VERSION_PATTERNS", nil),
+			StartupSplay:             getDurationEnvOrDefault("CHECKER_STARTUP_SPLAY", 0),
+			TrustedSourceHosts:       getListEnvOrDefault("CHECKER_TRUSTED_SOURCE_HOSTS", nil),
+			BumpCooldown:             getDurationEnvOrDefault("CHECKER_BUMP_COOLDOWN", 0),
+			ResolveConcurrency:       getIntEnvOrDefault("CHECKER_RESOLVE_CONCURRENCY", 4),
+			ShutdownGracePeriod:      getDurationEnvOrDefault("CHECKER_SHUTDOWN_GRACE_PERIOD", 30*time.Second),
+			PRDeduplication:          getEnvOrDefault("CHECKER_PR_DEDUPLICATION", DedupBranch),
+			PrefetchOpenPRs:          getBoolEnvOrDefault("CHECKER_PREFETCH_OPEN_PRS", false),
+			BatchUpdates:             getBoolEnvOrDefault("CHECKER_BATCH_UPDATES", false),
+			PostUpdateWebhooks:       getListEnvOrDefault("CHECKER_POST_UPDATE_WEBHOOKS", nil),
+			NegativeCacheTTL:         getDurationEnvOrDefault("CHECKER_NEGATIVE_CACHE_TTL", 0),
+			RequestMaintainerReviews: getBoolEnvOrDefault("CHECKER_REQUEST_MAINTAINER_REVIEWS", false),
+			ManifestDiffReview:       getBoolEnvOrDefault("CHECKER_MANIFEST_DIFF_REVIEW", false),
+			ChartListFile:            getEnvOrDefault("CHECKER_CHART_LIST_FILE", ""),
+			ReportDir:                getEnvOrDefault("CHECKER_REPORT_DIR", ""),
+			StateFile:                getEnvOrDefault("CHECKER_STATE_FILE", filepath.Join(os.TempDir(), "helmchecker", "state.json")),
+			StateDir:                 getEnvOrDefault("CHECKER_STATE_DIR", ""),
+			StateBackend:             getEnvOrDefault("CHECKER_STATE_BACKEND", StateBackendFile),
+			RedisURL:                 getEnvOrDefault("CHECKER_REDIS_URL", ""),
+			MaintenanceWindow: MaintenanceWindow{
+				Days:     getListEnvOrDefault("CHECKER_MAINTENANCE_DAYS", nil),
+				Hours:    getEnvOrDefault("CHECKER_MAINTENANCE_HOURS", ""),
+				Timezone: getEnvOrDefault("CHECKER_MAINTENANCE_TIMEZONE", ""),
+			},
+			Policy: PolicyConfig{
+				Files:     getListEnvOrDefault("CHECKER_POLICY_FILES", nil),
+				Query:     getEnvOrDefault("CHECKER_POLICY_QUERY", "data.helmchecker.deny"),
+				Mode:      getEnvOrDefault("CHECKER_POLICY_MODE", PolicyModeBlock),
+				OPABinary: getEnvOrDefault("CHECKER_OPA_BINARY", "opa"),
+			},
+			CommitMessage:      getEnvOrDefault("CHECKER_COMMIT_MESSAGE", "chore: update helm chart %s to version %s"),
+			GroupCommitMessage: getEnvOrDefault("CHECKER_GROUP_COMMIT_MESSAGE", "chore: update %s helm chart(s)"),
+			PullRequestTitle:   getEnvOrDefault("CHECKER_PR_TITLE", "Update Helm chart %s to version %s"),
+			BranchTemplate:     getEnvOrDefault("CHECKER_BRANCH_TEMPLATE", "update-{{.Chart}}-{{.Version}}"),
+			PullRequestBody:    getEnvOrDefault("CHECKER_PR_BODY", "This PR updates the Helm chart %s from version %s to %s.\n\n**Changes:**\n- Updated chart version\n- Updated application version (if applicable)\n\n**Testing:**\n- [ ] Chart linting passed\n- [ ] Deployment tested in staging\n\nGenerated by helmchecker 🤖"),
 		},
 	}
 
+	if err := getJSONEnv("CHECKER_DIRECTORY_RULES", &cfg.Checker.DirectoryRules); err != nil {
+		return nil, err
+	}
+
+	if err := getJSONEnv("CHECKER_POST_UPDATE_COMMANDS", &cfg.Checker.PostUpdateCommands); err != nil {
+		return nil, err
+	}
+
+	if err := getJSONEnv("CHECKER_CHART_CHANNELS", &cfg.Checker.ChartChannels); err != nil {
+		return nil, err
+	}
+
+	if err := getJSONEnv("CHECKER_MAINTAINER_REVIEWERS", &cfg.Checker.MaintainerReviewers); err != nil {
+		return nil, err
+	}
+
+	if err := getJSONEnv("CHECKER_MANUAL_MAJOR_CHARTS", &cfg.Checker.ManualMajorCharts); err != nil {
+		return nil, err
+	}
+
+	if err := getJSONEnv("CHECKER_VERSION_SCHEMES", &cfg.Checker.VersionSchemes); err != nil {
+		return nil, err
+	}
+
+	if err := getJSONEnv("CHECKER_PINNED_VERSIONS", &cfg.Checker.PinnedVersions); err != nil {
+		return nil, err
+	}
+
+	if err := getJSONEnv("HELM_REPOSITORY_TLS", &cfg.Helm.RepositoryTLS); err != nil {
+		return nil, err
+	}
+
+	if err := getJSONEnv("HELM_REGISTRY_CREDENTIALS", &cfg.Helm.Registries); err != nil {
+		return nil, err
+	}
+
+	// Resolve secret references such as env:NAME, file:/path or vault:path#key
+	if err := cfg.resolveSecrets(secrets.NewResolver()); err != nil {
+		return nil, err
+	}
+
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		return nil, err
@@ -82,6 +340,22 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// resolveSecrets replaces token references with the secrets they point to
+func (c *Config) resolveSecrets(resolver *secrets.Resolver) error {
+	tokens := []*string{&c.Git.Token, &c.GitHub.Token, &c.Checker.RedisURL}
+	for i := range c.Helm.Registries {
+		tokens = append(tokens, &c.Helm.Registries[i].Password)
+	}
+	for _, token := range tokens {
+		resolved, err := resolver.Resolve(*token)
+		if err != nil {
+			return err
+		}
+		*token = resolved
+	}
+	return nil
+}
+
 // Validate validates the configuration
 func (c *Config) Validate() error {
 	var errors []string
@@ -113,6 +387,137 @@ func (c *Config) Validate() error {
 		errors = append(errors, "GITHUB_REPO environment variable is required")
 	}
 
+	for i, rule := range c.Checker.DirectoryRules {
+		if rule.PathPrefix == "" {
+			errors = append(errors, fmt.Sprintf("directory rule %d: pathPrefix is required", i))
+		}
+		switch rule.Policy {
+		case "", PolicyUpdate, PolicySkip, PolicyDryRun:
+		default:
+			errors = append(errors, fmt.Sprintf("directory rule %d: unknown policy %q", i, rule.Policy))
+		}
+	}
+
+	for i, tls := range c.Helm.RepositoryTLS {
+		if tls.Repository == "" {
+			errors = append(errors, fmt.Sprintf("repository TLS entry %d: repository is required", i))
+		}
+		if (tls.CertFile == "") != (tls.KeyFile == "") {
+			errors = append(errors, fmt.Sprintf("repository TLS entry %d: certFile and keyFile must be set together", i))
+		}
+	}
+
+	for i, registry := range c.Helm.Registries {
+		if registry.Host == "" {
+			errors = append(errors, fmt.Sprintf("registry credential %d: host is required", i))
+		}
+		if (registry.Username == "") != (registry.Password == "") {
+			errors = append(errors, fmt.Sprintf("registry credential %d: username and password must be set together", i))
+		}
+	}
+
+	if c.Helm.DiffContext < 0 {
+		errors = append(errors, fmt.Sprintf("HELM_DIFF_CONTEXT must not be negative, got %d", c.Helm.DiffContext))
+	}
+
+	// Validate message templates against the arguments the checker passes them
+	type formatTemplate struct {
+		envVar string
+		format string
+		args   []string
+	}
+	templates := []formatTemplate{
+		{"CHECKER_COMMIT_MESSAGE", c.Checker.CommitMessage, []string{"chart", "version"}},
+		{"CHECKER_PR_TITLE", c.Checker.PullRequestTitle, []string{"chart", "version"}},
+		{"CHECKER_PR_BODY", c.Checker.PullRequestBody, []string{"chart", "current version", "new version"}},
+	}
+	if c.Checker.BatchUpdates {
+		// The group commit message is only used for grouped updates
+		templates = append(templates, formatTemplate{"CHECKER_GROUP_COMMIT_MESSAGE", c.Checker.GroupCommitMessage, []string{"number of charts"}})
+	}
+	for _, tmpl := range templates {
+		if err := validateFormat(tmpl.format, len(tmpl.args)); err != nil {
+			errors = append(errors, fmt.Sprintf("%s %v; expected %d %%s verbs for %s", tmpl.envVar, err, len(tmpl.args), strings.Join(tmpl.args, ", ")))
+		}
+	}
+
+	if c.Checker.BranchTemplate != "" {
+		if err := validateBranchTemplate(c.Checker.BranchTemplate, c.Checker.PRDeduplication); err != nil {
+			errors = append(errors, fmt.Sprintf("CHECKER_BRANCH_TEMPLATE %v", err))
+		}
+	}
+
+	switch c.Checker.Policy.Mode {
+	case "", PolicyModeBlock, PolicyModeWarn:
+	default:
+		errors = append(errors, fmt.Sprintf("CHECKER_POLICY_MODE must be %q or %q, got %q", PolicyModeBlock, PolicyModeWarn, c.Checker.Policy.Mode))
+	}
+
+	switch c.Checker.PRDeduplication {
+	case "", DedupBranch, DedupLabel, DedupBoth:
+	default:
+		errors = append(errors, fmt.Sprintf("CHECKER_PR_DEDUPLICATION must be %q, %q or %q, got %q", DedupBranch, DedupLabel, DedupBoth, c.Checker.PRDeduplication))
+	}
+
+	switch c.Checker.StateBackend {
+	case "", StateBackendFile:
+	case StateBackendRedis:
+		if c.Checker.RedisURL == "" {
+			errors = append(errors, "CHECKER_REDIS_URL is required when CHECKER_STATE_BACKEND is redis")
+		} else if u, err := url.Parse(c.Checker.RedisURL); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") {
+			errors = append(errors, "CHECKER_REDIS_URL must be a redis:// or rediss:// URL")
+		}
+	default:
+		errors = append(errors, fmt.Sprintf("CHECKER_STATE_BACKEND must be %q or %q, got %q", StateBackendFile, StateBackendRedis, c.Checker.StateBackend))
+	}
+
+	chartNames := make([]string, 0, len(c.Checker.ChartChannels))
+	for chartName := range c.Checker.ChartChannels {
+		chartNames = append(chartNames, chartName)
+	}
+	sort.Strings(chartNames)
+	for _, chartName := range chartNames {
+		switch channel := c.Checker.ChartChannels[chartName]; channel {
+		case ChannelStable, ChannelEdge:
+		default:
+			errors = append(errors, fmt.Sprintf("CHECKER_CHART_CHANNELS: chart %s must use channel %q or %q, got %q", chartName, ChannelStable, ChannelEdge, channel))
+		}
+	}
+
+	schemeCharts := make([]string, 0, len(c.Checker.VersionSchemes))
+	for chartName := range c.Checker.VersionSchemes {
+		schemeCharts = append(schemeCharts, chartName)
+	}
+	sort.Strings(schemeCharts)
+	for _, chartName := range schemeCharts {
+		switch scheme := c.Checker.VersionSchemes[chartName]; scheme {
+		case VersionSchemeSemver, VersionSchemeDate, VersionSchemeInteger:
+		default:
+			errors = append(errors, fmt.Sprintf("CHECKER_VERSION_SCHEMES: chart %s must use scheme %q, %q or %q, got %q", chartName, VersionSchemeSemver, VersionSchemeDate, VersionSchemeInteger, scheme))
+		}
+	}
+
+	pinnedCharts := make([]string, 0, len(c.Checker.PinnedVersions))
+	for chartName := range c.Checker.PinnedVersions {
+		pinnedCharts = append(pinnedCharts, chartName)
+	}
+	sort.Strings(pinnedCharts)
+	for _, chartName := range pinnedCharts {
+		if strings.TrimSpace(c.Checker.PinnedVersions[chartName]) == "" {
+			errors = append(errors, fmt.Sprintf("CHECKER_PINNED_VERSIONS: chart %s must pin a version", chartName))
+		}
+	}
+
+	if _, _, _, _, err := c.Checker.MaintenanceWindow.parse(); err != nil {
+		errors = append(errors, err.Error())
+	}
+
+	for _, pattern := range c.Checker.ExcludeVersionPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errors = append(errors, fmt.Sprintf("invalid version exclude pattern %q: %v", pattern, err))
+		}
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("configuration validation failed:\n  - %s", strings.Join(errors, "\n  - "))
 	}
@@ -120,6 +525,52 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// validateFormat checks that a format string consumes exactly argCount string
+// arguments, catching missing, extra or mistyped verbs before they produce
+// garbage such as %!s(MISSING) in commits and pull requests
+func validateFormat(format string, argCount int) error {
+	args := make([]interface{}, argCount)
+	for i := range args {
+		args[i] = "x"
+	}
+
+	output := fmt.Sprintf(format, args...)
+	if idx := strings.Index(output, "%!"); idx >= 0 {
+		problem := output[idx:]
+		if end := strings.Index(problem, ")"); end >= 0 {
+			problem = problem[:end+1]
+		}
+		return fmt.Errorf("has an invalid format string (%s)", problem)
+	}
+	return nil
+}
+
+// validateBranchTemplate checks that a branch name template parses and only
+// uses the fields the checker provides. A name that changes every run, such
+// as one using .Timestamp, can't find existing pull requests by branch, so
+// it requires label deduplication.
+func validateBranchTemplate(text, dedup string) error {
+	tmpl, err := template.New("branch").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return fmt.Errorf("is not a valid template: %w", err)
+	}
+
+	var first, second strings.Builder
+	data := map[string]string{"Chart": "x", "Version": "x", "Timestamp": "20240101000000", "Hash": "x"}
+	if err := tmpl.Execute(&first, data); err != nil {
+		return fmt.Errorf("failed to render (available fields are .Chart, .Version, .Timestamp and .Hash): %w", err)
+	}
+	data["Timestamp"] = "20240102000000"
+	if err := tmpl.Execute(&second, data); err != nil {
+		return fmt.Errorf("failed to render: %w", err)
+	}
+
+	if first.String() != second.String() && dedup != DedupLabel && dedup != DedupBoth {
+		return fmt.Errorf("uses .Timestamp, which requires CHECKER_PR_DEDUPLICATION to be %q or %q", DedupLabel, DedupBoth)
+	}
+	return nil
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -134,4 +585,53 @@ func getBoolEnvOrDefault(key string, defaultValue bool) bool {
 		}
 	}
 	return defaultValue
+}
+
+func getIntEnvOrDefault(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getDurationEnvOrDefault(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getListEnvOrDefault parses a comma-separated environment variable into a list,
+// trimming whitespace and dropping empty entries
+func getListEnvOrDefault(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// getJSONEnv decodes a JSON-encoded environment variable into target, leaving
+// target untouched when the variable is unset
+func getJSONEnv(key string, target interface{}) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	if err := json.Unmarshal([]byte(value), target); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	return nil
 }
\ No newline at end of file
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -90,4 +91,364 @@ func TestGetBoolEnvOrDefault(t *testing.T) {
 	}
 
 	_ = os.Unsetenv("TEST_BOOL")
-}
\ No newline at end of file
+}
+func TestLoadDirectoryRules(t *testing.T) {
+	_ = os.Setenv("GIT_REPOSITORY", "https://github.com/test/repo.git")
+	_ = os.Setenv("GITHUB_TOKEN", "test-token")
+	_ = os.Setenv("GITHUB_OWNER", "test-owner")
+	_ = os.Setenv("GITHUB_REPO", "test-repo")
+	_ = os.Setenv("CHECKER_DIRECTORY_RULES", `[{"pathPrefix": "teams/payments/", "targetBranch": "payments-main", "reviewers": ["alice", "org/payments"], "policy": "update"}]`)
+	defer func() {
+		_ = os.Unsetenv("GIT_REPOSITORY")
+		_ = os.Unsetenv("GITHUB_TOKEN")
+		_ = os.Unsetenv("GITHUB_OWNER")
+		_ = os.Unsetenv("GITHUB_REPO")
+		_ = os.Unsetenv("CHECKER_DIRECTORY_RULES")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if len(cfg.Checker.DirectoryRules) != 1 {
+		t.Fatalf("Expected 1 directory rule, got %d", len(cfg.Checker.DirectoryRules))
+	}
+
+	rule := cfg.Checker.DirectoryRules[0]
+	if rule.PathPrefix != "teams/payments/" || rule.TargetBranch != "payments-main" {
+		t.Errorf("Unexpected directory rule: %+v", rule)
+	}
+	if len(rule.Reviewers) != 2 {
+		t.Errorf("Expected 2 reviewers, got %d", len(rule.Reviewers))
+	}
+
+	// Unknown policies are rejected
+	_ = os.Setenv("CHECKER_DIRECTORY_RULES", `[{"pathPrefix": "teams/", "policy": "sometimes"}]`)
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected error for unknown directory rule policy")
+	}
+
+	// Malformed JSON is rejected
+	_ = os.Setenv("CHECKER_DIRECTORY_RULES", `not-json`)
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected error for malformed directory rules")
+	}
+}
+
+func TestValidateVersionSchemes(t *testing.T) {
+	cfg := &Config{
+		Git:    GitConfig{Repository: "https://github.com/test/repo.git"},
+		GitHub: GitHubConfig{Token: "test-token", Owner: "test-owner", Repo: "test-repo"},
+		Checker: CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+			VersionSchemes:   map[string]string{"calendar": VersionSchemeDate, "build": VersionSchemeInteger},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected known version schemes to be valid, got %v", err)
+	}
+
+	cfg.Checker.VersionSchemes["nightly"] = "lexical"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "chart nightly") {
+		t.Errorf("Expected error for unknown version scheme, got %v", err)
+	}
+}
+
+func TestValidatePinnedVersions(t *testing.T) {
+	cfg := &Config{
+		Git:    GitConfig{Repository: "https://github.com/test/repo.git"},
+		GitHub: GitHubConfig{Token: "test-token", Owner: "test-owner", Repo: "test-repo"},
+		Checker: CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+			PinnedVersions:   map[string]string{"redis": "18.6.1"},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected pinned version to be valid, got %v", err)
+	}
+
+	cfg.Checker.PinnedVersions["nginx"] = " "
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "chart nginx") {
+		t.Errorf("Expected error for empty pinned version, got %v", err)
+	}
+}
+
+func TestValidateStateBackend(t *testing.T) {
+	cfg := &Config{
+		Git:    GitConfig{Repository: "https://github.com/test/repo.git"},
+		GitHub: GitHubConfig{Token: "test-token", Owner: "test-owner", Repo: "test-repo"},
+		Checker: CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+			StateBackend:     StateBackendRedis,
+			RedisURL:         "redis://:secret@redis:6379/0",
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected Redis state backend to be valid, got %v", err)
+	}
+
+	cfg.Checker.RedisURL = ""
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "CHECKER_REDIS_URL is required") {
+		t.Errorf("Expected error for missing Redis URL, got %v", err)
+	}
+
+	cfg.Checker.RedisURL = "http://redis:6379"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "redis:// or rediss://") {
+		t.Errorf("Expected error for non-Redis URL, got %v", err)
+	}
+
+	cfg.Checker.StateBackend = "etcd"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "CHECKER_STATE_BACKEND") {
+		t.Errorf("Expected error for unknown state backend, got %v", err)
+	}
+}
+
+func TestStateDirectory(t *testing.T) {
+	cfg := CheckerConfig{StateFile: "/var/lib/helmchecker/state.json"}
+	if dir := cfg.StateDirectory(); dir != "/var/lib/helmchecker/state.json.d" {
+		t.Errorf("Expected the state directory to default next to the state file, got %s", dir)
+	}
+
+	cfg.StateDir = "/data/state"
+	if dir := cfg.StateDirectory(); dir != "/data/state" {
+		t.Errorf("Expected the configured state directory, got %s", dir)
+	}
+}
+
+func TestValidateDiffContext(t *testing.T) {
+	cfg := &Config{
+		Git:    GitConfig{Repository: "https://github.com/test/repo.git"},
+		GitHub: GitHubConfig{Token: "test-token", Owner: "test-owner", Repo: "test-repo"},
+		Helm:   HelmConfig{DiffContext: 0},
+		Checker: CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected zero diff context to be valid, got %v", err)
+	}
+
+	cfg.Helm.DiffContext = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "HELM_DIFF_CONTEXT") {
+		t.Errorf("Expected error for negative diff context, got %v", err)
+	}
+}
+
+func TestValidateBranchTemplate(t *testing.T) {
+	cfg := &Config{
+		Git:    GitConfig{Repository: "https://github.com/test/repo.git"},
+		GitHub: GitHubConfig{Token: "test-token", Owner: "test-owner", Repo: "test-repo"},
+		Checker: CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+			BranchTemplate:   "deps/{{.Chart}}-{{.Version}}-{{.Hash}}",
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected branch template to be valid, got %v", err)
+	}
+
+	// A timestamped branch changes every run, so only labels find its pull request
+	cfg.Checker.BranchTemplate = "deps/{{.Chart}}-{{.Version}}-{{.Timestamp}}"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "CHECKER_BRANCH_TEMPLATE uses .Timestamp") {
+		t.Errorf("Expected error for timestamped template with branch deduplication, got %v", err)
+	}
+	for _, dedup := range []string{DedupLabel, DedupBoth} {
+		cfg.Checker.PRDeduplication = dedup
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Expected timestamped template to be valid with %s deduplication, got %v", dedup, err)
+		}
+	}
+
+	cfg.Checker.BranchTemplate = "update-{{.Chart"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "CHECKER_BRANCH_TEMPLATE is not a valid template") {
+		t.Errorf("Expected error for unparsable template, got %v", err)
+	}
+
+	cfg.Checker.BranchTemplate = "update-{{.Release}}"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "available fields") {
+		t.Errorf("Expected error for unknown template field, got %v", err)
+	}
+}
+
+func TestValidateRegistryCredentials(t *testing.T) {
+	cfg := &Config{
+		Git:    GitConfig{Repository: "https://github.com/test/repo.git"},
+		GitHub: GitHubConfig{Token: "test-token", Owner: "test-owner", Repo: "test-repo"},
+		Helm: HelmConfig{Registries: []RegistryCredential{
+			{Host: "ghcr.io", Username: "bot", Password: "secret"},
+			{Host: "registry.local:5000", PlainHTTP: true},
+		}},
+		Checker: CheckerConfig{
+			CommitMessage:    "chore: update helm chart %s to version %s",
+			PullRequestTitle: "Update Helm chart %s to version %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected registry credentials to be valid, got %v", err)
+	}
+
+	cfg.Helm.Registries = append(cfg.Helm.Registries, RegistryCredential{Username: "bot"})
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "registry credential 2: host is required") ||
+		!strings.Contains(err.Error(), "registry credential 2: username and password must be set together") {
+		t.Errorf("Expected errors for incomplete registry credential, got %v", err)
+	}
+}
+
+func TestLoadChartChannels(t *testing.T) {
+	_ = os.Setenv("GIT_REPOSITORY", "https://github.com/test/repo.git")
+	_ = os.Setenv("GITHUB_TOKEN", "test-token")
+	_ = os.Setenv("GITHUB_OWNER", "test-owner")
+	_ = os.Setenv("GITHUB_REPO", "test-repo")
+	_ = os.Setenv("CHECKER_CHART_CHANNELS", `{"cert-manager": "edge"}`)
+	defer func() {
+		_ = os.Unsetenv("GIT_REPOSITORY")
+		_ = os.Unsetenv("GITHUB_TOKEN")
+		_ = os.Unsetenv("GITHUB_OWNER")
+		_ = os.Unsetenv("GITHUB_REPO")
+		_ = os.Unsetenv("CHECKER_CHART_CHANNELS")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if channel := cfg.Checker.ChannelFor("cert-manager"); channel != ChannelEdge {
+		t.Errorf("Expected cert-manager on %s channel, got %s", ChannelEdge, channel)
+	}
+	if channel := cfg.Checker.ChannelFor("nginx"); channel != ChannelStable {
+		t.Errorf("Expected nginx on %s channel, got %s", ChannelStable, channel)
+	}
+
+	// Allowing or checking pre-releases moves unsubscribed charts to edge
+	cfg.Checker.AllowPrerelease = true
+	if channel := cfg.Checker.ChannelFor("nginx"); channel != ChannelEdge {
+		t.Errorf("Expected nginx on %s channel, got %s", ChannelEdge, channel)
+	}
+	cfg.Checker.AllowPrerelease = false
+	cfg.Checker.CheckPrerelease = true
+	if channel := cfg.Checker.ChannelFor("nginx"); channel != ChannelEdge {
+		t.Errorf("Expected nginx on %s channel, got %s", ChannelEdge, channel)
+	}
+
+	// Unknown channels are rejected
+	_ = os.Setenv("CHECKER_CHART_CHANNELS", `{"cert-manager": "nightly"}`)
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected error for unknown channel")
+	}
+}
+
+func TestLoadResolvesSecretReferences(t *testing.T) {
+	_ = os.Setenv("GIT_REPOSITORY", "https://github.com/test/repo.git")
+	_ = os.Setenv("GITHUB_TOKEN", "env:TEST_GITHUB_SECRET")
+	_ = os.Setenv("TEST_GITHUB_SECRET", "resolved-token")
+	_ = os.Setenv("GITHUB_OWNER", "test-owner")
+	_ = os.Setenv("GITHUB_REPO", "test-repo")
+	defer func() {
+		_ = os.Unsetenv("GIT_REPOSITORY")
+		_ = os.Unsetenv("GITHUB_TOKEN")
+		_ = os.Unsetenv("TEST_GITHUB_SECRET")
+		_ = os.Unsetenv("GITHUB_OWNER")
+		_ = os.Unsetenv("GITHUB_REPO")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.GitHub.Token != "resolved-token" {
+		t.Errorf("Expected GitHub token 'resolved-token', got '%s'", cfg.GitHub.Token)
+	}
+	if cfg.Git.Token != "resolved-token" {
+		t.Errorf("Expected Git token to fall back to 'resolved-token', got '%s'", cfg.Git.Token)
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	tests := []struct {
+		format   string
+		argCount int
+		valid    bool
+	}{
+		{"chore: update helm chart %s to version %s", 2, true},
+		{"Update %[1]s to %[2]s (100%% automated)", 2, true},
+		{"This PR updates %s from %s to %s.", 3, true},
+		{"Update %s", 2, false},
+		{"Update %s to %s from %s", 2, false},
+		{"Update %s to %d", 2, false},
+	}
+
+	for _, tt := range tests {
+		err := validateFormat(tt.format, tt.argCount)
+		if tt.valid && err != nil {
+			t.Errorf("validateFormat(%q, %d): unexpected error: %v", tt.format, tt.argCount, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("validateFormat(%q, %d): expected error", tt.format, tt.argCount)
+		}
+	}
+}
+
+func TestValidateRejectsMalformedTemplates(t *testing.T) {
+	cfg := &Config{
+		Git:    GitConfig{Repository: "https://github.com/test/repo.git"},
+		GitHub: GitHubConfig{Token: "token", Owner: "owner", Repo: "repo"},
+		Checker: CheckerConfig{
+			CommitMessage:    "chore: update %s to %s",
+			PullRequestTitle: "Update %s",
+			PullRequestBody:  "Updates %s from %s to %s",
+		},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatalf("Expected validation error for malformed PR title")
+	}
+	if !strings.Contains(err.Error(), "CHECKER_PR_TITLE") {
+		t.Errorf("Expected error to mention CHECKER_PR_TITLE, got: %v", err)
+	}
+	if strings.Contains(err.Error(), "CHECKER_COMMIT_MESSAGE") || strings.Contains(err.Error(), "CHECKER_PR_BODY") {
+		t.Errorf("Expected only the PR title to be rejected, got: %v", err)
+	}
+}
+
+func TestValidateGroupCommitMessage(t *testing.T) {
+	cfg := &Config{
+		Git:    GitConfig{Repository: "https://github.com/test/repo.git"},
+		GitHub: GitHubConfig{Token: "token", Owner: "owner", Repo: "repo"},
+		Checker: CheckerConfig{
+			CommitMessage:      "chore: update %s to %s",
+			GroupCommitMessage: "chore: update %d charts",
+			PullRequestTitle:   "Update %s to %s",
+			PullRequestBody:    "Updates %s from %s to %s",
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected the group commit message to be ignored without batch updates, got: %v", err)
+	}
+
+	cfg.Checker.BatchUpdates = true
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "CHECKER_GROUP_COMMIT_MESSAGE") {
+		t.Errorf("Expected error to mention CHECKER_GROUP_COMMIT_MESSAGE, got: %v", err)
+	}
+
+	cfg.Checker.GroupCommitMessage = "chore: update %s charts"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected a valid group commit message, got: %v", err)
+	}
+}
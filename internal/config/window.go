@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaintenanceWindow restricts when pull requests may be opened. Days are
+// three-letter weekday names and Hours is a "start-end" range of whole hours in
+// the window's timezone, e.g. "09-17"; a range such as "22-06" spans midnight.
+type MaintenanceWindow struct {
+	Days     []string `yaml:"days"`
+	Hours    string   `yaml:"hours"`
+	Timezone string   `yaml:"timezone"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// IsSet reports whether any maintenance window restriction is configured
+func (w MaintenanceWindow) IsSet() bool {
+	return len(w.Days) > 0 || w.Hours != ""
+}
+
+// Contains reports whether t falls inside the maintenance window. An unset
+// window always contains t.
+func (w MaintenanceWindow) Contains(t time.Time) (bool, error) {
+	if !w.IsSet() {
+		return true, nil
+	}
+
+	loc, days, start, end, err := w.parse()
+	if err != nil {
+		return false, err
+	}
+
+	t = t.In(loc)
+	day := t.Weekday()
+	hour := t.Hour()
+
+	// For windows spanning midnight, early hours belong to the previous day's window
+	if start > end && hour < end {
+		day = (day + 6) % 7
+	}
+
+	if len(days) > 0 && !days[day] {
+		return false, nil
+	}
+
+	switch {
+	case start == end:
+		return true, nil
+	case start < end:
+		return hour >= start && hour < end, nil
+	default:
+		return hour >= start || hour < end, nil
+	}
+}
+
+// parse validates and decodes the window configuration
+func (w MaintenanceWindow) parse() (*time.Location, map[time.Weekday]bool, int, int, error) {
+	loc := time.UTC
+	if w.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(w.Timezone); err != nil {
+			return nil, nil, 0, 0, fmt.Errorf("invalid maintenance window timezone %q: %w", w.Timezone, err)
+		}
+	}
+
+	days := make(map[time.Weekday]bool)
+	for _, name := range w.Days {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if len(key) > 3 {
+			key = key[:3]
+		}
+		day, ok := weekdays[key]
+		if !ok {
+			return nil, nil, 0, 0, fmt.Errorf("invalid maintenance window day %q", name)
+		}
+		days[day] = true
+	}
+
+	start, end := 0, 0
+	if w.Hours != "" {
+		from, to, found := strings.Cut(w.Hours, "-")
+		if !found {
+			return nil, nil, 0, 0, fmt.Errorf("invalid maintenance window hours %q: expected start-end", w.Hours)
+		}
+
+		var err1, err2 error
+		start, err1 = strconv.Atoi(strings.TrimSpace(from))
+		end, err2 = strconv.Atoi(strings.TrimSpace(to))
+		if err1 != nil || err2 != nil || start < 0 || start > 24 || end < 0 || end > 24 {
+			return nil, nil, 0, 0, fmt.Errorf("invalid maintenance window hours %q: expected hours between 0 and 24", w.Hours)
+		}
+		start, end = start%24, end%24
+	}
+
+	return loc, days, start, end, nil
+}
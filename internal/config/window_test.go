@@ -0,0 +1,84 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindowContains(t *testing.T) {
+	window := MaintenanceWindow{
+		Days:     []string{"Mon", "Tue", "Wed", "Thu", "Fri"},
+		Hours:    "09-17",
+		Timezone: "Europe/London",
+	}
+
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		time   time.Time
+		inside bool
+	}{
+		{"weekday morning", time.Date(2024, 1, 3, 10, 0, 0, 0, london), true},
+		{"weekday before hours", time.Date(2024, 1, 3, 8, 59, 0, 0, london), false},
+		{"weekday end of hours", time.Date(2024, 1, 3, 17, 0, 0, 0, london), false},
+		{"saturday", time.Date(2024, 1, 6, 10, 0, 0, 0, london), false},
+		{"utc converted to london", time.Date(2024, 7, 3, 8, 30, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		inside, err := window.Contains(tt.time)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if inside != tt.inside {
+			t.Errorf("%s: expected inside=%v, got %v", tt.name, tt.inside, inside)
+		}
+	}
+}
+
+func TestMaintenanceWindowOvernight(t *testing.T) {
+	window := MaintenanceWindow{Days: []string{"Friday"}, Hours: "22-06"}
+
+	tests := []struct {
+		time   time.Time
+		inside bool
+	}{
+		{time.Date(2024, 1, 5, 23, 0, 0, 0, time.UTC), true},  // Friday night
+		{time.Date(2024, 1, 6, 5, 0, 0, 0, time.UTC), true},   // early Saturday, part of Friday's window
+		{time.Date(2024, 1, 5, 5, 0, 0, 0, time.UTC), false},  // early Friday, part of Thursday's window
+		{time.Date(2024, 1, 6, 23, 0, 0, 0, time.UTC), false}, // Saturday night
+	}
+
+	for _, tt := range tests {
+		inside, err := window.Contains(tt.time)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if inside != tt.inside {
+			t.Errorf("%s: expected inside=%v, got %v", tt.time, tt.inside, inside)
+		}
+	}
+}
+
+func TestMaintenanceWindowUnsetAndInvalid(t *testing.T) {
+	inside, err := MaintenanceWindow{}.Contains(time.Now())
+	if err != nil || !inside {
+		t.Errorf("Expected unset window to always contain now, got %v, %v", inside, err)
+	}
+
+	invalid := []MaintenanceWindow{
+		{Days: []string{"Funday"}},
+		{Hours: "9to5"},
+		{Hours: "09-25"},
+		{Hours: "09-17", Timezone: "Mars/Olympus"},
+	}
+	for _, window := range invalid {
+		if _, err := window.Contains(time.Now()); err == nil {
+			t.Errorf("Expected error for %+v", window)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	godiff "github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change
+const DefaultContext = 3
+
+// line is a single line of a diff with its operation prefix
+type line struct {
+	op   byte
+	text string
+}
+
+// Unified returns a unified diff turning oldText into newText with context
+// unchanged lines around each change, or an empty string when they are identical
+func Unified(oldName, newName, oldText, newText string, context int) string {
+	if oldText == newText {
+		return ""
+	}
+
+	lines := diffLines(oldText, newText)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+
+	for _, h := range hunks(lines, context) {
+		oldStart, newStart := 1, 1
+		for _, l := range lines[:h[0]] {
+			if l.op != '+' {
+				oldStart++
+			}
+			if l.op != '-' {
+				newStart++
+			}
+		}
+
+		oldCount, newCount := 0, 0
+		for _, l := range lines[h[0]:h[1]] {
+			if l.op != '+' {
+				oldCount++
+			}
+			if l.op != '-' {
+				newCount++
+			}
+		}
+
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, l := range lines[h[0]:h[1]] {
+			b.WriteByte(l.op)
+			b.WriteString(l.text)
+			b.WriteByte('\n')
+		}
+	}
+
+	return b.String()
+}
+
+// diffLines computes a line-oriented diff of the two texts
+func diffLines(oldText, newText string) []line {
+	var lines []line
+	for _, d := range godiff.Do(oldText, newText) {
+		op := byte(' ')
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			op = '-'
+		case diffmatchpatch.DiffInsert:
+			op = '+'
+		}
+
+		for _, text := range strings.SplitAfter(d.Text, "\n") {
+			if text == "" {
+				continue
+			}
+			lines = append(lines, line{op: op, text: strings.TrimSuffix(text, "\n")})
+		}
+	}
+	return lines
+}
+
+// hunks groups changed lines with up to context surrounding lines, returning
+// half-open [start, end) index ranges into lines
+func hunks(lines []line, context int) [][2]int {
+	var result [][2]int
+	for i, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i + context + 1
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		if n := len(result); n > 0 && start <= result[n-1][1] {
+			result[n-1][1] = end
+			continue
+		}
+		result = append(result, [2]int{start, end})
+	}
+	return result
+}
+
+// hunkRange formats a hunk header range
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,81 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numberedLines returns n lines "line 1" to "line n"
+func numberedLines(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	return lines
+}
+
+// contextLines counts the unchanged lines of a unified diff
+func contextLines(unified string) int {
+	count := 0
+	for _, l := range strings.Split(unified, "\n") {
+		if strings.HasPrefix(l, " ") {
+			count++
+		}
+	}
+	return count
+}
+
+func TestUnifiedContext(t *testing.T) {
+	oldLines := numberedLines(20)
+	newLines := numberedLines(20)
+	newLines[9] = "line 10 changed"
+	oldText := strings.Join(oldLines, "\n") + "\n"
+	newText := strings.Join(newLines, "\n") + "\n"
+
+	tests := []struct {
+		context  int
+		header   string
+		expected int
+	}{
+		{DefaultContext, "@@ -7,7 +7,7 @@", 6},
+		{0, "@@ -10 +10 @@", 0},
+		{5, "@@ -5,11 +5,11 @@", 10},
+		// Context is limited by the start and end of the file
+		{15, "@@ -1,20 +1,20 @@", 19},
+	}
+
+	for _, tt := range tests {
+		unified := Unified("a", "b", oldText, newText, tt.context)
+		if !strings.Contains(unified, tt.header+"\n") {
+			t.Errorf("context %d: expected hunk header %q, got:\n%s", tt.context, tt.header, unified)
+		}
+		if got := contextLines(unified); got != tt.expected {
+			t.Errorf("context %d: expected %d context lines, got %d", tt.context, tt.expected, got)
+		}
+	}
+}
+
+func TestUnifiedMergesNearbyHunks(t *testing.T) {
+	oldLines := numberedLines(30)
+	newLines := numberedLines(30)
+	newLines[4] = "line 5 changed"
+	newLines[11] = "line 12 changed"
+	oldText := strings.Join(oldLines, "\n") + "\n"
+	newText := strings.Join(newLines, "\n") + "\n"
+
+	// Six unchanged lines apart: separate hunks with 2 lines of context...
+	if hunks := strings.Count(Unified("a", "b", oldText, newText, 2), "@@ -"); hunks != 2 {
+		t.Errorf("Expected 2 hunks with context 2, got %d", hunks)
+	}
+	// ...and a single hunk once the context of both changes overlaps
+	if hunks := strings.Count(Unified("a", "b", oldText, newText, 3), "@@ -"); hunks != 1 {
+		t.Errorf("Expected 1 hunk with context 3, got %d", hunks)
+	}
+}
+
+func TestUnifiedIdentical(t *testing.T) {
+	if unified := Unified("a", "b", "same\n", "same\n", DefaultContext); unified != "" {
+		t.Errorf("Expected no diff for identical texts, got %q", unified)
+	}
+}
@@ -2,6 +2,7 @@ package git
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,25 +14,46 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	gitconfig "github.com/marccoxall/helmchecker/internal/config"
+	"github.com/marccoxall/helmchecker/internal/redact"
 )
 
 // Client represents a Git client
 type Client struct {
 	config gitconfig.GitConfig
+	guard  *CloneGuard
 }
 
 // NewClient creates a new Git client
 func NewClient(cfg gitconfig.GitConfig) *Client {
 	return &Client{
 		config: cfg,
+		guard:  NewCloneGuard(cfg.MaxConcurrentClones, int64(cfg.CloneDiskBudgetMB)*1024*1024),
 	}
 }
 
+// redact removes the token and URL credentials from errors that may echo
+// remote URLs or server responses
+func (c *Client) redact(err error) error {
+	return redact.Error(err, c.config.Token)
+}
+
 // CloneRepository clones a repository to a temporary directory
 func (c *Client) CloneRepository(ctx context.Context) (string, *gogit.Repository, error) {
-	// Create a temporary directory
-	tempDir, err := os.MkdirTemp("", "helmchecker-*")
+	// Wait for a free clone slot and disk budget
+	if err := c.guard.Acquire(ctx); err != nil {
+		return "", nil, fmt.Errorf("failed waiting for a clone slot: %w", err)
+	}
+
+	// Create a temporary directory, under the configured base directory if any
+	if c.config.TempDir != "" {
+		if err := os.MkdirAll(c.config.TempDir, 0755); err != nil {
+			c.guard.Release("", true)
+			return "", nil, fmt.Errorf("failed to create temp base directory: %w", err)
+		}
+	}
+	tempDir, err := os.MkdirTemp(c.config.TempDir, "helmchecker-*")
 	if err != nil {
+		c.guard.Release("", true)
 		return "", nil, fmt.Errorf("failed to create temp directory: %w", err)
 	}
 
@@ -49,28 +71,110 @@ func (c *Client) CloneRepository(ctx context.Context) (string, *gogit.Repository
 		URL:      c.config.Repository,
 		Progress: os.Stdout,
 	}
-	
+
 	// Only set auth if we have credentials
 	if auth != nil {
 		cloneOptions.Auth = auth
 	}
 
+	// Check out the configured base branch rather than the remote's default
+	if c.config.Branch != "" {
+		cloneOptions.ReferenceName = plumbing.NewBranchReferenceName(c.config.Branch)
+	}
+
 	repo, err := gogit.PlainCloneContext(ctx, tempDir, false, cloneOptions)
 	if err != nil {
-		if removeErr := os.RemoveAll(tempDir); removeErr != nil {
+		if removeErr := c.RemoveClone(tempDir, true); removeErr != nil {
 			fmt.Printf("Warning: failed to clean up temp directory: %v\n", removeErr)
 		}
-		
+
 		// Provide more helpful error message
+		if errors.Is(err, plumbing.ErrReferenceNotFound) || errors.Is(err, gogit.NoMatchingRefSpecError{}) {
+			return "", nil, c.redact(fmt.Errorf("failed to clone repository: base branch %s does not exist: %w", c.config.Branch, err))
+		}
 		if c.config.Token == "" {
-			return "", nil, fmt.Errorf("failed to clone repository: %w (hint: make sure GIT_TOKEN environment variable is set if the repository requires authentication)", err)
+			return "", nil, c.redact(fmt.Errorf("failed to clone repository: %w (hint: make sure GIT_TOKEN environment variable is set if the repository requires authentication)", err))
 		}
-		return "", nil, fmt.Errorf("failed to clone repository: %w (hint: check repository URL and credentials)", err)
+		return "", nil, c.redact(fmt.Errorf("failed to clone repository: %w (hint: check repository URL and credentials)", err))
+	}
+
+	size, err := dirSize(tempDir)
+	if err != nil {
+		fmt.Printf("Warning: failed to measure clone size: %v\n", err)
+	}
+	if !c.guard.Track(tempDir, size) {
+		if removeErr := c.RemoveClone(tempDir, false); removeErr != nil {
+			fmt.Printf("Warning: failed to clean up temp directory: %v\n", removeErr)
+		}
+		return "", nil, fmt.Errorf("clone of %d MB exceeds the disk budget of %d MB", size/(1024*1024), c.config.CloneDiskBudgetMB)
 	}
 
 	return tempDir, repo, nil
 }
 
+// RemoveClone deletes a clone created by CloneRepository, freeing its slot.
+// When failed is set and clones are retained on failure, the clone is kept on
+// disk for debugging instead.
+func (c *Client) RemoveClone(repoPath string, failed bool) error {
+	if failed && c.config.RetainCloneOnFailure {
+		fmt.Printf("Retaining clone at %s for debugging\n", repoPath)
+		c.guard.Release(repoPath, false)
+		return nil
+	}
+
+	if err := os.RemoveAll(repoPath); err != nil {
+		c.guard.Release(repoPath, false)
+		return fmt.Errorf("failed to remove clone %s: %w", repoPath, err)
+	}
+	c.guard.Release(repoPath, true)
+	return nil
+}
+
+// FetchBranch updates the remote-tracking ref of a branch from origin so it
+// can be checked out at its latest commit
+func (c *Client) FetchBranch(ctx context.Context, repo *gogit.Repository, branchName string) error {
+	auth := &http.BasicAuth{
+		Username: c.config.Username,
+		Password: c.config.Token,
+	}
+
+	err := repo.FetchContext(ctx, &gogit.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", branchName, branchName)),
+		},
+		Auth: auth,
+	})
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return c.redact(fmt.Errorf("failed to fetch branch %s: %w", branchName, err))
+	}
+
+	return nil
+}
+
+// CheckoutBranch checks out the given branch as it exists on origin
+func (c *Client) CheckoutBranch(repo *gogit.Repository, branchName string) error {
+	workTree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	ref, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", branchName), true)
+	if err != nil {
+		return fmt.Errorf("branch %s not found on origin: %w", branchName, err)
+	}
+
+	err = workTree.Checkout(&gogit.CheckoutOptions{
+		Hash:  ref.Hash(),
+		Force: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to checkout branch %s: %w", branchName, err)
+	}
+
+	return nil
+}
+
 // CreateBranch creates a new branch from the base branch
 func (c *Client) CreateBranch(repo *gogit.Repository, branchName string) error {
 	workTree, err := repo.Worktree()
@@ -84,10 +188,10 @@ func (c *Client) CreateBranch(repo *gogit.Repository, branchName string) error {
 		return fmt.Errorf("failed to get HEAD: %w", err)
 	}
 
-	// Create and checkout the new branch
-	branchRefName := fmt.Sprintf("refs/heads/%s", branchName)
+	// Create the new branch at HEAD and check it out so commits land on it
 	err = workTree.Checkout(&gogit.CheckoutOptions{
-		Branch: headRef.Name(),
+		Branch: plumbing.NewBranchReferenceName(branchName),
+		Hash:   headRef.Hash(),
 		Create: true,
 		Force:  true,
 	})
@@ -95,13 +199,6 @@ func (c *Client) CreateBranch(repo *gogit.Repository, branchName string) error {
 		return fmt.Errorf("failed to create branch: %w", err)
 	}
 
-	// Create the branch reference
-	ref := plumbing.NewHashReference(plumbing.ReferenceName(branchRefName), headRef.Hash())
-	err = repo.Storer.SetReference(ref)
-	if err != nil {
-		return fmt.Errorf("failed to set branch reference: %w", err)
-	}
-
 	return nil
 }
 
@@ -157,7 +254,28 @@ func (c *Client) PushBranch(repo *gogit.Repository, branchName string) error {
 		Auth: auth,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to push branch: %w", err)
+		return c.redact(fmt.Errorf("failed to push branch: %w", err))
+	}
+
+	return nil
+}
+
+// DeleteRemoteBranch deletes a branch from origin
+func (c *Client) DeleteRemoteBranch(repo *gogit.Repository, branchName string) error {
+	auth := &http.BasicAuth{
+		Username: c.config.Username,
+		Password: c.config.Token,
+	}
+
+	err := repo.Push(&gogit.PushOptions{
+		RemoteName: "origin",
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(fmt.Sprintf(":refs/heads/%s", branchName)),
+		},
+		Auth: auth,
+	})
+	if err != nil {
+		return c.redact(fmt.Errorf("failed to delete remote branch: %w", err))
 	}
 
 	return nil
@@ -179,4 +297,4 @@ func (c *Client) UpdateFile(repoPath, filePath, content string) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
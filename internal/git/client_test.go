@@ -0,0 +1,313 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	gitconfig "github.com/marccoxall/helmchecker/internal/config"
+)
+
+func TestCloneErrorRedactsCredentials(t *testing.T) {
+	const token = "ghp_s3cr3tT0k3n"
+
+	client := NewClient(gitconfig.GitConfig{
+		// Nothing listens on port 1, so the clone fails quickly
+		Repository: "http://bot:" + token + "@127.0.0.1:1/org/charts.git",
+		Username:   "bot",
+		Token:      token,
+	})
+
+	_, _, err := client.CloneRepository(context.Background())
+	if err == nil {
+		t.Fatal("Expected clone to fail")
+	}
+	if strings.Contains(err.Error(), token) {
+		t.Errorf("Expected token to be redacted, got %q", err.Error())
+	}
+}
+
+// initOrigin creates a repository with a main branch to clone from
+func initOrigin(t *testing.T) (string, *gogit.Repository) {
+	t.Helper()
+
+	originDir := t.TempDir()
+	origin, err := gogit.PlainInitWithOptions(originDir, &gogit.PlainInitOptions{
+		InitOptions: gogit.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	if err != nil {
+		t.Fatalf("failed to init origin: %v", err)
+	}
+	commitFile(t, origin, originDir, "Chart.yaml", "version: 1.0.0\n")
+	return originDir, origin
+}
+
+func TestCloneUsesTempDir(t *testing.T) {
+	originDir, _ := initOrigin(t)
+	baseDir := filepath.Join(t.TempDir(), "clones")
+
+	client := NewClient(gitconfig.GitConfig{Repository: originDir, Branch: "main", TempDir: baseDir})
+	repoPath, _, err := client.CloneRepository(context.Background())
+	if err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+
+	if filepath.Dir(repoPath) != baseDir {
+		t.Errorf("Expected clone under %s, got %s", baseDir, repoPath)
+	}
+
+	// Successful runs are always cleaned up
+	if err := client.RemoveClone(repoPath, false); err != nil {
+		t.Fatalf("RemoveClone failed: %v", err)
+	}
+	if _, err := os.Stat(repoPath); !os.IsNotExist(err) {
+		t.Errorf("Expected clone to be removed, got %v", err)
+	}
+}
+
+func TestRetainCloneOnFailure(t *testing.T) {
+	originDir, _ := initOrigin(t)
+
+	for _, retain := range []bool{false, true} {
+		client := NewClient(gitconfig.GitConfig{Repository: originDir, Branch: "main", TempDir: t.TempDir(), RetainCloneOnFailure: retain})
+		repoPath, _, err := client.CloneRepository(context.Background())
+		if err != nil {
+			t.Fatalf("failed to clone: %v", err)
+		}
+
+		if err := client.RemoveClone(repoPath, true); err != nil {
+			t.Fatalf("RemoveClone failed: %v", err)
+		}
+
+		_, err = os.Stat(repoPath)
+		if retain && err != nil {
+			t.Errorf("Expected failed clone to be retained, got %v", err)
+		}
+		if !retain && !os.IsNotExist(err) {
+			t.Errorf("Expected failed clone to be removed, got %v", err)
+		}
+	}
+}
+
+func TestRetainedCloneKeepsDiskBudget(t *testing.T) {
+	originDir, _ := initOrigin(t)
+
+	client := NewClient(gitconfig.GitConfig{Repository: originDir, Branch: "main", TempDir: t.TempDir(), RetainCloneOnFailure: true})
+	client.guard = NewCloneGuard(0, cloneSize(t, originDir))
+
+	repoPath, _, err := client.CloneRepository(context.Background())
+	if err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+	if err := client.RemoveClone(repoPath, true); err != nil {
+		t.Fatalf("RemoveClone failed: %v", err)
+	}
+
+	// The retained clone still fills the budget, so the next clone queues
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := client.CloneRepository(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the second clone to wait for disk budget, got %v", err)
+	}
+}
+
+func TestCloneExceedingDiskBudget(t *testing.T) {
+	originDir, _ := initOrigin(t)
+	tempDir := t.TempDir()
+
+	client := NewClient(gitconfig.GitConfig{Repository: originDir, Branch: "main", TempDir: tempDir, CloneDiskBudgetMB: 1})
+	client.guard = NewCloneGuard(0, cloneSize(t, originDir)-1)
+
+	_, _, err := client.CloneRepository(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "disk budget") {
+		t.Fatalf("Expected the clone to exceed the disk budget, got %v", err)
+	}
+
+	// The oversized clone is removed and frees its budget again
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("failed to read temp directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected the clone to be removed, got %d entries", len(entries))
+	}
+	if client.guard.usedBytes != 0 || client.guard.active != 0 {
+		t.Errorf("Expected the budget to be freed, got %d bytes in %d clones", client.guard.usedBytes, client.guard.active)
+	}
+}
+
+// cloneSize returns the disk usage of a clone of origin
+func cloneSize(t *testing.T, originDir string) int64 {
+	t.Helper()
+
+	client := NewClient(gitconfig.GitConfig{Repository: originDir, Branch: "main", TempDir: t.TempDir()})
+	repoPath, _, err := client.CloneRepository(context.Background())
+	if err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+	size, err := dirSize(repoPath)
+	if err != nil {
+		t.Fatalf("failed to measure clone: %v", err)
+	}
+	return size
+}
+
+func TestCloneChecksOutConfiguredBranch(t *testing.T) {
+	originDir, origin := initOrigin(t)
+
+	// Add a develop branch while keeping main as the default branch
+	workTree, err := origin.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	if err := workTree.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("develop"), Create: true}); err != nil {
+		t.Fatalf("failed to create develop: %v", err)
+	}
+	develop := commitFile(t, origin, originDir, "Chart.yaml", "version: 2.0.0-dev\n")
+	if err := workTree.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("main")}); err != nil {
+		t.Fatalf("failed to check out main: %v", err)
+	}
+
+	client := NewClient(gitconfig.GitConfig{Repository: originDir, Branch: "develop"})
+	repoPath, repo, err := client.CloneRepository(context.Background())
+	if err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+	defer func() { _ = client.RemoveClone(repoPath, false) }()
+
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+	if head.Name() != plumbing.NewBranchReferenceName("develop") || head.Hash() != develop {
+		t.Errorf("Expected HEAD on develop at %s, got %s at %s", develop, head.Name(), head.Hash())
+	}
+
+	// A missing base branch fails clearly
+	client = NewClient(gitconfig.GitConfig{Repository: originDir, Branch: "release"})
+	if _, _, err := client.CloneRepository(context.Background()); err == nil || !strings.Contains(err.Error(), "base branch release does not exist") {
+		t.Errorf("Expected missing base branch error, got %v", err)
+	}
+}
+
+// commitFile writes a file to a non-bare repository and commits it
+func commitFile(t *testing.T, repo *gogit.Repository, dir, name, content string) plumbing.Hash {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	workTree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	if _, err := workTree.Add(name); err != nil {
+		t.Fatalf("failed to add file: %v", err)
+	}
+	hash, err := workTree.Commit("update "+name, &gogit.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	return hash
+}
+
+func TestFetchBranchUpdatesStaleBase(t *testing.T) {
+	originDir, origin := initOrigin(t)
+
+	client := NewClient(gitconfig.GitConfig{Repository: originDir, Branch: "main"})
+	repoPath, repo, err := client.CloneRepository(context.Background())
+	if err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+	defer func() { _ = client.RemoveClone(repoPath, false) }()
+
+	// The base branch moves on after the clone was made
+	latest := commitFile(t, origin, originDir, "Chart.yaml", "version: 1.1.0\n")
+
+	if err := client.FetchBranch(context.Background(), repo, "main"); err != nil {
+		t.Fatalf("FetchBranch failed: %v", err)
+	}
+	if err := client.CheckoutBranch(repo, "main"); err != nil {
+		t.Fatalf("CheckoutBranch failed: %v", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+	if head.Hash() != latest {
+		t.Errorf("Expected HEAD at the latest origin commit %s, got %s", latest, head.Hash())
+	}
+
+	data, err := os.ReadFile(filepath.Join(repoPath, "Chart.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(data) != "version: 1.1.0\n" {
+		t.Errorf("Expected the worktree to reflect the latest commit, got %q", data)
+	}
+
+	// Fetching again when nothing changed is not an error
+	if err := client.FetchBranch(context.Background(), repo, "main"); err != nil {
+		t.Errorf("Expected up-to-date fetch to succeed, got %v", err)
+	}
+}
+
+func TestCreateBranchChecksOutNewBranch(t *testing.T) {
+	originDir, _ := initOrigin(t)
+
+	client := NewClient(gitconfig.GitConfig{Repository: originDir, Branch: "main", Username: "bot", Email: "bot@example.com"})
+	repoPath, repo, err := client.CloneRepository(context.Background())
+	if err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+	defer func() { _ = client.RemoveClone(repoPath, false) }()
+
+	base, err := repo.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+
+	if err := client.CreateBranch(repo, "update-nginx-1.1.0"); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+	if head.Name() != plumbing.NewBranchReferenceName("update-nginx-1.1.0") {
+		t.Errorf("Expected HEAD on the new branch, got %s", head.Name())
+	}
+
+	if err := client.UpdateFile(repoPath, "Chart.yaml", "version: 1.1.0\n"); err != nil {
+		t.Fatalf("UpdateFile failed: %v", err)
+	}
+	if err := client.CommitChanges(repo, "update nginx"); err != nil {
+		t.Fatalf("CommitChanges failed: %v", err)
+	}
+
+	// The commit advances the new branch and leaves the base branch alone
+	branch, err := repo.Reference(plumbing.NewBranchReferenceName("update-nginx-1.1.0"), true)
+	if err != nil {
+		t.Fatalf("failed to resolve new branch: %v", err)
+	}
+	if branch.Hash() == base.Hash() {
+		t.Errorf("Expected the new branch to advance past %s", base.Hash())
+	}
+	mainRef, err := repo.Reference(plumbing.NewBranchReferenceName("main"), true)
+	if err != nil {
+		t.Fatalf("failed to resolve main: %v", err)
+	}
+	if mainRef.Hash() != base.Hash() {
+		t.Errorf("Expected main to stay at %s, got %s", base.Hash(), mainRef.Hash())
+	}
+}
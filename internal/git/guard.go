@@ -0,0 +1,102 @@
+package git
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"sync"
+)
+
+// CloneGuard limits how many clones may exist at once and how much temporary
+// disk space they may use in total. Clone requests beyond either limit wait
+// until an existing clone is released.
+type CloneGuard struct {
+	maxClones int
+	maxBytes  int64
+
+	mu        sync.Mutex
+	active    int
+	usedBytes int64
+	sizes     map[string]int64
+	changed   chan struct{}
+}
+
+// NewCloneGuard creates a guard allowing at most maxClones clones using at
+// most maxBytes of disk; zero disables the respective limit
+func NewCloneGuard(maxClones int, maxBytes int64) *CloneGuard {
+	return &CloneGuard{
+		maxClones: maxClones,
+		maxBytes:  maxBytes,
+		sizes:     make(map[string]int64),
+		changed:   make(chan struct{}),
+	}
+}
+
+// Acquire reserves a clone slot, waiting while the clone or disk limit is
+// reached or until ctx is done
+func (g *CloneGuard) Acquire(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		if (g.maxClones <= 0 || g.active < g.maxClones) && (g.maxBytes <= 0 || g.usedBytes < g.maxBytes) {
+			g.active++
+			g.mu.Unlock()
+			return nil
+		}
+		changed := g.changed
+		g.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Track records the disk usage of the clone at path, reporting whether the
+// clones still fit in the disk budget
+func (g *CloneGuard) Track(path string, size int64) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.usedBytes += size - g.sizes[path]
+	g.sizes[path] = size
+	return g.maxBytes <= 0 || g.usedBytes <= g.maxBytes
+}
+
+// Release frees the slot of the clone at path, and its disk usage once the
+// clone has been removed. A clone retained on disk keeps counting against the
+// disk budget.
+func (g *CloneGuard) Release(path string, removed bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.active--
+	if removed {
+		g.usedBytes -= g.sizes[path]
+		delete(g.sizes, path)
+	}
+
+	// Wake up waiting clone requests
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// dirSize returns the total size of the regular files under path
+func dirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,122 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// acquireAsync starts an Acquire and returns a channel receiving its result
+func acquireAsync(ctx context.Context, guard *CloneGuard) chan error {
+	done := make(chan error, 1)
+	go func() { done <- guard.Acquire(ctx) }()
+	return done
+}
+
+// expectBlocked fails if an Acquire completes within a short wait
+func expectBlocked(t *testing.T, done chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		t.Fatalf("Expected clone to queue, but it was admitted (err=%v)", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// expectAdmitted fails unless an Acquire completes successfully
+func expectAdmitted(t *testing.T, done chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected clone to be admitted, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected queued clone to be admitted")
+	}
+}
+
+func TestCloneGuardConcurrency(t *testing.T) {
+	guard := NewCloneGuard(2, 0)
+	ctx := context.Background()
+
+	expectAdmitted(t, acquireAsync(ctx, guard))
+	expectAdmitted(t, acquireAsync(ctx, guard))
+
+	third := acquireAsync(ctx, guard)
+	expectBlocked(t, third)
+
+	guard.Release("/tmp/first", true)
+	expectAdmitted(t, third)
+}
+
+func TestCloneGuardDiskBudget(t *testing.T) {
+	guard := NewCloneGuard(0, 100)
+	ctx := context.Background()
+
+	expectAdmitted(t, acquireAsync(ctx, guard))
+	if !guard.Track("/tmp/first", 60) {
+		t.Errorf("Expected 60 of 100 bytes to fit the budget")
+	}
+	expectAdmitted(t, acquireAsync(ctx, guard))
+	if guard.Track("/tmp/second", 60) {
+		t.Errorf("Expected 120 of 100 bytes to exceed the budget")
+	}
+
+	// 120 of 100 bytes in use: further clones wait for space
+	third := acquireAsync(ctx, guard)
+	expectBlocked(t, third)
+
+	guard.Release("/tmp/first", true)
+	expectAdmitted(t, third)
+}
+
+func TestCloneGuardRetainedClone(t *testing.T) {
+	guard := NewCloneGuard(0, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	expectAdmitted(t, acquireAsync(ctx, guard))
+	guard.Track("/tmp/first", 120)
+
+	// The clone is kept on disk, so its space stays in use
+	guard.Release("/tmp/first", false)
+	expectBlocked(t, acquireAsync(ctx, guard))
+}
+
+func TestCloneGuardContextCancelled(t *testing.T) {
+	guard := NewCloneGuard(1, 0)
+	expectAdmitted(t, acquireAsync(context.Background(), guard))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queued := acquireAsync(ctx, guard)
+	expectBlocked(t, queued)
+
+	cancel()
+	if err := <-queued; err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "nested"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a"), make([]byte, 10), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nested", "b"), make([]byte, 32), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	size, err := dirSize(dir)
+	if err != nil {
+		t.Fatalf("dirSize failed: %v", err)
+	}
+	if size != 42 {
+		t.Errorf("Expected 42 bytes, got %d", size)
+	}
+}
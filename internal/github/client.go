@@ -3,14 +3,17 @@ package github
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/google/go-github/v56/github"
+	"github.com/marccoxall/helmchecker/internal/redact"
 	"golang.org/x/oauth2"
 )
 
 // Client represents a GitHub client
 type Client struct {
 	client *github.Client
+	token  string
 }
 
 // NewClient creates a new GitHub client
@@ -20,14 +23,21 @@ func NewClient(token string) *Client {
 		&oauth2.Token{AccessToken: token},
 	)
 	tc := oauth2.NewClient(ctx, ts)
+	tc.Transport = NewRateLimitTransport(tc.Transport, DefaultRateLimitThreshold)
 
 	client := github.NewClient(tc)
 
 	return &Client{
 		client: client,
+		token:  token,
 	}
 }
 
+// redact removes the token and sensitive URL components from API errors
+func (c *Client) redact(err error) error {
+	return redact.Error(err, c.token)
+}
+
 // CreatePullRequest creates a new pull request
 func (c *Client) CreatePullRequest(ctx context.Context, owner, repo, title, body, head, base string) (*github.PullRequest, error) {
 	newPR := &github.NewPullRequest{
@@ -39,7 +49,22 @@ func (c *Client) CreatePullRequest(ctx context.Context, owner, repo, title, body
 
 	pr, _, err := c.client.PullRequests.Create(ctx, owner, repo, newPR)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create pull request: %w", err)
+		return nil, c.redact(fmt.Errorf("failed to create pull request: %w", err))
+	}
+
+	return pr, nil
+}
+
+// UpdatePullRequest replaces the title and body of a pull request
+func (c *Client) UpdatePullRequest(ctx context.Context, owner, repo string, number int, title, body string) (*github.PullRequest, error) {
+	edit := &github.PullRequest{
+		Title: github.String(title),
+		Body:  github.String(body),
+	}
+
+	pr, _, err := c.client.PullRequests.Edit(ctx, owner, repo, number, edit)
+	if err != nil {
+		return nil, c.redact(fmt.Errorf("failed to update pull request: %w", err))
 	}
 
 	return pr, nil
@@ -49,7 +74,7 @@ func (c *Client) CreatePullRequest(ctx context.Context, owner, repo, title, body
 func (c *Client) GetPullRequest(ctx context.Context, owner, repo string, number int) (*github.PullRequest, error) {
 	pr, _, err := c.client.PullRequests.Get(ctx, owner, repo, number)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pull request: %w", err)
+		return nil, c.redact(fmt.Errorf("failed to get pull request: %w", err))
 	}
 
 	return pr, nil
@@ -59,18 +84,159 @@ func (c *Client) GetPullRequest(ctx context.Context, owner, repo string, number
 func (c *Client) ListPullRequests(ctx context.Context, owner, repo string, opts *github.PullRequestListOptions) ([]*github.PullRequest, error) {
 	prs, _, err := c.client.PullRequests.List(ctx, owner, repo, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list pull requests: %w", err)
+		return nil, c.redact(fmt.Errorf("failed to list pull requests: %w", err))
 	}
 
 	return prs, nil
 }
 
-// CheckIfPRExists checks if a pull request already exists for the given head branch
-func (c *Client) CheckIfPRExists(ctx context.Context, owner, repo, head string) (*github.PullRequest, error) {
+// ListOpenPullRequests returns every open pull request of a repository
+func (c *Client) ListOpenPullRequests(ctx context.Context, owner, repo string) ([]*github.PullRequest, error) {
+	opts := &github.PullRequestListOptions{
+		State:       "open",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	var all []*github.PullRequest
+	for {
+		prs, resp, err := c.client.PullRequests.List(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, c.redact(fmt.Errorf("failed to list open pull requests: %w", err))
+		}
+		all = append(all, prs...)
+
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// RequestReviewers requests reviews on a pull request. Reviewers of the form
+// "org/team" are requested as team reviewers using the team slug.
+func (c *Client) RequestReviewers(ctx context.Context, owner, repo string, number int, reviewers []string) error {
+	var request github.ReviewersRequest
+	for _, reviewer := range reviewers {
+		if idx := strings.Index(reviewer, "/"); idx >= 0 {
+			request.TeamReviewers = append(request.TeamReviewers, reviewer[idx+1:])
+			continue
+		}
+		request.Reviewers = append(request.Reviewers, reviewer)
+	}
+
+	if _, _, err := c.client.PullRequests.RequestReviewers(ctx, owner, repo, number, request); err != nil {
+		return c.redact(fmt.Errorf("failed to request reviewers: %w", err))
+	}
+
+	return nil
+}
+
+// FindPRByLabel returns the first open pull request carrying the given label, or nil if there is none
+func (c *Client) FindPRByLabel(ctx context.Context, owner, repo, label string) (*github.PullRequest, error) {
+	opts := &github.IssueListByRepoOptions{
+		State:  "open",
+		Labels: []string{label},
+	}
+
+	issues, _, err := c.client.Issues.ListByRepo(ctx, owner, repo, opts)
+	if err != nil {
+		return nil, c.redact(fmt.Errorf("failed to list issues labelled %s: %w", label, err))
+	}
+
+	for _, issue := range issues {
+		if !issue.IsPullRequest() {
+			continue
+		}
+		return c.GetPullRequest(ctx, owner, repo, issue.GetNumber())
+	}
+
+	return nil, nil
+}
+
+// AddLabels adds labels to a pull request, creating labels that don't exist yet
+func (c *Client) AddLabels(ctx context.Context, owner, repo string, number int, labels []string) error {
+	if _, _, err := c.client.Issues.AddLabelsToIssue(ctx, owner, repo, number, labels); err != nil {
+		return c.redact(fmt.Errorf("failed to add labels: %w", err))
+	}
+
+	return nil
+}
+
+// FindOpenIssue returns the open issue (not pull request) with the given
+// title, or nil if there is none
+func (c *Client) FindOpenIssue(ctx context.Context, owner, repo, title string) (*github.Issue, error) {
+	opts := &github.IssueListByRepoOptions{
+		State:       "open",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	for {
+		issues, resp, err := c.client.Issues.ListByRepo(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, c.redact(fmt.Errorf("failed to list issues: %w", err))
+		}
+
+		for _, issue := range issues {
+			if !issue.IsPullRequest() && issue.GetTitle() == title {
+				return issue, nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// CreateIssue opens an issue
+func (c *Client) CreateIssue(ctx context.Context, owner, repo, title, body string) (*github.Issue, error) {
+	request := &github.IssueRequest{
+		Title: github.String(title),
+		Body:  github.String(body),
+	}
+
+	issue, _, err := c.client.Issues.Create(ctx, owner, repo, request)
+	if err != nil {
+		return nil, c.redact(fmt.Errorf("failed to create issue: %w", err))
+	}
+
+	return issue, nil
+}
+
+// CreateReview adds a comment-only review to a pull request
+func (c *Client) CreateReview(ctx context.Context, owner, repo string, number int, body string) error {
+	review := &github.PullRequestReviewRequest{
+		Body:  github.String(body),
+		Event: github.String("COMMENT"),
+	}
+
+	if _, _, err := c.client.PullRequests.CreateReview(ctx, owner, repo, number, review); err != nil {
+		return c.redact(fmt.Errorf("failed to create review: %w", err))
+	}
+
+	return nil
+}
+
+// CreateComment adds a comment to a pull request
+func (c *Client) CreateComment(ctx context.Context, owner, repo string, number int, body string) error {
+	comment := &github.IssueComment{
+		Body: github.String(body),
+	}
+
+	if _, _, err := c.client.Issues.CreateComment(ctx, owner, repo, number, comment); err != nil {
+		return c.redact(fmt.Errorf("failed to create comment: %w", err))
+	}
+
+	return nil
+}
+
+// CheckIfPRExists checks if a pull request already exists for the given head and base branches
+func (c *Client) CheckIfPRExists(ctx context.Context, owner, repo, head, base string) (*github.PullRequest, error) {
 	opts := &github.PullRequestListOptions{
 		State: "open",
 		Head:  fmt.Sprintf("%s:%s", owner, head),
-		Base:  "main",
+		Base:  base,
 	}
 
 	prs, err := c.ListPullRequests(ctx, owner, repo, opts)
@@ -83,4 +249,4 @@ func (c *Client) CheckIfPRExists(ctx context.Context, owner, repo, head string)
 	}
 
 	return nil, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,53 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v56/github"
+)
+
+func TestErrorsRedactToken(t *testing.T) {
+	const token = "ghp_s3cr3tT0k3n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"message": "Bad credentials: token ` + token + ` is invalid"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(token)
+	baseURL, err := url.Parse(server.URL + "/api/?access_token=" + token)
+	if err != nil {
+		t.Fatalf("failed to parse server URL: %v", err)
+	}
+	baseURL.User = url.UserPassword("bot", token)
+	client.client.BaseURL = baseURL
+
+	ctx := context.Background()
+	errs := []error{}
+	_, err = client.CreatePullRequest(ctx, "o", "r", "title", "body", "head", "main")
+	errs = append(errs, err)
+	_, err = client.UpdatePullRequest(ctx, "o", "r", 1, "title", "body")
+	errs = append(errs, err)
+	_, err = client.ListPullRequests(ctx, "o", "r", &github.PullRequestListOptions{})
+	errs = append(errs, err)
+	errs = append(errs, client.CreateComment(ctx, "o", "r", 1, "comment"))
+
+	for _, err := range errs {
+		if err == nil {
+			t.Fatal("Expected an error from the failing server")
+		}
+		if strings.Contains(err.Error(), token) {
+			t.Errorf("Expected token to be redacted, got %q", err.Error())
+		}
+		if !strings.Contains(err.Error(), "REDACTED") {
+			t.Errorf("Expected redaction placeholder in %q", err.Error())
+		}
+	}
+}
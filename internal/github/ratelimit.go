@@ -0,0 +1,110 @@
+package github
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRateLimitThreshold is the remaining request quota below which requests are slowed down
+	DefaultRateLimitThreshold = 100
+
+	// maxRateLimitDelay caps the delay introduced before a single request
+	maxRateLimitDelay = time.Minute
+)
+
+// RateLimitTransport is an http.RoundTripper that tracks the GitHub
+// X-RateLimit-Remaining and X-RateLimit-Reset response headers and spreads the
+// remaining requests over the time left until the reset once the quota runs low.
+type RateLimitTransport struct {
+	base      http.RoundTripper
+	threshold int
+
+	mu        sync.Mutex
+	known     bool
+	remaining int
+	reset     time.Time
+
+	sleep func(time.Duration)
+	now   func() time.Time
+}
+
+// NewRateLimitTransport wraps base with proactive rate limiting
+func NewRateLimitTransport(base http.RoundTripper, threshold int) *RateLimitTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &RateLimitTransport{
+		base:      base,
+		threshold: threshold,
+		sleep:     time.Sleep,
+		now:       time.Now,
+	}
+}
+
+// RoundTrip delays the request if the quota is running low and records the rate limit headers of the response
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if delay := t.delay(); delay > 0 {
+		t.sleep(delay)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.update(resp.Header)
+
+	return resp, nil
+}
+
+// delay returns how long to wait before the next request
+func (t *RateLimitTransport) delay() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.known || t.remaining >= t.threshold {
+		return 0
+	}
+
+	untilReset := t.reset.Sub(t.now())
+	if untilReset <= 0 {
+		return 0
+	}
+
+	delay := maxRateLimitDelay
+	if t.remaining > 0 {
+		delay = untilReset / time.Duration(t.remaining+1)
+	}
+	if delay > untilReset {
+		delay = untilReset
+	}
+	if delay > maxRateLimitDelay {
+		delay = maxRateLimitDelay
+	}
+
+	return delay
+}
+
+// update records the rate limit headers of a response
+func (t *RateLimitTransport) update(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.known = true
+	t.remaining = remaining
+	t.reset = time.Unix(reset, 0)
+}
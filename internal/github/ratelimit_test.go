@@ -0,0 +1,79 @@
+package github
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimitTransportDelaysAsQuotaDrops(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	reset := now.Add(10 * time.Minute)
+
+	remaining := []int{500, 200, 50, 9, 0, 0}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining[calls]))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		calls++
+	}))
+	defer server.Close()
+
+	transport := NewRateLimitTransport(http.DefaultTransport, DefaultRateLimitThreshold)
+	transport.now = func() time.Time { return now }
+	var delays []time.Duration
+	transport.sleep = func(d time.Duration) { delays = append(delays, d) }
+
+	client := &http.Client{Transport: transport}
+	for range remaining {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// No delay before the first request or while the quota stays above the threshold
+	expected := []time.Duration{
+		10 * time.Minute / 51,
+		time.Minute,
+		time.Minute,
+	}
+	if len(delays) != len(expected) {
+		t.Fatalf("Expected %d delays, got %v", len(expected), delays)
+	}
+	for i := range expected {
+		if delays[i] != expected[i] {
+			t.Errorf("Expected delay %d to be %v, got %v", i, expected[i], delays[i])
+		}
+	}
+}
+
+func TestRateLimitTransportNoDelayAfterReset(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "1")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(-time.Second).Unix(), 10))
+	}))
+	defer server.Close()
+
+	transport := NewRateLimitTransport(nil, DefaultRateLimitThreshold)
+	transport.now = func() time.Time { return now }
+	slept := false
+	transport.sleep = func(time.Duration) { slept = true }
+
+	client := &http.Client{Transport: transport}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if slept {
+		t.Errorf("Expected no delay once the reset time has passed")
+	}
+}
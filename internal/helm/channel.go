@@ -0,0 +1,107 @@
+package helm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/marccoxall/helmchecker/internal/config"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// ChannelAnnotation marks a chart version as belonging to a release channel,
+// overriding the channel implied by its version
+const ChannelAnnotation = "helmchecker.io/channel"
+
+// VersionComparator orders chart versions of a scheme other than semantic
+// versioning, such as date-based versions
+type VersionComparator interface {
+	// Validate returns an error if version is not valid in the scheme
+	Validate(version string) error
+	// Compare returns -1, 0 or 1 as a is older than, equal to or newer than b
+	Compare(a, b string) int
+}
+
+// VersionChannel returns the release channel a chart version belongs to: the
+// value of its channel annotation if set, otherwise edge for pre-releases and
+// stable for everything else
+func VersionChannel(cv *repo.ChartVersion) string {
+	return versionChannel(cv, nil)
+}
+
+// versionChannel returns the release channel of a chart version. Versions
+// ordered by a comparator have no pre-releases, so they are stable unless
+// annotated otherwise.
+func versionChannel(cv *repo.ChartVersion, comparator VersionComparator) string {
+	if channel := strings.TrimSpace(cv.Annotations[ChannelAnnotation]); channel != "" {
+		return strings.ToLower(channel)
+	}
+
+	if comparator != nil {
+		return config.ChannelStable
+	}
+	if v, err := semver.NewVersion(cv.Version); err == nil && v.Prerelease() != "" {
+		return config.ChannelEdge
+	}
+	return config.ChannelStable
+}
+
+// inChannel reports whether a chart version may be offered to a subscriber of
+// channel. The edge channel follows every version; the stable channel only
+// follows stable ones.
+func inChannel(cv *repo.ChartVersion, channel string, comparator VersionComparator) bool {
+	if channel == config.ChannelEdge {
+		return true
+	}
+	return versionChannel(cv, comparator) == config.ChannelStable
+}
+
+// excluded reports whether a version matches any of the exclude patterns,
+// such as nightly or canary tags
+func excluded(version string, exclude []*regexp.Regexp) bool {
+	for _, re := range exclude {
+		if re.MatchString(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// LatestInChannel returns the highest version of a chart in the index that
+// belongs to the given channel and matches none of the exclude patterns.
+// Versions are ordered by comparator, or as semantic versions if it is nil.
+func LatestInChannel(index *repo.IndexFile, chartName, channel string, exclude []*regexp.Regexp, comparator VersionComparator) (*ChartVersion, error) {
+	return latestInChannel(sortVersions(index.Entries[chartName], comparator), chartName, channel, exclude, comparator)
+}
+
+// latestInChannel returns the first of the sorted versions of a chart that
+// belongs to the given channel and matches none of the exclude patterns
+func latestInChannel(sorted repo.ChartVersions, chartName, channel string, exclude []*regexp.Regexp, comparator VersionComparator) (*ChartVersion, error) {
+	for _, cv := range sorted {
+		if !inChannel(cv, channel, comparator) || excluded(cv.Version, exclude) {
+			continue
+		}
+		return toChartVersion(cv), nil
+	}
+
+	return nil, fmt.Errorf("no %s version of chart %s found", channel, chartName)
+}
+
+// toChartVersion converts a repository index entry to a ChartVersion
+func toChartVersion(cv *repo.ChartVersion) *ChartVersion {
+	version := &ChartVersion{
+		Version:     cv.Version,
+		AppVersion:  cv.AppVersion,
+		Deprecated:  cv.Deprecated,
+		Annotations: cv.Annotations,
+		Digest:      cv.Digest,
+		Created:     cv.Created,
+		Home:        cv.Home,
+		Sources:     cv.Sources,
+	}
+	if len(cv.URLs) > 0 {
+		version.URL = cv.URLs[0]
+	}
+	return version
+}
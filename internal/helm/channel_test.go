@@ -0,0 +1,99 @@
+package helm
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/marccoxall/helmchecker/internal/config"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// channelIndex returns a repository index publishing both stable and edge releases
+func channelIndex() *repo.IndexFile {
+	version := func(v string, annotations map[string]string) *repo.ChartVersion {
+		return &repo.ChartVersion{Metadata: &chart.Metadata{Name: "app", Version: v, AppVersion: v, Annotations: annotations}}
+	}
+
+	index := repo.NewIndexFile()
+	index.Entries["app"] = repo.ChartVersions{
+		version("1.1.0", nil),
+		version("1.2.0", nil),
+		version("1.3.0-rc.1", nil),
+		version("1.4.0", map[string]string{ChannelAnnotation: "edge"}),
+	}
+	return index
+}
+
+func TestVersionChannel(t *testing.T) {
+	tests := []struct {
+		version     string
+		annotations map[string]string
+		expected    string
+	}{
+		{"1.2.0", nil, config.ChannelStable},
+		{"1.3.0-rc.1", nil, config.ChannelEdge},
+		{"1.4.0", map[string]string{ChannelAnnotation: "Edge"}, config.ChannelEdge},
+		{"1.5.0-beta.1", map[string]string{ChannelAnnotation: "stable"}, config.ChannelStable},
+	}
+
+	for _, tt := range tests {
+		cv := &repo.ChartVersion{Metadata: &chart.Metadata{Version: tt.version, Annotations: tt.annotations}}
+		if got := VersionChannel(cv); got != tt.expected {
+			t.Errorf("VersionChannel(%s) = %s, expected %s", tt.version, got, tt.expected)
+		}
+	}
+}
+
+func TestLatestInChannel(t *testing.T) {
+	index := channelIndex()
+
+	stable, err := LatestInChannel(index, "app", config.ChannelStable, nil, nil)
+	if err != nil {
+		t.Fatalf("LatestInChannel failed: %v", err)
+	}
+	if stable.Version != "1.2.0" {
+		t.Errorf("Expected stable latest 1.2.0, got %s", stable.Version)
+	}
+
+	edge, err := LatestInChannel(index, "app", config.ChannelEdge, nil, nil)
+	if err != nil {
+		t.Fatalf("LatestInChannel failed: %v", err)
+	}
+	if edge.Version != "1.4.0" {
+		t.Errorf("Expected edge latest 1.4.0, got %s", edge.Version)
+	}
+
+	if _, err := LatestInChannel(index, "missing", config.ChannelStable, nil, nil); err == nil {
+		t.Errorf("Expected error for a chart missing from the index")
+	}
+}
+
+func TestLatestInChannelExclude(t *testing.T) {
+	index := repo.NewIndexFile()
+	for _, v := range []string{"1.1.0", "1.2.0", "1.3.0-nightly.20240102", "2.0.0-canary.1"} {
+		index.Entries["app"] = append(index.Entries["app"], &repo.ChartVersion{Metadata: &chart.Metadata{Name: "app", Version: v}})
+	}
+	exclude := []*regexp.Regexp{regexp.MustCompile(`nightly`), regexp.MustCompile(`canary`)}
+
+	// Excluded versions sort highest, so the newest allowed version is offered
+	latest, err := LatestInChannel(index, "app", config.ChannelEdge, exclude, nil)
+	if err != nil {
+		t.Fatalf("LatestInChannel failed: %v", err)
+	}
+	if latest.Version != "1.2.0" {
+		t.Errorf("Expected 1.2.0 with nightly and canary excluded, got %s", latest.Version)
+	}
+
+	latest, err = LatestInChannel(index, "app", config.ChannelEdge, nil, nil)
+	if err != nil {
+		t.Fatalf("LatestInChannel failed: %v", err)
+	}
+	if latest.Version != "2.0.0-canary.1" {
+		t.Errorf("Expected 2.0.0-canary.1 without exclude patterns, got %s", latest.Version)
+	}
+
+	if _, err := LatestInChannel(index, "app", config.ChannelEdge, []*regexp.Regexp{regexp.MustCompile(`.`)}, nil); err == nil {
+		t.Errorf("Expected error when every version is excluded")
+	}
+}
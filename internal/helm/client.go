@@ -2,31 +2,70 @@ package helm
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
+	"github.com/marccoxall/helmchecker/internal/config"
+	"github.com/marccoxall/helmchecker/internal/redact"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/repo"
 )
 
+// ErrNoRepositories is returned when no Helm repositories are configured, so no
+// chart versions can be resolved
+var ErrNoRepositories = errors.New("no helm repositories configured")
+
+// ErrChartVersionNotFound is returned when a chart version is not in any
+// repository index
+var ErrChartVersionNotFound = errors.New("chart version not found")
+
+// ErrChartNotFound is returned when a chart is not in any repository index
+var ErrChartNotFound = errors.New("chart not found")
+
 // Client represents a Helm client
 type Client struct {
 	actionConfig *action.Configuration
 	settings     *cli.EnvSettings
 	namespace    string
+
+	repositoryTLS []config.RepositoryTLS
+	registries    []config.RegistryCredential
+	indexes       *IndexCache
+	diffContext   int
 }
 
 // Release represents an installed Helm release
 type Release struct {
-	Name      string
-	Namespace string
-	Chart     string
-	Version   string
-	AppVersion string
-	Repository string
+	Name         string
+	Namespace    string
+	Chart        string
+	Version      string
+	AppVersion   string
+	Repository   string
+	Sources      []string
+	Dependencies []*chart.Dependency
+	Maintainers  []*chart.Maintainer
+	Values       map[string]interface{}
+	Annotations  map[string]string
+	Labels       map[string]string
+
+	chart *chart.Chart
 }
 
 // ChartVersion represents a chart version from a repository
@@ -34,27 +73,42 @@ type ChartVersion struct {
 	Version    string
 	AppVersion string
 	Repository string
+	// URL is the chart archive's download URL
+	URL         string
+	Deprecated  bool
+	Annotations map[string]string
+	// Digest, Created, Home and Sources describe the version's repository
+	// index entry, when known
+	Digest  string
+	Created time.Time
+	Home    string
+	Sources []string
 }
 
-// NewClient creates a new Helm client
-func NewClient(namespace string) (*Client, error) {
+// NewClient creates a new Helm client using the repository TLS, registry and
+// index settings of cfg
+func NewClient(namespace string, cfg config.HelmConfig) (*Client, error) {
 	settings := cli.New()
-	
+
 	if namespace != "" {
 		settings.SetNamespace(namespace)
 	}
 
 	actionConfig := new(action.Configuration)
-	
+
 	// Initialize the action configuration
 	if err := actionConfig.Init(settings.RESTClientGetter(), settings.Namespace(), os.Getenv("HELM_DRIVER"), func(format string, v ...interface{}) {}); err != nil {
 		return nil, fmt.Errorf("failed to initialize Helm action configuration: %w", err)
 	}
 
 	return &Client{
-		actionConfig: actionConfig,
-		settings:     settings,
-		namespace:    namespace,
+		actionConfig:  actionConfig,
+		settings:      settings,
+		namespace:     namespace,
+		repositoryTLS: cfg.RepositoryTLS,
+		registries:    cfg.Registries,
+		indexes:       NewIndexCache(cfg.IndexConcurrency),
+		diffContext:   cfg.DiffContext,
 	}, nil
 }
 
@@ -71,11 +125,18 @@ func (c *Client) ListReleases(ctx context.Context) ([]*Release, error) {
 	var result []*Release
 	for _, rel := range releases {
 		release := &Release{
-			Name:       rel.Name,
-			Namespace:  rel.Namespace,
-			Chart:      rel.Chart.Metadata.Name,
-			Version:    rel.Chart.Metadata.Version,
-			AppVersion: rel.Chart.Metadata.AppVersion,
+			Name:         rel.Name,
+			Namespace:    rel.Namespace,
+			Chart:        rel.Chart.Metadata.Name,
+			Version:      rel.Chart.Metadata.Version,
+			AppVersion:   rel.Chart.Metadata.AppVersion,
+			Sources:      rel.Chart.Metadata.Sources,
+			Dependencies: rel.Chart.Metadata.Dependencies,
+			Maintainers:  rel.Chart.Metadata.Maintainers,
+			Values:       rel.Config,
+			Annotations:  rel.Chart.Metadata.Annotations,
+			Labels:       rel.Labels,
+			chart:        rel.Chart,
 		}
 
 		// Try to determine the repository
@@ -89,21 +150,189 @@ func (c *Client) ListReleases(ctx context.Context) ([]*Release, error) {
 	return result, nil
 }
 
-// GetLatestChartVersion gets the latest version of a chart from its repository
-func (c *Client) GetLatestChartVersion(ctx context.Context, chartName, repoURL string) (*ChartVersion, error) {
-	// For now, return the current version as latest
-	// This is a placeholder implementation that prevents the application from crashing
-	// In a real implementation, you would:
-	// 1. Search through configured helm repositories
-	// 2. Find the chart by name
-	// 3. Return the actual latest version
-	
-	// Return a higher version to simulate an update being available
-	return &ChartVersion{
-		Version:    "0.0.2", // Higher than the current 0.0.1
-		AppVersion: "0.0.2",
-		Repository: repoURL,
-	}, nil
+// GetLatestChartVersion gets the latest version of a chart in the given
+// release channel from the repository indexes cached by UpdateRepositories,
+// passing over versions that match any of the exclude patterns. Versions are
+// ordered by comparator, or as semantic versions if it is nil.
+// repoURL may be a repository's URL or configured name; when it matches no
+// repository, the highest version across all repositories is returned. Charts
+// in OCI registries (oci:// URLs) are looked up from the registry's tags.
+func (c *Client) GetLatestChartVersion(ctx context.Context, chartName, repoURL, channel string, exclude []*regexp.Regexp, comparator VersionComparator) (*ChartVersion, error) {
+	if registry.IsOCI(repoURL) {
+		return c.latestOCIChartVersion(ctx, strings.TrimSuffix(repoURL, "/")+"/"+chartName, channel, exclude, comparator)
+	}
+
+	entries, err := c.repositoriesFor(repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// A repository whose index can't be loaded is skipped so it doesn't
+	// fail the lookups of charts from every other repository
+	var latest *ChartVersion
+	var latestVersion *semver.Version
+	var loadErr error
+	found, loaded := false, 0
+	for _, entry := range entries {
+		if err := c.loadIndex(entry.Name); err != nil {
+			log.Printf("Warning: skipping repository %s: %v", entry.Name, err)
+			loadErr = err
+			continue
+		}
+		loaded++
+		if _, ok := c.indexes.Versions(entry.Name, chartName); !ok {
+			continue
+		}
+		found = true
+
+		cv, err := c.indexes.LatestInChannel(entry.Name, chartName, channel, exclude, comparator)
+		if err != nil {
+			continue
+		}
+		if comparator != nil {
+			if latest == nil || comparator.Compare(cv.Version, latest.Version) > 0 {
+				latest = fromRepository(cv, entry)
+			}
+			continue
+		}
+		v, err := semver.NewVersion(cv.Version)
+		if err != nil {
+			continue
+		}
+		if latest == nil || v.GreaterThan(latestVersion) {
+			latest, latestVersion = fromRepository(cv, entry), v
+		}
+	}
+
+	if loaded == 0 && loadErr != nil {
+		return nil, loadErr
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrChartNotFound, chartName)
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no %s version of chart %s found", channel, chartName)
+	}
+	return latest, nil
+}
+
+// GetChartVersion looks up a specific version of a chart in the cached
+// repository indexes, returning ErrChartVersionNotFound if no repository has it
+func (c *Client) GetChartVersion(ctx context.Context, chartName, repoURL, version string) (*ChartVersion, error) {
+	entries, err := c.repositoriesFor(repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var loadErr error
+	loaded := 0
+	for _, entry := range entries {
+		if err := c.loadIndex(entry.Name); err != nil {
+			log.Printf("Warning: skipping repository %s: %v", entry.Name, err)
+			loadErr = err
+			continue
+		}
+		loaded++
+		if cv, ok := c.indexes.Version(entry.Name, chartName, version); ok {
+			return fromRepository(cv, entry), nil
+		}
+	}
+
+	if loaded == 0 && loadErr != nil {
+		return nil, loadErr
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrChartVersionNotFound, chartName, version)
+}
+
+// repositoriesFor returns the configured repositories matching repoURL by URL
+// or name, or all of them if none match
+func (c *Client) repositoriesFor(repoURL string) ([]*repo.Entry, error) {
+	f, err := repo.LoadFile(c.settings.RepositoryConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load repository file: %w", err)
+	}
+	if len(f.Repositories) == 0 {
+		return nil, ErrNoRepositories
+	}
+
+	var matched []*repo.Entry
+	for _, entry := range f.Repositories {
+		if repoURL != "" && (entry.Name == repoURL || strings.TrimSuffix(entry.URL, "/") == strings.TrimSuffix(repoURL, "/")) {
+			matched = append(matched, entry)
+		}
+	}
+	if len(matched) > 0 {
+		return matched, nil
+	}
+	return f.Repositories, nil
+}
+
+// fromRepository records the repository a chart version was found in and
+// resolves its download URL, which indexes may give relative to the repository
+func fromRepository(cv *ChartVersion, entry *repo.Entry) *ChartVersion {
+	cv.Repository = entry.URL
+	if cv.URL != "" {
+		if resolved, err := repo.ResolveReferenceURL(entry.URL, cv.URL); err == nil {
+			cv.URL = resolved
+		}
+	}
+	return cv
+}
+
+// loadIndex loads a repository's cached index file into the index cache
+// unless it is already there
+func (c *Client) loadIndex(repoName string) error {
+	if c.indexes.Loaded(repoName) {
+		return nil
+	}
+
+	index, err := repo.LoadIndexFile(filepath.Join(c.settings.RepositoryCache, helmpath.CacheIndexFile(repoName)))
+	if err != nil {
+		return fmt.Errorf("failed to load index for repository %s: %w", repoName, err)
+	}
+	c.indexes.Load(repoName, index)
+	return nil
+}
+
+// LoadChart downloads (or reuses from cache) and loads a specific chart version
+func (c *Client) LoadChart(ctx context.Context, chartName, version, repoURL string) (*chart.Chart, error) {
+	pathOptions := action.ChartPathOptions{
+		RepoURL: repoURL,
+		Version: version,
+	}
+	if tls := c.tlsFor("", repoURL); tls != nil {
+		pathOptions.CaFile = tls.CAFile
+		pathOptions.CertFile = tls.CertFile
+		pathOptions.KeyFile = tls.KeyFile
+		pathOptions.InsecureSkipTLSverify = tls.InsecureSkipTLSVerify
+	}
+
+	chartPath, err := pathOptions.LocateChart(chartName, c.settings)
+	if err != nil {
+		return nil, redact.Error(fmt.Errorf("failed to locate chart %s %s: %w", chartName, version, err))
+	}
+
+	ch, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart %s %s: %w", chartName, version, err)
+	}
+
+	return ch, nil
+}
+
+// SimulateUpgrade renders the release's installed chart and the target chart
+// version with the release's values and diffs the resulting manifests
+func (c *Client) SimulateUpgrade(ctx context.Context, release *Release, targetVersion string) (*UpgradeSimulation, error) {
+	if release.chart == nil {
+		return nil, fmt.Errorf("installed chart for release %s is not available", release.Name)
+	}
+
+	target, err := c.LoadChart(ctx, release.Chart, targetVersion, release.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	return SimulateUpgrade(release.chart, target, release.Name, release.Namespace, release.Values, c.diffContext)
 }
 
 // AddRepository adds a Helm repository
@@ -115,6 +344,7 @@ func (c *Client) AddRepository(ctx context.Context, name, url string) error {
 		Name: name,
 		URL:  url,
 	}
+	c.applyRepositoryTLS(chartRepo)
 
 	// Create getter providers
 	providers := getter.All(c.settings)
@@ -127,7 +357,7 @@ func (c *Client) AddRepository(ctx context.Context, name, url string) error {
 
 	// Download the index file
 	if _, err := r.DownloadIndexFile(); err != nil {
-		return fmt.Errorf("failed to download repository index: %w", err)
+		return redact.Error(fmt.Errorf("failed to download repository index: %w", err))
 	}
 
 	// Load existing repositories
@@ -158,30 +388,93 @@ func (c *Client) UpdateRepositories(ctx context.Context) error {
 
 	f, err := repo.LoadFile(repoFile)
 	if err != nil {
-		// If file doesn't exist, create a new one
-		if os.IsNotExist(err) {
+		// If file doesn't exist, create a new one. Helm wraps the error, so
+		// os.IsNotExist can't see through it.
+		if errors.Is(err, fs.ErrNotExist) {
 			f = repo.NewFile()
 			if err := f.WriteFile(repoFile, 0644); err != nil {
 				return fmt.Errorf("failed to create repository file: %w", err)
 			}
-			return nil // No repositories to update yet
+			return ErrNoRepositories
 		}
 		return fmt.Errorf("failed to load repository file: %w", err)
 	}
 
+	if len(f.Repositories) == 0 {
+		return ErrNoRepositories
+	}
+
 	// Create getter providers
 	providers := getter.All(c.settings)
 
 	for _, cfg := range f.Repositories {
+		c.applyRepositoryTLS(cfg)
+
 		r, err := repo.NewChartRepository(cfg, providers)
 		if err != nil {
 			continue
 		}
+		r.CachePath = c.settings.RepositoryCache
 
 		if _, err := r.DownloadIndexFile(); err != nil {
-			return fmt.Errorf("failed to update repository %s: %w", cfg.Name, err)
+			return redact.Error(fmt.Errorf("failed to update repository %s: %w", cfg.Name, err), cfg.Password)
 		}
 	}
 
+	// Versions sorted from the previous indexes are stale
+	c.indexes.Reset()
+
 	return nil
-}
\ No newline at end of file
+}
+
+// IndexDigest returns a digest of the cached index files of all configured
+// repositories, which changes whenever a repository refresh brings in new charts
+func (c *Client) IndexDigest(ctx context.Context) (string, error) {
+	f, err := repo.LoadFile(c.settings.RepositoryConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to load repository file: %w", err)
+	}
+
+	hash := sha256.New()
+	for _, entry := range f.Repositories {
+		data, err := os.ReadFile(filepath.Join(c.settings.RepositoryCache, helmpath.CacheIndexFile(entry.Name)))
+		if err != nil {
+			return "", fmt.Errorf("failed to read index for repository %s: %w", entry.Name, err)
+		}
+		fmt.Fprintf(hash, "%s\n%d\n", entry.Name, len(data))
+		hash.Write(data)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// tlsFor returns the TLS settings for a repository, matched by name or by URL
+// prefix, or nil if none are configured
+func (c *Client) tlsFor(name, url string) *config.RepositoryTLS {
+	for i, tls := range c.repositoryTLS {
+		if (name != "" && tls.Repository == name) || (url != "" && strings.HasPrefix(url, tls.Repository)) {
+			return &c.repositoryTLS[i]
+		}
+	}
+	return nil
+}
+
+// applyRepositoryTLS sets the configured TLS settings on a repository entry,
+// keeping any already set in repositories.yaml
+func (c *Client) applyRepositoryTLS(entry *repo.Entry) {
+	tls := c.tlsFor(entry.Name, entry.URL)
+	if tls == nil {
+		return
+	}
+
+	if entry.CAFile == "" {
+		entry.CAFile = tls.CAFile
+	}
+	if entry.CertFile == "" && entry.KeyFile == "" {
+		entry.CertFile = tls.CertFile
+		entry.KeyFile = tls.KeyFile
+	}
+	if tls.InsecureSkipTLSVerify {
+		entry.InsecureSkipTLSverify = true
+	}
+}
@@ -0,0 +1,401 @@
+package helm
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marccoxall/helmchecker/internal/config"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+
+	settings := cli.New()
+	dir := t.TempDir()
+	settings.RepositoryConfig = filepath.Join(dir, "config", "repositories.yaml")
+	settings.RepositoryCache = filepath.Join(dir, "cache")
+
+	return &Client{settings: settings, indexes: NewIndexCache(0)}
+}
+
+func TestUpdateRepositoriesNoRepositories(t *testing.T) {
+	client := newTestClient(t)
+
+	// Missing repositories.yaml
+	err := client.UpdateRepositories(context.Background())
+	if !errors.Is(err, ErrNoRepositories) {
+		t.Fatalf("Expected ErrNoRepositories for missing repository file, got %v", err)
+	}
+
+	if _, err := os.Stat(client.settings.RepositoryConfig); err != nil {
+		t.Errorf("Expected repository file to be created: %v", err)
+	}
+
+	// Existing but empty repositories.yaml
+	err = client.UpdateRepositories(context.Background())
+	if !errors.Is(err, ErrNoRepositories) {
+		t.Errorf("Expected ErrNoRepositories for empty repository file, got %v", err)
+	}
+}
+
+// writeClientCertificate writes a self-signed client certificate and key to
+// dir and returns their paths along with the certificate
+func writeClientCertificate(t *testing.T, dir string) (string, string, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "helmchecker"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+
+	return certFile, keyFile, cert
+}
+
+func TestUpdateRepositoriesMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, clientCert := writeClientCertificate(t, dir)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/index.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "apiVersion: v1\nentries: {}\n")
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600); err != nil {
+		t.Fatalf("failed to write CA: %v", err)
+	}
+
+	client := newTestClient(t)
+	f := repo.NewFile()
+	f.Add(&repo.Entry{Name: "private", URL: server.URL})
+	if err := os.MkdirAll(filepath.Dir(client.settings.RepositoryConfig), 0755); err != nil {
+		t.Fatalf("failed to create config directory: %v", err)
+	}
+	if err := f.WriteFile(client.settings.RepositoryConfig, 0644); err != nil {
+		t.Fatalf("failed to write repository file: %v", err)
+	}
+
+	// Without a client certificate the server rejects the handshake
+	client.repositoryTLS = []config.RepositoryTLS{{Repository: "private", CAFile: caFile}}
+	if err := client.UpdateRepositories(context.Background()); err == nil {
+		t.Fatal("Expected update to fail without a client certificate")
+	}
+
+	client.repositoryTLS = []config.RepositoryTLS{{Repository: server.URL, CAFile: caFile, CertFile: certFile, KeyFile: keyFile}}
+	if err := client.UpdateRepositories(context.Background()); err != nil {
+		t.Fatalf("Expected update to succeed with a client certificate, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(client.settings.RepositoryCache, "private-index.yaml")); err != nil {
+		t.Errorf("Expected index to be cached: %v", err)
+	}
+}
+
+func TestIndexDigest(t *testing.T) {
+	client := newTestClient(t)
+
+	f := repo.NewFile()
+	f.Add(&repo.Entry{Name: "stable", URL: "https://charts.example.com"})
+	if err := os.MkdirAll(filepath.Dir(client.settings.RepositoryConfig), 0755); err != nil {
+		t.Fatalf("failed to create config directory: %v", err)
+	}
+	if err := f.WriteFile(client.settings.RepositoryConfig, 0644); err != nil {
+		t.Fatalf("failed to write repository file: %v", err)
+	}
+
+	if _, err := client.IndexDigest(context.Background()); err == nil {
+		t.Error("Expected an error for a repository without a cached index")
+	}
+
+	indexFile := filepath.Join(client.settings.RepositoryCache, "stable-index.yaml")
+	if err := os.MkdirAll(client.settings.RepositoryCache, 0755); err != nil {
+		t.Fatalf("failed to create cache directory: %v", err)
+	}
+	if err := os.WriteFile(indexFile, []byte("apiVersion: v1\nentries: {}\n"), 0644); err != nil {
+		t.Fatalf("failed to write index: %v", err)
+	}
+
+	first, err := client.IndexDigest(context.Background())
+	if err != nil {
+		t.Fatalf("IndexDigest failed: %v", err)
+	}
+	again, err := client.IndexDigest(context.Background())
+	if err != nil {
+		t.Fatalf("IndexDigest failed: %v", err)
+	}
+	if first != again {
+		t.Errorf("Expected a stable digest, got %s and %s", first, again)
+	}
+
+	if err := os.WriteFile(indexFile, []byte("apiVersion: v1\nentries:\n  nginx: []\n"), 0644); err != nil {
+		t.Fatalf("failed to write index: %v", err)
+	}
+	changed, err := client.IndexDigest(context.Background())
+	if err != nil {
+		t.Fatalf("IndexDigest failed: %v", err)
+	}
+	if changed == first {
+		t.Errorf("Expected the digest to change with the index")
+	}
+}
+
+// writeCachedIndex configures a single repository with a cached index file
+func writeCachedIndex(t *testing.T, client *Client, name, url, index string) {
+	t.Helper()
+
+	f := repo.NewFile()
+	f.Add(&repo.Entry{Name: name, URL: url})
+	if err := os.MkdirAll(filepath.Dir(client.settings.RepositoryConfig), 0755); err != nil {
+		t.Fatalf("failed to create config directory: %v", err)
+	}
+	if err := f.WriteFile(client.settings.RepositoryConfig, 0644); err != nil {
+		t.Fatalf("failed to write repository file: %v", err)
+	}
+
+	if err := os.MkdirAll(client.settings.RepositoryCache, 0755); err != nil {
+		t.Fatalf("failed to create cache directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(client.settings.RepositoryCache, name+"-index.yaml"), []byte(index), 0644); err != nil {
+		t.Fatalf("failed to write index: %v", err)
+	}
+}
+
+func TestGetChartVersion(t *testing.T) {
+	client := newTestClient(t)
+	writeCachedIndex(t, client, "bitnami", "https://charts.example.com", `apiVersion: v1
+entries:
+  redis:
+  - name: redis
+    version: 18.6.1
+    appVersion: 7.2.3
+  - name: redis
+    version: 19.0.0
+`)
+
+	cv, err := client.GetChartVersion(context.Background(), "redis", "", "18.6.1")
+	if err != nil {
+		t.Fatalf("GetChartVersion failed: %v", err)
+	}
+	if cv.Version != "18.6.1" || cv.AppVersion != "7.2.3" || cv.Repository != "https://charts.example.com" {
+		t.Errorf("Unexpected chart version %+v", cv)
+	}
+
+	_, err = client.GetChartVersion(context.Background(), "redis", "", "18.7.0")
+	if !errors.Is(err, ErrChartVersionNotFound) {
+		t.Errorf("Expected ErrChartVersionNotFound for a missing version, got %v", err)
+	}
+}
+
+func TestGetLatestChartVersion(t *testing.T) {
+	client := newTestClient(t)
+	writeCachedIndex(t, client, "bitnami", "https://charts.example.com/", `apiVersion: v1
+entries:
+  redis:
+  - name: redis
+    version: 18.6.1
+    appVersion: 7.2.3
+    urls:
+    - https://downloads.example.com/redis-18.6.1.tgz
+  - name: redis
+    version: 19.0.0
+    appVersion: 7.2.4
+    digest: 3b1c0e6f
+    created: "2024-03-01T10:30:00Z"
+    home: https://redis.io
+    sources:
+    - https://github.com/example/charts
+    urls:
+    - charts/redis-19.0.0.tgz
+  - name: redis
+    version: 19.1.0-rc.1
+    appVersion: 7.4.0
+`)
+
+	for _, repoURL := range []string{"https://charts.example.com", "bitnami", ""} {
+		cv, err := client.GetLatestChartVersion(context.Background(), "redis", repoURL, config.ChannelStable, nil, nil)
+		if err != nil {
+			t.Fatalf("GetLatestChartVersion(%q) failed: %v", repoURL, err)
+		}
+		if cv.Version != "19.0.0" || cv.AppVersion != "7.2.4" {
+			t.Errorf("Expected 19.0.0 (app 7.2.4) from %q, got %s (app %s)", repoURL, cv.Version, cv.AppVersion)
+		}
+		if cv.URL != "https://charts.example.com/charts/redis-19.0.0.tgz" {
+			t.Errorf("Expected the relative download URL to be resolved, got %s", cv.URL)
+		}
+		if cv.Digest != "3b1c0e6f" || !cv.Created.Equal(time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)) || cv.Home != "https://redis.io" ||
+			len(cv.Sources) != 1 || cv.Sources[0] != "https://github.com/example/charts" {
+			t.Errorf("Expected index entry metadata, got %+v", cv)
+		}
+	}
+
+	cv, err := client.GetLatestChartVersion(context.Background(), "redis", "bitnami", config.ChannelEdge, nil, nil)
+	if err != nil {
+		t.Fatalf("GetLatestChartVersion failed: %v", err)
+	}
+	if cv.Version != "19.1.0-rc.1" {
+		t.Errorf("Expected the edge channel to offer 19.1.0-rc.1, got %s", cv.Version)
+	}
+
+	_, err = client.GetLatestChartVersion(context.Background(), "nginx", "bitnami", config.ChannelStable, nil, nil)
+	if !errors.Is(err, ErrChartNotFound) {
+		t.Errorf("Expected ErrChartNotFound for a missing chart, got %v", err)
+	}
+}
+
+func TestGetLatestChartVersionSkipsBrokenRepository(t *testing.T) {
+	client := newTestClient(t)
+	writeCachedIndex(t, client, "bitnami", "https://charts.example.com", `apiVersion: v1
+entries:
+  redis:
+  - name: redis
+    version: 19.0.0
+`)
+
+	// A second repository whose index was never downloaded
+	f, err := repo.LoadFile(client.settings.RepositoryConfig)
+	if err != nil {
+		t.Fatalf("failed to load repository file: %v", err)
+	}
+	f.Add(&repo.Entry{Name: "broken", URL: "https://broken.example.com"})
+	if err := f.WriteFile(client.settings.RepositoryConfig, 0644); err != nil {
+		t.Fatalf("failed to write repository file: %v", err)
+	}
+
+	cv, err := client.GetLatestChartVersion(context.Background(), "redis", "", config.ChannelStable, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected the broken repository to be skipped, got %v", err)
+	}
+	if cv.Version != "19.0.0" {
+		t.Errorf("Expected 19.0.0, got %s", cv.Version)
+	}
+	if _, err := client.GetChartVersion(context.Background(), "redis", "", "19.0.0"); err != nil {
+		t.Errorf("Expected the broken repository to be skipped for pinned versions, got %v", err)
+	}
+
+	_, err = client.GetLatestChartVersion(context.Background(), "nginx", "", config.ChannelStable, nil, nil)
+	if !errors.Is(err, ErrChartNotFound) {
+		t.Errorf("Expected ErrChartNotFound for a missing chart, got %v", err)
+	}
+
+	// Without any loadable index the load error is returned
+	_, err = client.GetLatestChartVersion(context.Background(), "redis", "broken", config.ChannelStable, nil, nil)
+	if err == nil || errors.Is(err, ErrChartNotFound) {
+		t.Errorf("Expected the index load error, got %v", err)
+	}
+}
+
+// lexicalComparator orders fixed-width date versions such as 2024-01-02.1
+type lexicalComparator struct{}
+
+func (lexicalComparator) Validate(version string) error {
+	if len(version) < len("2006-01-02") {
+		return errors.New("not a date version")
+	}
+	return nil
+}
+
+func (lexicalComparator) Compare(a, b string) int {
+	return strings.Compare(a, b)
+}
+
+func TestGetLatestChartVersionWithComparator(t *testing.T) {
+	client := newTestClient(t)
+	writeCachedIndex(t, client, "feeds", "https://charts.example.com", `apiVersion: v1
+entries:
+  feed:
+  - name: feed
+    version: 2024-01-02.1
+  - name: feed
+    version: 2024-01-10
+  - name: feed
+    version: 2024-01-09.3
+  - name: feed
+    version: 2024-01-11
+    annotations:
+      helmchecker.io/channel: edge
+  - name: feed
+    version: "9"
+`)
+
+	// As semantic versions every date is a pre-release of 2024.0.0, so only 9 is stable
+	cv, err := client.GetLatestChartVersion(context.Background(), "feed", "feeds", config.ChannelStable, nil, nil)
+	if err != nil || cv.Version != "9" {
+		t.Errorf("Expected semantic versioning to resolve 9, got %v (err=%v)", cv, err)
+	}
+
+	cv, err = client.GetLatestChartVersion(context.Background(), "feed", "feeds", config.ChannelStable, nil, lexicalComparator{})
+	if err != nil {
+		t.Fatalf("GetLatestChartVersion failed: %v", err)
+	}
+	if cv.Version != "2024-01-10" {
+		t.Errorf("Expected the newest stable date version 2024-01-10, got %s", cv.Version)
+	}
+
+	cv, err = client.GetLatestChartVersion(context.Background(), "feed", "feeds", config.ChannelEdge, nil, lexicalComparator{})
+	if err != nil {
+		t.Fatalf("GetLatestChartVersion failed: %v", err)
+	}
+	if cv.Version != "2024-01-11" {
+		t.Errorf("Expected the annotated edge version 2024-01-11, got %s", cv.Version)
+	}
+}
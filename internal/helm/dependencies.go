@@ -0,0 +1,51 @@
+package helm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// exactVersionPattern matches a constraint naming a single full version, which
+// Helm resolves to exactly that version
+var exactVersionPattern = regexp.MustCompile(`^=?\s*v?\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// DependencyPin describes how a chart dependency's version is constrained
+type DependencyPin struct {
+	Name       string
+	Constraint string
+	Exact      bool
+	Suggestion string
+}
+
+// ClassifyDependencyPins reports, for each dependency, whether its version
+// constraint pins an exact version. Exact pins miss transitive security fixes,
+// so a patch-level range is suggested for them.
+func ClassifyDependencyPins(deps []*chart.Dependency) []DependencyPin {
+	var pins []DependencyPin
+	for _, dep := range deps {
+		if dep == nil {
+			continue
+		}
+
+		constraint := strings.TrimSpace(dep.Version)
+		pin := DependencyPin{
+			Name:       dep.Name,
+			Constraint: constraint,
+			Exact:      isExactConstraint(constraint),
+		}
+		if pin.Exact {
+			pin.Suggestion = fmt.Sprintf("~%s", strings.TrimSpace(strings.TrimPrefix(constraint, "=")))
+		}
+
+		pins = append(pins, pin)
+	}
+	return pins
+}
+
+// isExactConstraint reports whether a version constraint allows only one version
+func isExactConstraint(constraint string) bool {
+	return exactVersionPattern.MatchString(constraint)
+}
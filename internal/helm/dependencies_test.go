@@ -0,0 +1,51 @@
+package helm
+
+import (
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestClassifyDependencyPins(t *testing.T) {
+	deps := []*chart.Dependency{
+		{Name: "exact", Version: "1.2.3"},
+		{Name: "exact-eq", Version: "=1.2.3"},
+		{Name: "exact-pre", Version: "2.0.0-rc.1"},
+		{Name: "tilde", Version: "~1.2.0"},
+		{Name: "caret", Version: "^1.2.0"},
+		{Name: "partial", Version: "1.2"},
+		{Name: "wildcard", Version: "1.2.x"},
+		{Name: "range", Version: ">=1.0.0, <2.0.0"},
+		{Name: "unset", Version: ""},
+	}
+
+	expected := map[string]bool{
+		"exact":     true,
+		"exact-eq":  true,
+		"exact-pre": true,
+		"tilde":     false,
+		"caret":     false,
+		"partial":   false,
+		"wildcard":  false,
+		"range":     false,
+		"unset":     false,
+	}
+
+	pins := ClassifyDependencyPins(deps)
+	if len(pins) != len(deps) {
+		t.Fatalf("Expected %d pins, got %d", len(deps), len(pins))
+	}
+
+	for _, pin := range pins {
+		if pin.Exact != expected[pin.Name] {
+			t.Errorf("Dependency %s (%q): expected exact=%v, got %v", pin.Name, pin.Constraint, expected[pin.Name], pin.Exact)
+		}
+		if pin.Exact && pin.Suggestion == "" {
+			t.Errorf("Dependency %s: expected a loosening suggestion", pin.Name)
+		}
+	}
+
+	if pins[1].Suggestion != "~1.2.3" {
+		t.Errorf("Expected suggestion '~1.2.3', got '%s'", pins[1].Suggestion)
+	}
+}
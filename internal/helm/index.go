@@ -0,0 +1,176 @@
+package helm
+
+import (
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// IndexCache holds the parsed and sorted versions of each chart in the
+// repository indexes, so large indexes are only processed once per run
+type IndexCache struct {
+	concurrency int
+
+	mu       sync.Mutex
+	versions map[string]repo.ChartVersions
+	entries  map[string]repo.ChartVersions
+	loaded   map[string]bool
+}
+
+// NewIndexCache creates a cache sorting up to concurrency charts at once;
+// zero or less uses one worker per CPU
+func NewIndexCache(concurrency int) *IndexCache {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	return &IndexCache{
+		concurrency: concurrency,
+		versions:    make(map[string]repo.ChartVersions),
+		entries:     make(map[string]repo.ChartVersions),
+		loaded:      make(map[string]bool),
+	}
+}
+
+// Load parses and sorts the versions of every chart in a repository's index
+// concurrently, replacing anything cached for that repository
+func (c *IndexCache) Load(repoName string, index *repo.IndexFile) {
+	charts := make([]string, 0, len(index.Entries))
+	for chartName := range index.Entries {
+		charts = append(charts, chartName)
+	}
+
+	sorted := make([]repo.ChartVersions, len(charts))
+	sem := make(chan struct{}, c.concurrency)
+	var wg sync.WaitGroup
+	for i, chartName := range charts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, versions repo.ChartVersions) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			sorted[i] = sortVersions(versions, nil)
+		}(i, index.Entries[chartName])
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, chartName := range charts {
+		c.versions[indexCacheKey(repoName, chartName)] = sorted[i]
+		c.entries[indexCacheKey(repoName, chartName)] = index.Entries[chartName]
+	}
+	c.loaded[repoName] = true
+}
+
+// Loaded reports whether a repository's index is in the cache
+func (c *IndexCache) Loaded(repoName string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.loaded[repoName]
+}
+
+// Versions returns the versions of a chart in a repository, newest first, and
+// whether the chart is in the cache
+func (c *IndexCache) Versions(repoName, chartName string) (repo.ChartVersions, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	versions, ok := c.versions[indexCacheKey(repoName, chartName)]
+	return versions, ok
+}
+
+// Reset empties the cache, e.g. after the indexes were refreshed
+func (c *IndexCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.versions = make(map[string]repo.ChartVersions)
+	c.entries = make(map[string]repo.ChartVersions)
+	c.loaded = make(map[string]bool)
+}
+
+// indexCacheKey identifies a chart of a repository in the cache
+func indexCacheKey(repoName, chartName string) string {
+	return repoName + "/" + chartName
+}
+
+// sortVersions returns the versions that are valid in the comparator's scheme,
+// newest first. Without a comparator, versions are semantic versions and each
+// is parsed once rather than on every comparison.
+func sortVersions(versions repo.ChartVersions, comparator VersionComparator) repo.ChartVersions {
+	if comparator != nil {
+		var sorted repo.ChartVersions
+		for _, cv := range versions {
+			if comparator.Validate(cv.Version) == nil {
+				sorted = append(sorted, cv)
+			}
+		}
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return comparator.Compare(sorted[i].Version, sorted[j].Version) > 0
+		})
+		return sorted
+	}
+
+	type parsedVersion struct {
+		cv *repo.ChartVersion
+		v  *semver.Version
+	}
+
+	parsed := make([]parsedVersion, 0, len(versions))
+	for _, cv := range versions {
+		v, err := semver.NewVersion(cv.Version)
+		if err != nil {
+			continue
+		}
+		parsed = append(parsed, parsedVersion{cv: cv, v: v})
+	}
+
+	sort.SliceStable(parsed, func(i, j int) bool {
+		return parsed[i].v.GreaterThan(parsed[j].v)
+	})
+
+	sorted := make(repo.ChartVersions, len(parsed))
+	for i, p := range parsed {
+		sorted[i] = p.cv
+	}
+	return sorted
+}
+
+// LatestInChannel returns the highest cached version of a chart in a
+// repository that belongs to the given channel and matches none of the
+// exclude patterns. A comparator orders versions of another scheme than
+// semantic versioning, which the cache is sorted by.
+func (c *IndexCache) LatestInChannel(repoName, chartName, channel string, exclude []*regexp.Regexp, comparator VersionComparator) (*ChartVersion, error) {
+	if comparator != nil {
+		c.mu.Lock()
+		entries := c.entries[indexCacheKey(repoName, chartName)]
+		c.mu.Unlock()
+		return latestInChannel(sortVersions(entries, comparator), chartName, channel, exclude, comparator)
+	}
+
+	versions, _ := c.Versions(repoName, chartName)
+	return latestInChannel(versions, chartName, channel, exclude, nil)
+}
+
+// Version returns a specific cached version of a chart in a repository and
+// whether it exists. Versions are compared semantically, so 1.0 matches 1.0.0.
+func (c *IndexCache) Version(repoName, chartName, version string) (*ChartVersion, bool) {
+	want, err := semver.NewVersion(strings.TrimSpace(version))
+	if err != nil {
+		return nil, false
+	}
+
+	versions, _ := c.Versions(repoName, chartName)
+	for _, cv := range versions {
+		if v, err := semver.NewVersion(cv.Version); err == nil && v.Equal(want) {
+			return toChartVersion(cv), true
+		}
+	}
+	return nil, false
+}
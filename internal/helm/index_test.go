@@ -0,0 +1,123 @@
+package helm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/marccoxall/helmchecker/internal/config"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// largeIndex returns an index of charts with many unordered versions each
+func largeIndex(charts, versions int) *repo.IndexFile {
+	index := repo.NewIndexFile()
+	for c := 0; c < charts; c++ {
+		name := fmt.Sprintf("chart-%03d", c)
+		for v := 0; v < versions; v++ {
+			// Interleave majors so the input is not already sorted
+			version := fmt.Sprintf("%d.%d.0", v%7, v)
+			index.Entries[name] = append(index.Entries[name], &repo.ChartVersion{Metadata: &chart.Metadata{Name: name, Version: version}})
+		}
+	}
+	return index
+}
+
+func TestIndexCacheSortsVersions(t *testing.T) {
+	index := repo.NewIndexFile()
+	for _, version := range []string{"1.9.0", "not-a-version", "1.10.0", "1.10.0-rc.1", "0.5.0"} {
+		index.Entries["app"] = append(index.Entries["app"], &repo.ChartVersion{Metadata: &chart.Metadata{Name: "app", Version: version}})
+	}
+
+	cache := NewIndexCache(2)
+	cache.Load("stable", index)
+
+	versions, ok := cache.Versions("stable", "app")
+	if !ok {
+		t.Fatal("Expected app to be cached")
+	}
+	var got []string
+	for _, cv := range versions {
+		got = append(got, cv.Version)
+	}
+	if strings.Join(got, ",") != "1.10.0,1.10.0-rc.1,1.9.0,0.5.0" {
+		t.Errorf("Unexpected sorted versions %v", got)
+	}
+
+	if _, ok := cache.Versions("other", "app"); ok {
+		t.Errorf("Expected charts to be cached per repository")
+	}
+
+	latest, err := cache.LatestInChannel("stable", "app", config.ChannelStable, nil, nil)
+	if err != nil || latest.Version != "1.10.0" {
+		t.Errorf("Expected stable latest 1.10.0, got %v (err=%v)", latest, err)
+	}
+
+	cache.Reset()
+	if _, ok := cache.Versions("stable", "app"); ok {
+		t.Errorf("Expected Reset to empty the cache")
+	}
+}
+
+func TestIndexCacheMatchesSerialSort(t *testing.T) {
+	index := largeIndex(50, 40)
+
+	cache := NewIndexCache(8)
+	cache.Load("stable", index)
+
+	for chartName, versions := range index.Entries {
+		expected := sortVersions(versions, nil)
+		cached, _ := cache.Versions("stable", chartName)
+		if len(cached) != len(expected) {
+			t.Fatalf("Expected %d versions of %s, got %d", len(expected), chartName, len(cached))
+		}
+		for i := range expected {
+			if cached[i] != expected[i] {
+				t.Errorf("%s: version %d is %s, expected %s", chartName, i, cached[i].Version, expected[i].Version)
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkIndexCacheLoad(b *testing.B) {
+	index := largeIndex(500, 200)
+
+	for _, concurrency := range []int{1, 0} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				NewIndexCache(concurrency).Load("stable", index)
+			}
+		})
+	}
+}
+
+func TestIndexCacheVersion(t *testing.T) {
+	index := repo.NewIndexFile()
+	for _, version := range []string{"1.0.0", "1.1.0"} {
+		index.Entries["app"] = append(index.Entries["app"], &repo.ChartVersion{Metadata: &chart.Metadata{Name: "app", Version: version, AppVersion: "v" + version}})
+	}
+
+	cache := NewIndexCache(0)
+	if cache.Loaded("stable") {
+		t.Errorf("Expected an empty cache to have nothing loaded")
+	}
+	cache.Load("stable", index)
+	if !cache.Loaded("stable") {
+		t.Errorf("Expected stable to be loaded")
+	}
+
+	cv, ok := cache.Version("stable", "app", "1.0")
+	if !ok || cv.Version != "1.0.0" || cv.AppVersion != "v1.0.0" {
+		t.Errorf("Expected 1.0 to match 1.0.0, got %+v", cv)
+	}
+	if _, ok := cache.Version("stable", "app", "2.0.0"); ok {
+		t.Errorf("Expected 2.0.0 not to be found")
+	}
+
+	cache.Reset()
+	if cache.Loaded("stable") {
+		t.Errorf("Expected Reset to forget loaded repositories")
+	}
+}
@@ -0,0 +1,147 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/marccoxall/helmchecker/internal/config"
+	"github.com/marccoxall/helmchecker/internal/redact"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/errcode"
+)
+
+// ErrTagListingUnsupported is returned when an OCI registry does not support
+// listing the tags of a repository, so the latest version cannot be found
+var ErrTagListingUnsupported = errors.New("registry does not support listing tags")
+
+// GetLatestOCIChartVersion returns the newest semantic version tag of a chart
+// in an OCI registry, e.g. oci://ghcr.io/org/charts/mychart
+func (c *Client) GetLatestOCIChartVersion(ctx context.Context, ref string) (*ChartVersion, error) {
+	return c.latestOCIChartVersion(ctx, ref, config.ChannelEdge, nil, nil)
+}
+
+// latestOCIChartVersion returns the newest tag of a chart in an OCI registry
+// that belongs to the given channel and matches none of the exclude patterns,
+// ordering tags by comparator if set
+func (c *Client) latestOCIChartVersion(ctx context.Context, ref, channel string, exclude []*regexp.Regexp, comparator VersionComparator) (*ChartVersion, error) {
+	ref = strings.TrimPrefix(ref, registry.OCIScheme+"://")
+
+	// The chart's repository is the reference up to its last path segment
+	slash := strings.LastIndex(ref, "/")
+	if slash <= 0 || slash == len(ref)-1 {
+		return nil, fmt.Errorf("failed to parse OCI chart reference %s: expected oci://<registry>/<chart>", ref)
+	}
+	credential := c.registryCredentialFor(ref)
+
+	client, err := c.newRegistryClient(credential)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry client: %w", err)
+	}
+
+	// Tags are semantic versions only, newest first
+	tags, err := client.Tags(ref)
+	if err != nil {
+		if tagListingUnsupported(err) {
+			return nil, fmt.Errorf("%w: %s", ErrTagListingUnsupported, ref)
+		}
+		if credential != nil {
+			err = redact.Error(err, credential.Password)
+		}
+		return nil, fmt.Errorf("failed to list tags of %s: %w", ref, err)
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrChartNotFound, ref)
+	}
+
+	versions := make(repo.ChartVersions, len(tags))
+	for i, tag := range tags {
+		versions[i] = &repo.ChartVersion{Metadata: &chart.Metadata{Version: tag}}
+	}
+	if comparator != nil {
+		versions = sortVersions(versions, comparator)
+	}
+
+	for _, cv := range versions {
+		tag := cv.Version
+		if !inChannel(cv, channel, comparator) || excluded(tag, exclude) {
+			continue
+		}
+		return &ChartVersion{
+			Version:    tag,
+			Repository: registry.OCIScheme + "://" + ref[:slash],
+			URL:        registry.OCIScheme + "://" + ref + ":" + tag,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no %s version of chart %s found", channel, ref)
+}
+
+// newRegistryClient creates an OCI registry client. Explicit credentials take
+// precedence; otherwise the Helm registry config and Docker config are used.
+func (c *Client) newRegistryClient(credential *config.RegistryCredential) (*registry.Client, error) {
+	options := []registry.ClientOption{
+		registry.ClientOptCredentialsFile(c.settings.RegistryConfig),
+		registry.ClientOptEnableCache(true),
+	}
+
+	if credential != nil {
+		if credential.PlainHTTP {
+			options = append(options, registry.ClientOptPlainHTTP())
+		}
+		if credential.Username != "" {
+			options = append(options, registry.ClientOptAuthorizer(auth.Client{
+				Client: http.DefaultClient,
+				Cache:  auth.NewCache(),
+				Credential: auth.StaticCredential(credential.Host, auth.Credential{
+					Username: credential.Username,
+					Password: credential.Password,
+				}),
+			}))
+		}
+	}
+
+	return registry.NewClient(options...)
+}
+
+// registryCredentialFor returns the configured credential for the registry
+// host of ref, or nil if none is configured
+func (c *Client) registryCredentialFor(ref string) *config.RegistryCredential {
+	host := ref
+	if i := strings.Index(ref, "/"); i >= 0 {
+		host = ref[:i]
+	}
+
+	for i, credential := range c.registries {
+		if strings.EqualFold(credential.Host, host) {
+			return &c.registries[i]
+		}
+	}
+	return nil
+}
+
+// tagListingUnsupported reports whether a registry error means the tag list
+// API is not available
+func tagListingUnsupported(err error) bool {
+	var response *errcode.ErrorResponse
+	if !errors.As(err, &response) {
+		return false
+	}
+
+	switch response.StatusCode {
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	}
+	for _, e := range response.Errors {
+		if e.Code == errcode.ErrorCodeUnsupported {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/marccoxall/helmchecker/internal/config"
+)
+
+// newTestRegistry serves the OCI tag list API for a chart with a few tags and
+// for a repository whose registry refuses tag listing
+func newTestRegistry(t *testing.T) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/org/charts/app/tags/list":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name": "org/charts/app", "tags": ["1.0.0", "latest", "1.2.0-rc.1", "1.1.0"]}`))
+		case "/v2/org/charts/legacy/tags/list":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			_, _ = w.Write([]byte(`{"errors": [{"code": "UNSUPPORTED", "message": "tag listing is disabled"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func newTestOCIClient(t *testing.T, host string) *Client {
+	t.Helper()
+
+	client := newTestClient(t)
+	client.settings.RegistryConfig = filepath.Join(t.TempDir(), "registry", "config.json")
+	client.registries = []config.RegistryCredential{{Host: host, PlainHTTP: true}}
+	return client
+}
+
+func TestGetLatestOCIChartVersion(t *testing.T) {
+	host := newTestRegistry(t)
+	client := newTestOCIClient(t, host)
+
+	cv, err := client.GetLatestOCIChartVersion(context.Background(), "oci://"+host+"/org/charts/app")
+	if err != nil {
+		t.Fatalf("GetLatestOCIChartVersion failed: %v", err)
+	}
+	if cv.Version != "1.2.0-rc.1" {
+		t.Errorf("Expected the newest tag 1.2.0-rc.1, got %s", cv.Version)
+	}
+
+	// Charts with oci:// repositories resolve through the registry and follow channels
+	cv, err = client.GetLatestChartVersion(context.Background(), "app", "oci://"+host+"/org/charts", config.ChannelStable, nil, nil)
+	if err != nil {
+		t.Fatalf("GetLatestChartVersion failed: %v", err)
+	}
+	if cv.Version != "1.1.0" {
+		t.Errorf("Expected the newest stable tag 1.1.0, got %s", cv.Version)
+	}
+	if cv.Repository != "oci://"+host+"/org/charts" || cv.URL != "oci://"+host+"/org/charts/app:1.1.0" {
+		t.Errorf("Unexpected repository %s or URL %s", cv.Repository, cv.URL)
+	}
+
+	// Excluded tags are passed over even when they are the newest
+	exclude := []*regexp.Regexp{regexp.MustCompile(`-rc\.`)}
+	cv, err = client.GetLatestChartVersion(context.Background(), "app", "oci://"+host+"/org/charts", config.ChannelEdge, exclude, nil)
+	if err != nil {
+		t.Fatalf("GetLatestChartVersion failed: %v", err)
+	}
+	if cv.Version != "1.1.0" {
+		t.Errorf("Expected the newest tag not excluded 1.1.0, got %s", cv.Version)
+	}
+}
+
+func TestGetLatestOCIChartVersionUnsupported(t *testing.T) {
+	host := newTestRegistry(t)
+	client := newTestOCIClient(t, host)
+
+	_, err := client.GetLatestOCIChartVersion(context.Background(), "oci://"+host+"/org/charts/legacy")
+	if !errors.Is(err, ErrTagListingUnsupported) {
+		t.Errorf("Expected ErrTagListingUnsupported, got %v", err)
+	}
+}
+
+func TestGetLatestOCIChartVersionInvalidReference(t *testing.T) {
+	client := newTestOCIClient(t, "registry.example.com")
+
+	for _, ref := range []string{"oci://mychart", "oci:///mychart", "oci://registry.example.com/"} {
+		_, err := client.GetLatestOCIChartVersion(context.Background(), ref)
+		if err == nil || !strings.Contains(err.Error(), "failed to parse OCI chart reference") {
+			t.Errorf("Expected a parse error for %s, got %v", ref, err)
+		}
+	}
+}
+
+func TestRegistryCredentialFor(t *testing.T) {
+	client := &Client{registries: []config.RegistryCredential{
+		{Host: "ghcr.io", Username: "bot", Password: "secret"},
+		{Host: "registry.example.com:5000"},
+	}}
+
+	if credential := client.registryCredentialFor("GHCR.io/org/charts/app"); credential == nil || credential.Username != "bot" {
+		t.Errorf("Expected the ghcr.io credential, got %+v", credential)
+	}
+	if credential := client.registryCredentialFor("registry.example.com:5000/app"); credential == nil || credential.Host != "registry.example.com:5000" {
+		t.Errorf("Expected the registry.example.com:5000 credential, got %+v", credential)
+	}
+	if credential := client.registryCredentialFor("docker.io/library/app"); credential != nil {
+		t.Errorf("Expected no credential for docker.io, got %+v", credential)
+	}
+}
@@ -0,0 +1,201 @@
+package helm
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/marccoxall/helmchecker/internal/diff"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"sigs.k8s.io/yaml"
+)
+
+// Manifest change actions
+const (
+	ManifestAdded   = "added"
+	ManifestRemoved = "removed"
+	ManifestChanged = "changed"
+)
+
+// ManifestChange describes how a single Kubernetes resource changes between
+// two rendered chart versions
+type ManifestChange struct {
+	Resource string
+	Action   string
+	Diff     string
+}
+
+// UpgradeSimulation holds the resource-level result of rendering the current
+// and target chart versions with the same values
+type UpgradeSimulation struct {
+	CurrentVersion  string
+	TargetVersion   string
+	Changes         []ManifestChange
+	TargetManifests map[string]string
+	// CRDs lists the resource keys of custom resource definitions shipped by
+	// the target version, which Helm does not upgrade
+	CRDs []string
+	// RemovedValues lists the dotted paths of release values that the target
+	// version no longer defines
+	RemovedValues []string
+}
+
+// TargetObjects decodes the target version's rendered manifests, ordered by
+// resource key, for consumers such as policy engines
+func (s *UpgradeSimulation) TargetObjects() ([]map[string]interface{}, error) {
+	keys := make([]string, 0, len(s.TargetManifests))
+	for key := range s.TargetManifests {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	objects := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		var object map[string]interface{}
+		if err := yaml.Unmarshal([]byte(s.TargetManifests[key]), &object); err != nil {
+			return nil, fmt.Errorf("failed to decode manifest %s: %w", key, err)
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// resourceHeader is the subset of a manifest used to identify the resource
+type resourceHeader struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+// RenderManifests renders a chart the way `helm template` does and returns the
+// resulting manifests keyed by kind/namespace/name
+func RenderManifests(ch *chart.Chart, releaseName, namespace string, values map[string]interface{}) (map[string]string, error) {
+	options := chartutil.ReleaseOptions{
+		Name:      releaseName,
+		Namespace: namespace,
+		Revision:  1,
+		IsInstall: true,
+	}
+
+	renderValues, err := chartutil.ToRenderValues(ch, values, options, chartutil.DefaultCapabilities.Copy())
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare values: %w", err)
+	}
+
+	rendered, err := engine.Render(ch, renderValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render templates: %w", err)
+	}
+
+	for _, crd := range ch.CRDObjects() {
+		rendered[crd.Filename] = string(crd.File.Data)
+	}
+
+	manifests := make(map[string]string)
+	for name, content := range rendered {
+		base := path.Base(name)
+		if strings.HasPrefix(base, "_") || strings.HasSuffix(base, "NOTES.txt") {
+			continue
+		}
+
+		for _, doc := range releaseutil.SplitManifests(content) {
+			var header resourceHeader
+			if err := yaml.Unmarshal([]byte(doc), &header); err != nil {
+				return nil, fmt.Errorf("failed to parse manifest from %s: %w", name, err)
+			}
+			if header.Kind == "" {
+				continue
+			}
+
+			key := resourceKey(header, namespace)
+			manifests[key] = strings.TrimSpace(doc) + "\n"
+		}
+	}
+
+	return manifests, nil
+}
+
+// DiffManifests compares two sets of rendered manifests, returning the changed
+// resources sorted by resource key with context lines around each change
+func DiffManifests(current, target map[string]string, context int) []ManifestChange {
+	var changes []ManifestChange
+	for key, oldManifest := range current {
+		newManifest, ok := target[key]
+		switch {
+		case !ok:
+			changes = append(changes, ManifestChange{Resource: key, Action: ManifestRemoved, Diff: diff.Unified(key, "/dev/null", oldManifest, "", context)})
+		case newManifest != oldManifest:
+			changes = append(changes, ManifestChange{Resource: key, Action: ManifestChanged, Diff: diff.Unified(key, key, oldManifest, newManifest, context)})
+		}
+	}
+	for key, newManifest := range target {
+		if _, ok := current[key]; !ok {
+			changes = append(changes, ManifestChange{Resource: key, Action: ManifestAdded, Diff: diff.Unified("/dev/null", key, "", newManifest, context)})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Resource < changes[j].Resource
+	})
+	return changes
+}
+
+// SimulateUpgrade renders the current and target charts with the same values
+// and returns the resource-level differences between them, with diffContext
+// unchanged lines around each change
+func SimulateUpgrade(current, target *chart.Chart, releaseName, namespace string, values map[string]interface{}, diffContext int) (*UpgradeSimulation, error) {
+	currentManifests, err := RenderManifests(current, releaseName, namespace, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s %s: %w", current.Name(), current.Metadata.Version, err)
+	}
+
+	targetManifests, err := RenderManifests(target, releaseName, namespace, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s %s: %w", target.Name(), target.Metadata.Version, err)
+	}
+
+	return &UpgradeSimulation{
+		CurrentVersion:  current.Metadata.Version,
+		TargetVersion:   target.Metadata.Version,
+		Changes:         DiffManifests(currentManifests, targetManifests, diffContext),
+		TargetManifests: targetManifests,
+		CRDs:            findCRDs(targetManifests),
+		RemovedValues:   RemovedValueKeys(target, values),
+	}, nil
+}
+
+// findCRDs returns the sorted keys of the custom resource definitions among
+// rendered manifests, whether shipped in crds/ or as templates
+func findCRDs(manifests map[string]string) []string {
+	var crds []string
+	for key, manifest := range manifests {
+		var header resourceHeader
+		if err := yaml.Unmarshal([]byte(manifest), &header); err != nil {
+			continue
+		}
+		if header.Kind == "CustomResourceDefinition" && strings.HasPrefix(header.APIVersion, "apiextensions.k8s.io/") {
+			crds = append(crds, key)
+		}
+	}
+	sort.Strings(crds)
+	return crds
+}
+
+// resourceKey identifies a resource by kind, namespace and name
+func resourceKey(header resourceHeader, defaultNamespace string) string {
+	namespace := header.Metadata.Namespace
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	if namespace == "" {
+		return fmt.Sprintf("%s/%s", header.Kind, header.Metadata.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", header.Kind, namespace, header.Metadata.Name)
+}
@@ -0,0 +1,162 @@
+package helm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/marccoxall/helmchecker/internal/diff"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func fixtureChart(version string, templates map[string]string) *chart.Chart {
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion: chart.APIVersionV2,
+			Name:       "fixture",
+			Version:    version,
+		},
+		Values: map[string]interface{}{
+			"replicas": 1,
+		},
+	}
+	for name, data := range templates {
+		ch.Templates = append(ch.Templates, &chart.File{Name: name, Data: []byte(data)})
+	}
+	return ch
+}
+
+const configMapTemplate = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}-config
+data:
+  version: %s
+  replicas: "{{ .Values.replicas }}"
+`
+
+func TestSimulateUpgrade(t *testing.T) {
+	current := fixtureChart("1.0.0", map[string]string{
+		"templates/configmap.yaml": strings.Replace(configMapTemplate, "%s", "one", 1),
+		"templates/secret.yaml": `apiVersion: v1
+kind: Secret
+metadata:
+  name: {{ .Release.Name }}-legacy
+`,
+		"templates/_helpers.tpl": `{{- define "fixture.name" -}}fixture{{- end -}}`,
+		"templates/NOTES.txt":    "Installed {{ .Release.Name }}",
+	})
+	target := fixtureChart("2.0.0", map[string]string{
+		"templates/configmap.yaml": strings.Replace(configMapTemplate, "%s", "two", 1),
+		"templates/service.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: {{ .Release.Name }}
+`,
+	})
+
+	values := map[string]interface{}{"replicas": 3}
+	simulation, err := SimulateUpgrade(current, target, "demo", "apps", values, diff.DefaultContext)
+	if err != nil {
+		t.Fatalf("Failed to simulate upgrade: %v", err)
+	}
+
+	if simulation.CurrentVersion != "1.0.0" || simulation.TargetVersion != "2.0.0" {
+		t.Errorf("Unexpected versions: %s -> %s", simulation.CurrentVersion, simulation.TargetVersion)
+	}
+
+	expected := []struct {
+		resource string
+		action   string
+	}{
+		{"ConfigMap/apps/demo-config", ManifestChanged},
+		{"Secret/apps/demo-legacy", ManifestRemoved},
+		{"Service/apps/demo", ManifestAdded},
+	}
+
+	if len(simulation.Changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %d: %+v", len(expected), len(simulation.Changes), simulation.Changes)
+	}
+
+	for i, want := range expected {
+		got := simulation.Changes[i]
+		if got.Resource != want.resource || got.Action != want.action {
+			t.Errorf("Change %d: expected %s %s, got %s %s", i, want.resource, want.action, got.Resource, got.Action)
+		}
+	}
+
+	configDiff := simulation.Changes[0].Diff
+	if !strings.Contains(configDiff, "-  version: one") || !strings.Contains(configDiff, "+  version: two") {
+		t.Errorf("Expected version change in diff, got:\n%s", configDiff)
+	}
+	if !strings.Contains(configDiff, `replicas: "3"`) {
+		t.Errorf("Expected release values to be used when rendering, got:\n%s", configDiff)
+	}
+}
+
+func TestSimulateUpgradeRenderError(t *testing.T) {
+	current := fixtureChart("1.0.0", map[string]string{
+		"templates/configmap.yaml": strings.Replace(configMapTemplate, "%s", "one", 1),
+	})
+	target := fixtureChart("2.0.0", map[string]string{
+		"templates/broken.yaml": `{{ required "image.tag is required" .Values.image.tag }}`,
+	})
+
+	_, err := SimulateUpgrade(current, target, "demo", "apps", nil, diff.DefaultContext)
+	if err == nil {
+		t.Fatalf("Expected render error for target chart")
+	}
+	if !strings.Contains(err.Error(), "2.0.0") {
+		t.Errorf("Expected error to name the failing version, got: %v", err)
+	}
+}
+
+const widgetCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    plural: widgets
+  scope: Namespaced
+`
+
+func TestSimulateUpgradeDetectsCRDs(t *testing.T) {
+	current := fixtureChart("1.0.0", map[string]string{
+		"templates/configmap.yaml": strings.Replace(configMapTemplate, "%s", "one", 1),
+	})
+
+	target := fixtureChart("2.0.0", map[string]string{
+		"templates/configmap.yaml":   strings.Replace(configMapTemplate, "%s", "one", 1),
+		"templates/gadgets-crd.yaml": strings.NewReplacer("widgets", "gadgets", "Widget", "Gadget").Replace(widgetCRD),
+	})
+	target.Files = append(target.Files, &chart.File{Name: "crds/widgets.yaml", Data: []byte(widgetCRD)})
+
+	simulation, err := SimulateUpgrade(current, target, "demo", "apps", nil, diff.DefaultContext)
+	if err != nil {
+		t.Fatalf("Failed to simulate upgrade: %v", err)
+	}
+
+	expected := []string{
+		"CustomResourceDefinition/apps/gadgets.example.com",
+		"CustomResourceDefinition/apps/widgets.example.com",
+	}
+	if len(simulation.CRDs) != len(expected) {
+		t.Fatalf("Expected CRDs %v, got %v", expected, simulation.CRDs)
+	}
+	for i := range expected {
+		if simulation.CRDs[i] != expected[i] {
+			t.Errorf("Expected CRD %s, got %s", expected[i], simulation.CRDs[i])
+		}
+	}
+
+	// A chart without CRDs reports none
+	simulation, err = SimulateUpgrade(current, current, "demo", "apps", nil, diff.DefaultContext)
+	if err != nil {
+		t.Fatalf("Failed to simulate upgrade: %v", err)
+	}
+	if len(simulation.CRDs) != 0 {
+		t.Errorf("Expected no CRDs, got %v", simulation.CRDs)
+	}
+}
@@ -0,0 +1,91 @@
+package helm
+
+import (
+	"encoding/json"
+	"sort"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// RemovedValueKeys returns the sorted dotted paths of user-supplied values
+// that the target chart neither defines in its default values nor declares in
+// its values schema. Helm silently ignores such keys, or rejects them when the
+// schema forbids additional properties, so they usually need migrating.
+//
+// Keys under an empty default map (such as podAnnotations: {}) are free-form
+// and never reported, nor are the global and subchart sections.
+func RemovedValueKeys(target *chart.Chart, values map[string]interface{}) []string {
+	var schema map[string]interface{}
+	if len(target.Schema) > 0 {
+		if err := json.Unmarshal(target.Schema, &schema); err != nil {
+			schema = nil
+		}
+	}
+
+	skip := map[string]bool{"global": true}
+	for _, dependency := range target.Metadata.Dependencies {
+		skip[dependency.Name] = true
+		if dependency.Alias != "" {
+			skip[dependency.Alias] = true
+		}
+	}
+	for _, subchart := range target.Dependencies() {
+		skip[subchart.Name()] = true
+	}
+
+	var removed []string
+	for key, value := range values {
+		if skip[key] {
+			continue
+		}
+		removed = append(removed, removedKeys(key, key, value, target.Values, schema)...)
+	}
+
+	sort.Strings(removed)
+	return removed
+}
+
+// removedKeys checks a single user-supplied key against the defaults and
+// schema at the same level, descending into nested maps
+func removedKeys(path, key string, value interface{}, defaults, schema map[string]interface{}) []string {
+	defaultValue, inDefaults := defaults[key]
+	propertySchema, inSchema := schemaProperty(schema, key)
+	if !inDefaults && !inSchema {
+		return []string{path}
+	}
+
+	userMap, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	defaultMap, _ := defaultValue.(map[string]interface{})
+	if len(defaultMap) == 0 && !hasProperties(propertySchema) {
+		// The chart leaves this map free-form
+		return nil
+	}
+
+	var removed []string
+	for childKey, childValue := range userMap {
+		removed = append(removed, removedKeys(path+"."+childKey, childKey, childValue, defaultMap, propertySchema)...)
+	}
+	return removed
+}
+
+// schemaProperty returns the JSON schema of a property, reporting whether the
+// schema declares it
+func schemaProperty(schema map[string]interface{}, key string) (map[string]interface{}, bool) {
+	properties, _ := schema["properties"].(map[string]interface{})
+	property, ok := properties[key]
+	if !ok {
+		return nil, false
+	}
+	propertySchema, _ := property.(map[string]interface{})
+	return propertySchema, true
+}
+
+// hasProperties reports whether a JSON schema declares any properties
+func hasProperties(schema map[string]interface{}) bool {
+	properties, _ := schema["properties"].(map[string]interface{})
+	return len(properties) > 0
+}
@@ -0,0 +1,88 @@
+package helm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/marccoxall/helmchecker/internal/diff"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestRemovedValueKeys(t *testing.T) {
+	target := fixtureChart("2.0.0", nil)
+	target.Values = map[string]interface{}{
+		"replicas":       1,
+		"podAnnotations": map[string]interface{}{},
+		"image": map[string]interface{}{
+			"repository": "nginx",
+			"tag":        "",
+		},
+	}
+	target.Metadata.Dependencies = []*chart.Dependency{{Name: "redis"}}
+
+	values := map[string]interface{}{
+		"replicas":       3,
+		"legacyMode":     true,
+		"podAnnotations": map[string]interface{}{"example.com/team": "web"},
+		"image": map[string]interface{}{
+			"tag":        "1.25",
+			"pullPolicy": "Always",
+		},
+		"global": map[string]interface{}{"imageRegistry": "mirror.example.com"},
+		"redis":  map[string]interface{}{"enabled": false},
+	}
+
+	removed := RemovedValueKeys(target, values)
+	expected := []string{"image.pullPolicy", "legacyMode"}
+	if !reflect.DeepEqual(removed, expected) {
+		t.Errorf("Expected removed keys %v, got %v", expected, removed)
+	}
+}
+
+func TestRemovedValueKeysUsesSchema(t *testing.T) {
+	target := fixtureChart("2.0.0", nil)
+	target.Schema = []byte(`{
+  "properties": {
+    "resources": {"type": "object", "properties": {"limits": {"type": "object"}}},
+    "nodeSelector": {"type": "object"}
+  }
+}`)
+
+	values := map[string]interface{}{
+		"nodeSelector": map[string]interface{}{"disk": "ssd"},
+		"resources": map[string]interface{}{
+			"limits":   map[string]interface{}{"cpu": "1"},
+			"requests": map[string]interface{}{"cpu": "1"},
+		},
+	}
+
+	removed := RemovedValueKeys(target, values)
+	expected := []string{"resources.requests"}
+	if !reflect.DeepEqual(removed, expected) {
+		t.Errorf("Expected removed keys %v, got %v", expected, removed)
+	}
+}
+
+func TestSimulateUpgradeReportsRemovedValues(t *testing.T) {
+	templates := map[string]string{
+		"templates/configmap.yaml": strings.Replace(configMapTemplate, "%s", "one", 1),
+	}
+	current := fixtureChart("1.0.0", templates)
+	current.Values["ingress"] = map[string]interface{}{"enabled": false}
+	target := fixtureChart("2.0.0", templates)
+
+	values := map[string]interface{}{
+		"replicas": 2,
+		"ingress":  map[string]interface{}{"enabled": true},
+	}
+	simulation, err := SimulateUpgrade(current, target, "demo", "apps", values, diff.DefaultContext)
+	if err != nil {
+		t.Fatalf("Failed to simulate upgrade: %v", err)
+	}
+
+	expected := []string{"ingress"}
+	if !reflect.DeepEqual(simulation.RemovedValues, expected) {
+		t.Errorf("Expected removed values %v, got %v", expected, simulation.RemovedValues)
+	}
+}
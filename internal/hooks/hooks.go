@@ -0,0 +1,119 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Event describes a successful chart update passed to post-update hooks
+type Event struct {
+	Chart             string `json:"chart"`
+	Release           string `json:"release"`
+	Namespace         string `json:"namespace"`
+	CurrentVersion    string `json:"currentVersion"`
+	LatestVersion     string `json:"latestVersion"`
+	Branch            string `json:"branch"`
+	PullRequestNumber int    `json:"pullRequestNumber"`
+	PullRequestURL    string `json:"pullRequestURL"`
+}
+
+// Hook is invoked after a pull request has been opened for a chart update
+type Hook interface {
+	Name() string
+	Run(ctx context.Context, event *Event) error
+}
+
+// CommandHook runs a shell command with the event as JSON on stdin and as
+// HELMCHECKER_* environment variables
+type CommandHook struct {
+	command string
+}
+
+// NewCommandHook creates a hook running command with sh -c
+func NewCommandHook(command string) *CommandHook {
+	return &CommandHook{command: command}
+}
+
+// Name returns the command
+func (h *CommandHook) Name() string {
+	return h.command
+}
+
+// Run executes the command and fails if it exits non-zero
+func (h *CommandHook) Run(ctx context.Context, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode hook event: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.command)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		"HELMCHECKER_CHART="+event.Chart,
+		"HELMCHECKER_RELEASE="+event.Release,
+		"HELMCHECKER_NAMESPACE="+event.Namespace,
+		"HELMCHECKER_CURRENT_VERSION="+event.CurrentVersion,
+		"HELMCHECKER_LATEST_VERSION="+event.LatestVersion,
+		"HELMCHECKER_BRANCH="+event.Branch,
+		"HELMCHECKER_PR_NUMBER="+strconv.Itoa(event.PullRequestNumber),
+		"HELMCHECKER_PR_URL="+event.PullRequestURL,
+	)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("hook command failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// WebhookHook posts the event as JSON to a URL
+type WebhookHook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookHook creates a hook posting to url
+func NewWebhookHook(url string) *WebhookHook {
+	return &WebhookHook{url: url, client: http.DefaultClient}
+}
+
+// Name returns the webhook URL
+func (h *WebhookHook) Name() string {
+	return h.url
+}
+
+// Run posts the event and fails on a non-2xx response
+func (h *WebhookHook) Run(ctx context.Context, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode hook event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
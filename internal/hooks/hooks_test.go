@@ -0,0 +1,95 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testEvent() *Event {
+	return &Event{
+		Chart:             "nginx",
+		Release:           "web",
+		Namespace:         "apps",
+		CurrentVersion:    "1.0.0",
+		LatestVersion:     "1.1.0",
+		Branch:            "update-nginx-1.1.0",
+		PullRequestNumber: 42,
+		PullRequestURL:    "https://github.com/o/r/pull/42",
+	}
+}
+
+func TestCommandHook(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	hook := NewCommandHook(`cat > ` + out + `.json && echo "$HELMCHECKER_CHART $HELMCHECKER_PR_NUMBER $HELMCHECKER_PR_URL" > ` + out)
+
+	if err := hook.Run(context.Background(), testEvent()); err != nil {
+		t.Fatalf("hook failed: %v", err)
+	}
+
+	env, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read hook output: %v", err)
+	}
+	if got := strings.TrimSpace(string(env)); got != "nginx 42 https://github.com/o/r/pull/42" {
+		t.Errorf("Expected event in environment, got %q", got)
+	}
+
+	data, err := os.ReadFile(out + ".json")
+	if err != nil {
+		t.Fatalf("failed to read hook input: %v", err)
+	}
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		t.Fatalf("failed to decode hook input: %v", err)
+	}
+	if event != *testEvent() {
+		t.Errorf("Expected event on stdin, got %+v", event)
+	}
+}
+
+func TestCommandHookFailure(t *testing.T) {
+	hook := NewCommandHook("echo broken >&2; exit 3")
+
+	err := hook.Run(context.Background(), testEvent())
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected failure including output, got %v", err)
+	}
+}
+
+func TestWebhookHook(t *testing.T) {
+	var received Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request %s with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode webhook body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	if err := NewWebhookHook(server.URL).Run(context.Background(), testEvent()); err != nil {
+		t.Fatalf("webhook failed: %v", err)
+	}
+	if received != *testEvent() {
+		t.Errorf("Expected event in webhook body, got %+v", received)
+	}
+}
+
+func TestWebhookHookFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := NewWebhookHook(server.URL).Run(context.Background(), testEvent())
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Expected status error, got %v", err)
+	}
+}
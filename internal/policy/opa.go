@@ -0,0 +1,140 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// DefaultQuery is the Rego rule evaluated when no query is configured. Policies
+// add messages to this set for each violation they find.
+const DefaultQuery = "data.helmchecker.deny"
+
+// Violation is a single policy violation reported by an evaluator
+type Violation struct {
+	Message string
+}
+
+// Input is the document policies are evaluated against
+type Input struct {
+	Chart     string                   `json:"chart"`
+	Version   string                   `json:"version"`
+	Release   string                   `json:"release"`
+	Namespace string                   `json:"namespace"`
+	Values    map[string]interface{}   `json:"values"`
+	Manifests []map[string]interface{} `json:"manifests"`
+}
+
+// Evaluator evaluates policies against an upgrade's rendered output
+type Evaluator interface {
+	Evaluate(ctx context.Context, input *Input) ([]Violation, error)
+}
+
+// OPAEvaluator evaluates Rego policies using the opa command line tool
+type OPAEvaluator struct {
+	binary      string
+	policyFiles []string
+	query       string
+}
+
+// NewOPAEvaluator creates an evaluator for the given Rego policy files
+func NewOPAEvaluator(binary string, policyFiles []string, query string) *OPAEvaluator {
+	if binary == "" {
+		binary = "opa"
+	}
+	if query == "" {
+		query = DefaultQuery
+	}
+
+	return &OPAEvaluator{
+		binary:      binary,
+		policyFiles: policyFiles,
+		query:       query,
+	}
+}
+
+// opaOutput is the JSON output of `opa eval --format json`
+type opaOutput struct {
+	Result []struct {
+		Expressions []struct {
+			Value interface{} `json:"value"`
+		} `json:"expressions"`
+	} `json:"result"`
+}
+
+// Evaluate runs the configured query against input and returns one violation
+// per message produced by the policies
+func (e *OPAEvaluator) Evaluate(ctx context.Context, input *Input) ([]Violation, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode policy input: %w", err)
+	}
+
+	args := []string{"eval", "--format", "json", "--stdin-input"}
+	for _, file := range e.policyFiles {
+		args = append(args, "--data", file)
+	}
+	args = append(args, e.query)
+
+	cmd := exec.CommandContext(ctx, e.binary, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to evaluate policies: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var output opaOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("failed to parse policy output: %w", err)
+	}
+
+	var messages []string
+	for _, result := range output.Result {
+		for _, expr := range result.Expressions {
+			messages = append(messages, violationMessages(expr.Value)...)
+		}
+	}
+	sort.Strings(messages)
+
+	violations := make([]Violation, 0, len(messages))
+	for _, message := range messages {
+		violations = append(violations, Violation{Message: message})
+	}
+	return violations, nil
+}
+
+// violationMessages flattens a query result into messages. Deny rules usually
+// produce a set of strings, but objects with a "msg" field are also accepted.
+func violationMessages(value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case bool:
+		if v {
+			return []string{"policy denied the upgrade"}
+		}
+		return nil
+	case string:
+		return []string{v}
+	case []interface{}:
+		var messages []string
+		for _, item := range v {
+			messages = append(messages, violationMessages(item)...)
+		}
+		return messages
+	case map[string]interface{}:
+		if msg, ok := v["msg"].(string); ok {
+			return []string{msg}
+		}
+	}
+
+	encoded, _ := json.Marshal(value)
+	return []string{string(encoded)}
+}
@@ -0,0 +1,131 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeOPA writes a stand-in for the opa binary that fails any input containing
+// a privileged container, mimicking a "no privileged containers" policy
+func fakeOPA(t *testing.T) string {
+	t.Helper()
+
+	script := `#!/bin/sh
+input=$(cat)
+case "$input" in
+  *'"privileged":true'*)
+    echo '{"result":[{"expressions":[{"value":["container app must not be privileged"]}]}]}' ;;
+  *)
+    echo '{"result":[{"expressions":[{"value":[]}]}]}' ;;
+esac
+`
+	path := filepath.Join(t.TempDir(), "opa")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake opa: %v", err)
+	}
+	return path
+}
+
+func deploymentInput(privileged bool) *Input {
+	return &Input{
+		Chart:   "app",
+		Version: "2.0.0",
+		Manifests: []map[string]interface{}{
+			{
+				"kind": "Deployment",
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"containers": []interface{}{
+								map[string]interface{}{
+									"name":            "app",
+									"securityContext": map[string]interface{}{"privileged": privileged},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestOPAEvaluatorPass(t *testing.T) {
+	evaluator := NewOPAEvaluator(fakeOPA(t), []string{"policy.rego"}, "")
+
+	violations, err := evaluator.Evaluate(context.Background(), deploymentInput(false))
+	if err != nil {
+		t.Fatalf("Failed to evaluate policies: %v", err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations, got %+v", violations)
+	}
+}
+
+func TestOPAEvaluatorFail(t *testing.T) {
+	evaluator := NewOPAEvaluator(fakeOPA(t), []string{"policy.rego"}, "")
+
+	violations, err := evaluator.Evaluate(context.Background(), deploymentInput(true))
+	if err != nil {
+		t.Fatalf("Failed to evaluate policies: %v", err)
+	}
+	if len(violations) != 1 {
+		t.Fatalf("Expected 1 violation, got %d", len(violations))
+	}
+	if violations[0].Message != "container app must not be privileged" {
+		t.Errorf("Unexpected violation: %s", violations[0].Message)
+	}
+}
+
+func TestOPAEvaluatorRego(t *testing.T) {
+	binary, err := exec.LookPath("opa")
+	if err != nil {
+		t.Skip("opa binary not installed")
+	}
+
+	policy := `package helmchecker
+
+import rego.v1
+
+deny contains msg if {
+	some manifest in input.manifests
+	some container in manifest.spec.template.spec.containers
+	container.securityContext.privileged
+	msg := sprintf("container %s must not be privileged", [container.name])
+}
+`
+	path := filepath.Join(t.TempDir(), "policy.rego")
+	if err := os.WriteFile(path, []byte(policy), 0644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+
+	evaluator := NewOPAEvaluator(binary, []string{path}, "")
+
+	violations, err := evaluator.Evaluate(context.Background(), deploymentInput(false))
+	if err != nil {
+		t.Fatalf("Failed to evaluate policies: %v", err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations, got %+v", violations)
+	}
+
+	violations, err = evaluator.Evaluate(context.Background(), deploymentInput(true))
+	if err != nil {
+		t.Fatalf("Failed to evaluate policies: %v", err)
+	}
+	if len(violations) != 1 || !strings.Contains(violations[0].Message, "privileged") {
+		t.Errorf("Expected privileged violation, got %+v", violations)
+	}
+}
+
+func TestOPAEvaluatorError(t *testing.T) {
+	evaluator := NewOPAEvaluator(filepath.Join(t.TempDir(), "missing-opa"), nil, "")
+
+	if _, err := evaluator.Evaluate(context.Background(), &Input{}); err == nil {
+		t.Errorf("Expected error when opa cannot be run")
+	}
+}
@@ -0,0 +1,106 @@
+package redact
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Placeholder replaces redacted values
+const Placeholder = "REDACTED"
+
+var (
+	// urlPattern matches URLs embedded in error messages
+	urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+
+	// sensitiveParams are query parameters whose values are redacted
+	sensitiveParams = []string{"token", "access_token", "private_token", "client_secret", "secret", "password", "key", "apikey", "api_key", "sig", "signature"}
+)
+
+// String removes the given secrets, URL credentials and sensitive URL query
+// parameters from s
+func String(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		// Very short values would redact unrelated text
+		if len(secret) < 4 {
+			continue
+		}
+		s = strings.ReplaceAll(s, secret, Placeholder)
+	}
+
+	return urlPattern.ReplaceAllStringFunc(s, func(match string) string {
+		// Keep punctuation following the URL in the message out of it
+		trimmed := strings.TrimRight(match, ".,:;)]")
+		return URL(trimmed) + match[len(trimmed):]
+	})
+}
+
+// URL removes credentials and sensitive query parameters from a URL string,
+// returning it unchanged if it cannot be parsed
+func URL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	changed := false
+	if u.User != nil {
+		u.User = url.User(Placeholder)
+		changed = true
+	}
+
+	query := u.Query()
+	for name := range query {
+		if isSensitiveParam(name) {
+			query.Set(name, Placeholder)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return raw
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// isSensitiveParam reports whether a query parameter holds a secret
+func isSensitiveParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range sensitiveParams {
+		if name == sensitive {
+			return true
+		}
+	}
+	return false
+}
+
+// redactedError is an error whose message has been redacted. The original
+// error stays reachable through Unwrap for errors.Is and errors.As.
+type redactedError struct {
+	err     error
+	message string
+}
+
+// Error returns the redacted message
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+// Unwrap returns the original error
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// Error returns err with secrets removed from its message, or nil if err is nil
+func Error(err error, secrets ...string) error {
+	if err == nil {
+		return nil
+	}
+
+	message := String(err.Error(), secrets...)
+	if message == err.Error() {
+		return err
+	}
+	return &redactedError{err: err, message: message}
+}
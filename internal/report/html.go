@@ -0,0 +1,130 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/marccoxall/helmchecker/internal/checker"
+)
+
+// FileName is the name of the HTML report written to the report directory
+const FileName = "report.html"
+
+// htmlTemplate renders a self-contained page; html/template escapes all
+// values, which may contain untrusted chart metadata and error messages
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>helmchecker report</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292f; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #d0d7de; padding: 0.4em 0.8em; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+.summary td { font-weight: bold; }
+.empty { color: #57606a; font-style: italic; }
+</style>
+</head>
+<body>
+<h1>helmchecker report</h1>
+<p>Generated {{.Generated.Format "2006-01-02 15:04:05 MST"}}</p>
+
+<h2 id="summary">Summary</h2>
+<table class="summary">
+<tr><th>Charts checked</th><th>Updates found</th><th>Pull requests opened</th><th>Skipped</th><th>Failed</th></tr>
+<tr><td>{{.Result.Checked}}</td><td>{{.Result.UpdatesFound}}</td><td>{{.Result.PRsOpened}}</td><td>{{.Result.Skipped}}</td><td>{{len .Result.Failed}}</td></tr>
+</table>
+
+<h2 id="updates">Updates</h2>
+{{if .Result.Updates}}
+<table>
+<tr><th>Chart</th><th>Release</th><th>Current</th><th>Latest</th><th>Change</th><th>Why</th><th>Pull request</th></tr>
+{{range .Result.Updates}}
+<tr>
+<td>{{.Release.Chart}}</td>
+<td>{{.Release.Namespace}}/{{.Release.Name}}</td>
+<td>{{.CurrentVersion}}</td>
+<td>{{.LatestVersion}}</td>
+<td>{{.Change}}</td>
+<td>{{range .Reasons}}{{.Description}}<br>{{end}}</td>
+<td>{{if .PullRequestURL}}<a href="{{.PullRequestURL}}">{{.PullRequestURL}}</a>{{end}}</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p class="empty">No updates found.</p>
+{{end}}
+
+<h2 id="blocked">Blocked</h2>
+{{if .Result.Blocked}}
+<table>
+<tr><th>Chart</th><th>Current</th><th>Latest</th><th>Why</th></tr>
+{{range .Result.Blocked}}
+<tr>
+<td>{{.Release.Chart}}</td>
+<td>{{.CurrentVersion}}</td>
+<td>{{.LatestVersion}}</td>
+<td>{{range .Reasons}}{{.Description}}<br>{{end}}</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p class="empty">No updates were blocked.</p>
+{{end}}
+
+<h2 id="failed">Failed</h2>
+{{if .Result.Failed}}
+<table>
+<tr><th>Chart</th><th>Error</th></tr>
+{{range .Result.Failed}}
+<tr><td>{{.Chart}}</td><td>{{.Err}}</td></tr>
+{{end}}
+</table>
+{{else}}
+<p class="empty">No failures.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML renders a run result as a self-contained HTML page
+func WriteHTML(w io.Writer, result *checker.RunResult, generated time.Time) error {
+	data := struct {
+		Result    *checker.RunResult
+		Generated time.Time
+	}{result, generated}
+
+	if err := htmlTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return nil
+}
+
+// WriteHTMLFile writes the HTML report of a run result to FileName in dir,
+// returning the report's path
+func WriteHTMLFile(dir string, result *checker.RunResult, generated time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	path := filepath.Join(dir, FileName)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create HTML report: %w", err)
+	}
+
+	if err := WriteHTML(f, result, generated); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write HTML report: %w", err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,110 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marccoxall/helmchecker/internal/checker"
+	"github.com/marccoxall/helmchecker/internal/helm"
+)
+
+func testResult() *checker.RunResult {
+	return &checker.RunResult{
+		Checked:      3,
+		UpdatesFound: 1,
+		PRsOpened:    1,
+		Updates: []*checker.ChartUpdate{
+			{
+				Release:        &helm.Release{Name: "web", Namespace: "apps", Chart: "nginx"},
+				CurrentVersion: "1.0.0",
+				LatestVersion:  "1.1.0",
+				Change:         checker.VersionMinor,
+				Reasons:        []checker.UpdateReason{checker.ReasonNewVersion, checker.ReasonSecurityFix},
+				PullRequestURL: "https://github.com/org/charts/pull/7",
+			},
+		},
+		Blocked: []*checker.ChartUpdate{
+			{
+				Release:        &helm.Release{Chart: "redis"},
+				CurrentVersion: "2.0.0",
+				LatestVersion:  "1.9.0",
+				Change:         checker.VersionDowngrade,
+				Reasons:        []checker.UpdateReason{checker.ReasonDowngradeBlocked},
+			},
+		},
+		Failed: []checker.ChartError{
+			{Chart: "<script>alert(1)</script>", Err: errors.New(`index unreachable: <a href="x">`)},
+		},
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteHTML(&out, testResult(), time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)); err != nil {
+		t.Fatalf("WriteHTML failed: %v", err)
+	}
+	html := out.String()
+
+	for _, want := range []string{
+		`<h2 id="summary">Summary</h2>`,
+		`<h2 id="updates">Updates</h2>`,
+		`<h2 id="blocked">Blocked</h2>`,
+		`<h2 id="failed">Failed</h2>`,
+		"Generated 2024-01-02 03:04:05 UTC",
+		"<td>apps/web</td>",
+		"<td>minor</td>",
+		"The new version contains security fixes",
+		`<a href="https://github.com/org/charts/pull/7">`,
+		"The latest version is lower than the installed one",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected report to contain %q", want)
+		}
+	}
+
+	// Untrusted content is escaped
+	if strings.Contains(html, "<script>") || strings.Contains(html, `<a href="x">`) {
+		t.Errorf("Expected untrusted content to be escaped, got:\n%s", html)
+	}
+	if !strings.Contains(html, "&lt;script&gt;alert(1)&lt;/script&gt;") {
+		t.Errorf("Expected escaped chart name in report")
+	}
+}
+
+func TestWriteHTMLEmpty(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteHTML(&out, &checker.RunResult{}, time.Now()); err != nil {
+		t.Fatalf("WriteHTML failed: %v", err)
+	}
+
+	for _, want := range []string{"No updates found.", "No updates were blocked.", "No failures."} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected report to contain %q", want)
+		}
+	}
+}
+
+func TestWriteHTMLFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "artifacts")
+
+	path, err := WriteHTMLFile(dir, testResult(), time.Now())
+	if err != nil {
+		t.Fatalf("WriteHTMLFile failed: %v", err)
+	}
+	if path != filepath.Join(dir, FileName) {
+		t.Errorf("Unexpected report path %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "<!DOCTYPE html>") {
+		t.Errorf("Expected an HTML document, got %q", string(data[:20]))
+	}
+}
@@ -0,0 +1,127 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrNotImplemented is returned by secret stores that are not yet supported
+var ErrNotImplemented = errors.New("secret store not implemented")
+
+// SecretStore resolves a secret reference to its value
+type SecretStore interface {
+	Get(ref string) (string, error)
+}
+
+// Reference is a parsed secret reference of the form scheme:path#key
+type Reference struct {
+	Scheme string
+	Path   string
+	Key    string
+}
+
+// ParseReference parses a secret reference such as "vault:secret/data/github#token".
+// It returns false when value does not use a known scheme, meaning it should be
+// treated as a literal secret.
+func ParseReference(value string, schemes map[string]SecretStore) (Reference, bool) {
+	scheme, rest, found := strings.Cut(value, ":")
+	if !found {
+		return Reference{}, false
+	}
+	if _, ok := schemes[scheme]; !ok {
+		return Reference{}, false
+	}
+
+	path, key, _ := strings.Cut(rest, "#")
+	return Reference{Scheme: scheme, Path: path, Key: key}, true
+}
+
+// String returns the reference in scheme:path#key form
+func (r Reference) String() string {
+	if r.Key == "" {
+		return fmt.Sprintf("%s:%s", r.Scheme, r.Path)
+	}
+	return fmt.Sprintf("%s:%s#%s", r.Scheme, r.Path, r.Key)
+}
+
+// Resolver dispatches secret references to the store registered for their scheme
+type Resolver struct {
+	stores map[string]SecretStore
+}
+
+// NewResolver creates a resolver with the env, file and vault stores registered
+func NewResolver() *Resolver {
+	return &Resolver{
+		stores: map[string]SecretStore{
+			"env":   &EnvStore{},
+			"file":  &FileStore{},
+			"vault": NewVaultStore(os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN")),
+		},
+	}
+}
+
+// Register adds or replaces the store used for a scheme
+func (r *Resolver) Register(scheme string, store SecretStore) {
+	r.stores[scheme] = store
+}
+
+// Resolve returns the secret referenced by value, or value itself when it is
+// not a secret reference
+func (r *Resolver) Resolve(value string) (string, error) {
+	ref, ok := ParseReference(value, r.stores)
+	if !ok {
+		return value, nil
+	}
+
+	secret, err := r.stores[ref.Scheme].Get(strings.TrimPrefix(value, ref.Scheme+":"))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve secret %s: %w", ref, err)
+	}
+	return secret, nil
+}
+
+// EnvStore reads secrets from environment variables
+type EnvStore struct{}
+
+// Get returns the value of the environment variable named by ref
+func (s *EnvStore) Get(ref string) (string, error) {
+	value, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", ref)
+	}
+	return value, nil
+}
+
+// FileStore reads secrets from files, such as mounted Kubernetes secrets
+type FileStore struct{}
+
+// Get returns the trimmed contents of the file at ref
+func (s *FileStore) Get(ref string) (string, error) {
+	content, err := os.ReadFile(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// VaultStore reads secrets from HashiCorp Vault
+type VaultStore struct {
+	address string
+	token   string
+}
+
+// NewVaultStore creates a new Vault secret store
+func NewVaultStore(address, token string) *VaultStore {
+	return &VaultStore{
+		address: address,
+		token:   token,
+	}
+}
+
+// Get is not implemented yet; references of the form path#key are accepted
+// so configuration can be written ahead of Vault support
+func (s *VaultStore) Get(ref string) (string, error) {
+	return "", fmt.Errorf("vault lookup of %s: %w", ref, ErrNotImplemented)
+}
@@ -0,0 +1,90 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	schemes := NewResolver().stores
+
+	ref, ok := ParseReference("vault:secret/data/github#token", schemes)
+	if !ok {
+		t.Fatalf("Expected vault reference to parse")
+	}
+	if ref.Scheme != "vault" || ref.Path != "secret/data/github" || ref.Key != "token" {
+		t.Errorf("Unexpected reference: %+v", ref)
+	}
+
+	ref, ok = ParseReference("env:MY_TOKEN", schemes)
+	if !ok || ref.Scheme != "env" || ref.Path != "MY_TOKEN" || ref.Key != "" {
+		t.Errorf("Unexpected env reference: %+v", ref)
+	}
+
+	// Literal tokens and unknown schemes are not references
+	if _, ok := ParseReference("ghp_abcdef", schemes); ok {
+		t.Errorf("Expected literal token not to parse as a reference")
+	}
+	if _, ok := ParseReference("https://example.com", schemes); ok {
+		t.Errorf("Expected unknown scheme not to parse as a reference")
+	}
+}
+
+func TestEnvStore(t *testing.T) {
+	_ = os.Setenv("TEST_SECRET", "s3cret")
+	defer func() { _ = os.Unsetenv("TEST_SECRET") }()
+
+	resolver := NewResolver()
+
+	value, err := resolver.Resolve("env:TEST_SECRET")
+	if err != nil {
+		t.Fatalf("Failed to resolve env secret: %v", err)
+	}
+	if value != "s3cret" {
+		t.Errorf("Expected 's3cret', got '%s'", value)
+	}
+
+	if _, err := resolver.Resolve("env:MISSING_TEST_SECRET"); err == nil {
+		t.Errorf("Expected error for missing environment variable")
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("file-token\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+
+	resolver := NewResolver()
+
+	value, err := resolver.Resolve("file:" + path)
+	if err != nil {
+		t.Fatalf("Failed to resolve file secret: %v", err)
+	}
+	if value != "file-token" {
+		t.Errorf("Expected 'file-token', got '%s'", value)
+	}
+
+	if _, err := resolver.Resolve("file:" + path + ".missing"); err == nil {
+		t.Errorf("Expected error for missing secret file")
+	}
+}
+
+func TestResolveLiteral(t *testing.T) {
+	value, err := NewResolver().Resolve("plain-token")
+	if err != nil {
+		t.Fatalf("Failed to resolve literal: %v", err)
+	}
+	if value != "plain-token" {
+		t.Errorf("Expected 'plain-token', got '%s'", value)
+	}
+}
+
+func TestVaultStoreNotImplemented(t *testing.T) {
+	_, err := NewResolver().Resolve("vault:secret/data/github#token")
+	if !errors.Is(err, ErrNotImplemented) {
+		t.Errorf("Expected ErrNotImplemented, got %v", err)
+	}
+}
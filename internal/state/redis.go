@@ -0,0 +1,103 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore is a Store keeping values in Redis under a key prefix
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore creates a Redis store from a redis:// or rediss:// URL such
+// as redis://:password@redis:6379/0. Keys are stored under prefix.
+func NewRedisStore(rawURL, prefix string) (*RedisStore, error) {
+	options, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+	return &RedisStore{client: redis.NewClient(options), prefix: prefix}, nil
+}
+
+// Get returns the value of key, reporting whether it exists
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get %s from Redis: %w", key, err)
+	}
+	return value, true, nil
+}
+
+// Set stores value under key, expiring it after ttl if ttl is positive
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := s.client.Set(ctx, s.prefix+key, value, redisTTL(ttl)).Err(); err != nil {
+		return fmt.Errorf("failed to set %s in Redis: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes key; deleting a missing key is not an error
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, s.prefix+key).Err(); err != nil {
+		return fmt.Errorf("failed to delete %s from Redis: %w", key, err)
+	}
+	return nil
+}
+
+// List returns the sorted keys that start with prefix
+func (s *RedisStore) List(ctx context.Context, prefix string) ([]string, error) {
+	seen := make(map[string]bool)
+	iter := s.client.Scan(ctx, 0, globEscape(s.prefix+prefix)+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		// SCAN may return a key more than once
+		seen[strings.TrimPrefix(iter.Val(), s.prefix)] = true
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list keys in Redis: %w", err)
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Close closes the connections to Redis
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// redisTTL rounds a positive TTL up to whole milliseconds, the resolution of
+// Redis expiries, so short TTLs don't become an invalid expire time of 0
+func redisTTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return 0
+	}
+	return (ttl + time.Millisecond - 1).Truncate(time.Millisecond)
+}
+
+// globEscape escapes the characters special to Redis MATCH patterns
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,182 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Store keys of the run state. Every chart has its own entries so checkers
+// sharing a store, such as replicas using Redis, don't overwrite each other.
+const (
+	// bumpPrefix prefixes the time an update pull request was last opened per chart
+	bumpPrefix = "bump/"
+	// latestPrefix prefixes cached lookups that found no newer version
+	latestPrefix = "latest/"
+	// indexDigestKey identifies the repository indexes the cached lookups were made against
+	indexDigestKey = "index-digest"
+)
+
+// State is run state persisted between checker runs in a Store. Entries
+// expire on their own: bumps after the cooldown, lookups after the cache TTL.
+type State struct {
+	store Store
+}
+
+// CachedVersion is a cached latest chart version lookup
+type CachedVersion struct {
+	Version   string    `json:"version"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// New returns the run state kept in store
+func New(store Store) *State {
+	return &State{store: store}
+}
+
+// entryKey returns the store key of name under prefix. Names are escaped so
+// lookup keys containing URLs stay a single path component.
+func entryKey(prefix, name string) string {
+	return prefix + url.PathEscape(name)
+}
+
+// LastBump returns when an update pull request was last opened for chart,
+// reporting whether one is recorded
+func (s *State) LastBump(ctx context.Context, chart string) (time.Time, bool, error) {
+	key := entryKey(bumpPrefix, chart)
+	data, ok, err := s.store.Get(ctx, key)
+	if err != nil || !ok {
+		return time.Time{}, false, err
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, string(data))
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to parse state %s: %w", key, err)
+	}
+	return t, true, nil
+}
+
+// InCooldown reports whether chart was bumped less than cooldown before now
+func (s *State) InCooldown(ctx context.Context, chart string, cooldown time.Duration, now time.Time) (bool, error) {
+	if cooldown <= 0 {
+		return false, nil
+	}
+	last, ok, err := s.LastBump(ctx, chart)
+	if err != nil || !ok {
+		return false, err
+	}
+	return now.Sub(last) < cooldown, nil
+}
+
+// RecordBump records that an update pull request was opened for chart at t,
+// keeping the record for cooldown
+func (s *State) RecordBump(ctx context.Context, chart string, t time.Time, cooldown time.Duration) error {
+	key := entryKey(bumpPrefix, chart)
+	if err := s.store.Set(ctx, key, []byte(t.UTC().Format(time.RFC3339Nano)), cooldown); err != nil {
+		return fmt.Errorf("failed to write state %s: %w", key, err)
+	}
+	return nil
+}
+
+// CachedLatest returns the cached latest version for key if it was checked less than ttl before now
+func (s *State) CachedLatest(ctx context.Context, key string, ttl time.Duration, now time.Time) (string, bool, error) {
+	if ttl <= 0 {
+		return "", false, nil
+	}
+
+	storeKey := entryKey(latestPrefix, key)
+	data, ok, err := s.store.Get(ctx, storeKey)
+	if err != nil || !ok {
+		return "", false, err
+	}
+
+	var cached CachedVersion
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return "", false, fmt.Errorf("failed to parse state %s: %w", storeKey, err)
+	}
+	if now.Sub(cached.CheckedAt) >= ttl {
+		return "", false, nil
+	}
+	return cached.Version, true, nil
+}
+
+// RecordLatest caches version as the latest version for key as of t, for ttl
+func (s *State) RecordLatest(ctx context.Context, key, version string, t time.Time, ttl time.Duration) error {
+	data, err := json.Marshal(CachedVersion{Version: version, CheckedAt: t})
+	if err != nil {
+		return fmt.Errorf("failed to encode cached version: %w", err)
+	}
+
+	storeKey := entryKey(latestPrefix, key)
+	if err := s.store.Set(ctx, storeKey, data, ttl); err != nil {
+		return fmt.Errorf("failed to write state %s: %w", storeKey, err)
+	}
+	return nil
+}
+
+// InvalidateLatest drops all cached lookups if the repository indexes changed
+// since they were made, and reports whether it did
+func (s *State) InvalidateLatest(ctx context.Context, indexDigest string) (bool, error) {
+	current, ok, err := s.store.Get(ctx, indexDigestKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read state %s: %w", indexDigestKey, err)
+	}
+	if indexDigest != "" && ok && string(current) == indexDigest {
+		return false, nil
+	}
+
+	keys, err := s.store.List(ctx, latestPrefix)
+	if err != nil {
+		return false, fmt.Errorf("failed to list cached versions: %w", err)
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			return false, fmt.Errorf("failed to delete state %s: %w", key, err)
+		}
+	}
+
+	if err := s.store.Set(ctx, indexDigestKey, []byte(indexDigest), 0); err != nil {
+		return false, fmt.Errorf("failed to write state %s: %w", indexDigestKey, err)
+	}
+	return true, nil
+}
+
+// legacyState is the single-document state written by earlier versions
+type legacyState struct {
+	LastBumps map[string]time.Time `json:"lastBumps"`
+}
+
+// MigrateLegacy moves the bumps of the single-document state file at path
+// to their own entries, keeping those still within cooldown of now, and
+// removes the file. Cached lookups are dropped and simply redone.
+func (s *State) MigrateLegacy(ctx context.Context, path string, cooldown time.Duration, now time.Time) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read state file %s: %w", path, err)
+	}
+
+	var legacy legacyState
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	for chart, bumped := range legacy.LastBumps {
+		remaining := cooldown - now.Sub(bumped)
+		if remaining <= 0 {
+			continue
+		}
+		if err := s.RecordBump(ctx, chart, bumped, remaining); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove state file %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package state
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBumps(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileStore(filepath.Join(t.TempDir(), "nested"))
+	s := New(store)
+
+	if _, ok, err := s.LastBump(ctx, "nginx"); err != nil || ok {
+		t.Errorf("Expected no bump in empty state, got ok=%v err=%v", ok, err)
+	}
+
+	bumped := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+	if err := s.RecordBump(ctx, "nginx", bumped, 24*time.Hour); err != nil {
+		t.Fatalf("Failed to record bump: %v", err)
+	}
+
+	// A new state over the same store sees the bump
+	loaded := New(store)
+	last, ok, err := loaded.LastBump(ctx, "nginx")
+	if err != nil || !ok || !last.Equal(bumped) {
+		t.Errorf("Expected last bump %s, got %s (ok=%v err=%v)", bumped, last, ok, err)
+	}
+
+	tests := []struct {
+		chart    string
+		now      time.Time
+		expected bool
+	}{
+		{"nginx", bumped.Add(time.Hour), true},
+		{"nginx", bumped.Add(25 * time.Hour), false},
+		{"redis", bumped, false},
+	}
+	for _, tt := range tests {
+		got, err := loaded.InCooldown(ctx, tt.chart, 24*time.Hour, tt.now)
+		if err != nil {
+			t.Fatalf("InCooldown failed: %v", err)
+		}
+		if got != tt.expected {
+			t.Errorf("InCooldown(%s, %s) = %v, expected %v", tt.chart, tt.now, got, tt.expected)
+		}
+	}
+
+	// Bumps expire from the store along with their cooldown
+	if err := s.RecordBump(ctx, "redis", bumped, 20*time.Millisecond); err != nil {
+		t.Fatalf("Failed to record bump: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, ok, _ := s.LastBump(ctx, "redis"); ok {
+		t.Errorf("Expected bump to expire after its cooldown")
+	}
+}
+
+func TestSharedStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileStore(t.TempDir())
+	bumped := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Two replicas opened before either writes keep each other's entries
+	first, second := New(store), New(store)
+	if err := first.RecordBump(ctx, "nginx", bumped, time.Hour); err != nil {
+		t.Fatalf("Failed to record bump: %v", err)
+	}
+	if err := second.RecordBump(ctx, "redis", bumped, time.Hour); err != nil {
+		t.Fatalf("Failed to record bump: %v", err)
+	}
+	if err := second.RecordLatest(ctx, "mysql", "9.0.0", bumped, time.Hour); err != nil {
+		t.Fatalf("Failed to record latest version: %v", err)
+	}
+
+	for _, chart := range []string{"nginx", "redis"} {
+		if _, ok, _ := first.LastBump(ctx, chart); !ok {
+			t.Errorf("Expected the bump of %s to be kept", chart)
+		}
+	}
+	if _, ok, _ := first.CachedLatest(ctx, "mysql", time.Hour, bumped); !ok {
+		t.Errorf("Expected the cached lookup of mysql to be kept")
+	}
+}
+
+func TestLatestVersionCache(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileStore(t.TempDir())
+	s := New(store)
+
+	if invalidated, err := s.InvalidateLatest(ctx, "digest-1"); err != nil || !invalidated {
+		t.Errorf("Expected first index digest to invalidate the cache, got %v (err=%v)", invalidated, err)
+	}
+
+	checked := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := "nginx@https://charts.example.com#stable"
+	if err := s.RecordLatest(ctx, key, "1.2.0", checked, time.Hour); err != nil {
+		t.Fatalf("Failed to record latest version: %v", err)
+	}
+	if err := s.RecordBump(ctx, "nginx", checked, time.Hour); err != nil {
+		t.Fatalf("Failed to record bump: %v", err)
+	}
+
+	// Lookup keys containing URLs are a single entry
+	keys, err := store.List(ctx, latestPrefix)
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if expected := []string{"latest/nginx@https:%2F%2Fcharts.example.com%23stable"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected keys %v, got %v", expected, keys)
+	}
+
+	loaded := New(store)
+	if version, ok, err := loaded.CachedLatest(ctx, key, time.Hour, checked.Add(30*time.Minute)); err != nil || !ok || version != "1.2.0" {
+		t.Errorf("Expected cached version 1.2.0 within the TTL, got %q (%v, err=%v)", version, ok, err)
+	}
+	if _, ok, _ := loaded.CachedLatest(ctx, key, time.Hour, checked.Add(2*time.Hour)); ok {
+		t.Errorf("Expected cache entry to expire after the TTL")
+	}
+	if _, ok, _ := loaded.CachedLatest(ctx, key, 0, checked); ok {
+		t.Errorf("Expected cache to be disabled without a TTL")
+	}
+
+	// An unchanged index keeps the cache, a refreshed one drops it
+	if invalidated, _ := loaded.InvalidateLatest(ctx, "digest-1"); invalidated {
+		t.Errorf("Expected unchanged index digest to keep the cache")
+	}
+	if invalidated, _ := loaded.InvalidateLatest(ctx, "digest-2"); !invalidated {
+		t.Errorf("Expected changed index digest to invalidate the cache")
+	}
+	if _, ok, _ := loaded.CachedLatest(ctx, key, time.Hour, checked); ok {
+		t.Errorf("Expected cache to be empty after invalidation")
+	}
+	if _, ok, _ := loaded.LastBump(ctx, "nginx"); !ok {
+		t.Errorf("Expected invalidation to keep bumps")
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	data := `{"lastBumps":{"nginx":"2024-01-01T00:00:00Z","redis":"2023-12-01T00:00:00Z"},"latestVersions":{},"indexDigest":""}`
+	if err := os.WriteFile(filepath.Join(dir, "state.json"), []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+
+	s := New(NewFileStore(filepath.Join(dir, "state.json.d")))
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	if err := s.MigrateLegacy(ctx, filepath.Join(dir, "state.json"), 72*time.Hour, now); err != nil {
+		t.Fatalf("Failed to migrate state file: %v", err)
+	}
+
+	// Bumps still in cooldown are kept, elapsed ones dropped
+	if last, ok, _ := s.LastBump(ctx, "nginx"); !ok || !last.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the nginx bump to be migrated, got %s (ok=%v)", last, ok)
+	}
+	if _, ok, _ := s.LastBump(ctx, "redis"); ok {
+		t.Errorf("Expected the elapsed redis bump to be dropped")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "state.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected the state file to be removed, got %v", err)
+	}
+
+	// Without a state file there is nothing to do
+	if err := s.MigrateLegacy(ctx, filepath.Join(dir, "state.json"), 72*time.Hour, now); err != nil {
+		t.Errorf("Expected migrating a missing state file to succeed, got %v", err)
+	}
+}
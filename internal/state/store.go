@@ -0,0 +1,228 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Store persists values by key between checker runs. A positive TTL makes a
+// value expire; expired values are neither returned nor listed.
+type Store interface {
+	// Get returns the value of key, reporting whether it exists
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key, expiring it after ttl if ttl is positive
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes key; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	// List returns the sorted keys that start with prefix
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// expiriesFile records the expiry times of file store values with a TTL
+const expiriesFile = ".expiries.json"
+
+// FileStore is a Store keeping each value in a file under a directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a file store rooted at dir
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Get returns the value of key, reporting whether it exists
+func (s *FileStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	expired, err := s.expired(key)
+	if err != nil || expired {
+		return nil, false, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return data, true, nil
+}
+
+// Set stores value under key, expiring it after ttl if ttl is positive
+func (s *FileStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(path, value); err != nil {
+		return err
+	}
+
+	return s.updateExpiries(func(expiries map[string]time.Time) {
+		if ttl > 0 {
+			expiries[key] = time.Now().Add(ttl)
+		} else {
+			delete(expiries, key)
+		}
+	})
+}
+
+// Delete removes key; deleting a missing key is not an error
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete %s: %w", path, err)
+	}
+
+	return s.updateExpiries(func(expiries map[string]time.Time) {
+		delete(expiries, key)
+	})
+}
+
+// List returns the sorted keys that start with prefix
+func (s *FileStore) List(ctx context.Context, prefix string) ([]string, error) {
+	expiries, err := s.readExpiries()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	var keys []string
+	err = filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == s.dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		// Skip bookkeeping and temporary files
+		if strings.HasPrefix(d.Name(), ".") && path != s.dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		if expiry, ok := expiries[key]; ok && !now.Before(expiry) {
+			return nil
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", s.dir, err)
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// path returns the file holding key, rejecting keys that would escape the store
+func (s *FileStore) path(key string) (string, error) {
+	clean := filepath.ToSlash(filepath.Clean(filepath.FromSlash(key)))
+	if key == "" || clean != key || filepath.IsAbs(key) || strings.HasPrefix(key, ".") || strings.Contains(key, "/.") {
+		return "", fmt.Errorf("invalid store key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// expired reports whether key has a TTL that has passed
+func (s *FileStore) expired(key string) (bool, error) {
+	expiries, err := s.readExpiries()
+	if err != nil {
+		return false, err
+	}
+	expiry, ok := expiries[key]
+	return ok && !time.Now().Before(expiry), nil
+}
+
+// readExpiries reads the expiry times of values with a TTL
+func (s *FileStore) readExpiries() (map[string]time.Time, error) {
+	expiries := make(map[string]time.Time)
+
+	path := filepath.Join(s.dir, expiriesFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return expiries, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &expiries); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return expiries, nil
+}
+
+// updateExpiries applies update to the recorded expiry times, writing them
+// back if they changed
+func (s *FileStore) updateExpiries(update func(map[string]time.Time)) error {
+	expiries, err := s.readExpiries()
+	if err != nil {
+		return err
+	}
+	before := len(expiries)
+	update(expiries)
+	if before == 0 && len(expiries) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(expiries)
+	if err != nil {
+		return fmt.Errorf("failed to encode expiries: %w", err)
+	}
+	return writeFile(filepath.Join(s.dir, expiriesFile), data)
+}
+
+// writeFile writes data to path atomically, creating parent directories as needed
+func writeFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".store-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}